@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/pkg/jwtkeys"
+)
+
+// JWT署名鍵の生成（ローテーション時に新しい鍵を鍵ディレクトリへ追加する）
+//
+//	go run ./cmd/jwtkey -dir keys -alg RS256
+func main() {
+	dir := flag.String("dir", "keys", "鍵ディレクトリ（JWT_KEYS_DIR）")
+	alg := flag.String("alg", "RS256", "署名方式（RS256 または EdDSA）")
+	kid := flag.String("kid", "", "鍵ID（省略時は生成日時）")
+	flag.Parse()
+
+	if *kid == "" {
+		*kid = time.Now().UTC().Format("20060102-150405")
+	}
+
+	data, err := jwtkeys.GenerateKey(*alg)
+	if err != nil {
+		log.Fatal("Failed to generate key:", err)
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		log.Fatal("Failed to create key directory:", err)
+	}
+
+	path := filepath.Join(*dir, *kid+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatal("Failed to write key:", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		log.Fatal("Failed to write key:", err)
+	}
+
+	fmt.Printf("Generated %s key: %s\n", *alg, path)
+	fmt.Printf("Set JWT_ACTIVE_KEY_ID=%s once verifiers have picked up the new JWKS.\n", *kid)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/Naonao3/EC-site/backend/config"
@@ -10,9 +11,14 @@ import (
 	"github.com/Naonao3/EC-site/backend/internal/repository"
 	"github.com/Naonao3/EC-site/backend/internal/service"
 	"github.com/Naonao3/EC-site/backend/pkg/database"
+	"github.com/Naonao3/EC-site/backend/pkg/jwtkeys"
+	"github.com/Naonao3/EC-site/backend/pkg/mailer"
+	"github.com/Naonao3/EC-site/backend/pkg/oidc"
 	redisClient "github.com/Naonao3/EC-site/backend/pkg/redis"
+	"github.com/Naonao3/EC-site/backend/pkg/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -23,6 +29,9 @@ func main() {
 
 	// 設定の読み込み
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
 
 	// データベース接続
 	db, err := database.NewPostgresDB(cfg)
@@ -31,6 +40,19 @@ func main() {
 	}
 	defer database.CloseDB(db)
 
+	// JWT署名鍵（鍵ディレクトリが無い場合はHS256の共有シークレット）
+	jwtKeys := jwtkeys.NewHMAC(cfg.JWT.Secret)
+	if cfg.JWT.KeysDir != "" {
+		jwtKeys, err = jwtkeys.LoadDir(cfg.JWT.KeysDir, cfg.JWT.ActiveKeyID)
+		if err != nil {
+			log.Fatalf("Failed to load JWT signing keys from JWT_KEYS_DIR=%s (generate them with cmd/jwtkey or unset JWT_KEYS_DIR to use JWT_SECRET): %v", cfg.JWT.KeysDir, err)
+		}
+		log.Printf("JWT signing key: %s", jwtKeys.ActiveKeyID())
+	}
+
+	// メール確認の導入前に登録されたユーザーは確認済みとして扱う（列を追加するマイグレーションでのみ設定）
+	backfillEmailVerified := db.Migrator().HasTable(&model.User{}) && !db.Migrator().HasColumn(&model.User{}, "EmailVerifiedAt")
+
 	// マイグレーション実行（Paymentを追加）
 	if err := db.AutoMigrate(
 		&model.User{},
@@ -39,9 +61,57 @@ func main() {
 		&model.OrderItem{},
 		&model.CartItem{},
 		&model.Payment{}, // NEW
+		&model.RefreshToken{},
+		&model.RevokedToken{},
+		&model.UserToken{},
+		&model.LoginAttempt{},
+		&model.RecoveryCode{},
+		&model.Permission{},
+		&model.Role{},
+		&model.Identity{},
+		&model.OAuthState{},
+		&model.APIKey{},
+		&model.Address{},
+		&model.DataErasureRequest{},
+		&model.Category{},
+		&model.ProductOption{},
+		&model.ProductVariant{},
+		&model.Review{},
+		&model.ReviewVote{},
+		&model.ProductImage{},
+		&model.DownloadGrant{},
+		&model.DownloadLog{},
+		&model.ProductLicense{},
+		&model.LicenseCertificate{},
+		&model.ProductImportJob{},
+		&model.InventoryReservation{},
+		&model.StockAlert{},
+		&model.StockSubscription{},
+		&model.StockMovement{},
+		&model.Warehouse{},
+		&model.WarehouseStock{},
+		&model.Coupon{},
+		&model.CouponProduct{},
+		&model.CouponCategory{},
+		&model.CouponRedemption{},
+		&model.CartCoupon{},
+		&model.OrderDiscount{},
 	); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	// 在庫予約は倉庫ごとに分けて作成するため、注文明細ごとの一意制約を削除（倉庫との組み合わせの一意制約に置き換え）
+	if db.Migrator().HasIndex(&model.InventoryReservation{}, "idx_inventory_reservations_order_item_id") {
+		if err := db.Migrator().DropIndex(&model.InventoryReservation{}, "idx_inventory_reservations_order_item_id"); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+	}
+	if backfillEmailVerified {
+		result := db.Model(&model.User{}).Where("email_verified_at IS NULL").Update("email_verified_at", gorm.Expr("created_at"))
+		if result.Error != nil {
+			log.Fatal("Failed to migrate database:", result.Error)
+		}
+		log.Printf("Marked %d existing users as email verified", result.RowsAffected)
+	}
 	log.Println("Database migration completed successfully")
 
 	// Redis接続
@@ -52,29 +122,180 @@ func main() {
 		defer redisClient.CloseRedis(redis)
 	}
 
+	// メール送信
+	mail, err := mailer.NewMailer(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize mailer:", err)
+	}
+
+	// 商品画像の保存先（透かし入りのプレビューは公開、元画像は非公開）
+	fileStorage, err := storage.NewStorage(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize storage:", err)
+	}
+	privateStorage, err := storage.NewPrivateStorage(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize private storage:", err)
+	}
+
+	// ソーシャルログインのプロバイダー
+	oidcProviders := oidc.NewRegistry()
+	for _, p := range cfg.OAuth.Providers {
+		oidcProviders.Register(oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.OAuth.RedirectURL + "/" + p.Name,
+			Scopes:       p.Scopes,
+		}))
+	}
+
 	// リポジトリの初期化
 	userRepo := repository.NewUserRepository(db)
 	productRepo := repository.NewProductRepository(db)
 	cartRepo := repository.NewCartRepository(db)
 	orderRepo := repository.NewOrderRepository(db)
 	paymentRepo := repository.NewPaymentRepository(db) // NEW
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	oauthStateRepo := repository.NewOAuthStateRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	addressRepo := repository.NewAddressRepository(db)
+	dataErasureRepo := repository.NewDataErasureRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	variantRepo := repository.NewVariantRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	productImageRepo := repository.NewProductImageRepository(db)
+	downloadRepo := repository.NewDownloadRepository(db)
+	licenseRepo := repository.NewLicenseRepository(db)
+	productImportRepo := repository.NewProductImportRepository(db)
+	reservationRepo := repository.NewReservationRepository(db)
+	stockNotificationRepo := repository.NewStockNotificationRepository(db)
+	stockMovementRepo := repository.NewStockMovementRepository(db)
+	warehouseRepo := repository.NewWarehouseRepository(db)
+	couponRepo := repository.NewCouponRepository(db)
 
 	// サービスの初期化
-	userService := service.NewUserService(userRepo, cfg.JWT.Secret)
-	productService := service.NewProductService(productRepo)
-	cartService := service.NewCartService(cartRepo, productRepo)
-	orderService := service.NewOrderService(orderRepo, cartRepo, productRepo)
-	paymentService := service.NewPaymentService(paymentRepo, orderRepo, cfg.Stripe.SecretKey) // NEW
+	revocationList := service.NewRevocationList(redis, revokedTokenRepo)
+	tokenService := service.NewTokenService(userRepo, roleRepo, refreshTokenRepo, revocationList, jwtKeys, cfg.JWT.Issuer, cfg.JWT.Expiry, cfg.JWT.RefreshExpiry)
+	loginThrottle := service.NewLoginThrottle(
+		service.NewAttemptStore(redis),
+		cfg.Auth.LoginMaxAttempts,
+		cfg.Auth.LoginMaxAttemptsPerIP,
+		cfg.Auth.LoginAttemptWindow,
+		cfg.Auth.LoginLockoutDuration,
+	)
+	userService := service.NewUserService(userRepo, userTokenRepo, loginAttemptRepo, apiKeyRepo, tokenService, loginThrottle, mail, cfg.Server.FrontendURL)
+	mfaService := service.NewMFAService(userRepo, recoveryCodeRepo, loginAttemptRepo, tokenService, loginThrottle, cfg.Auth.MFAIssuer)
+	stockNotifier := service.NewMailStockNotifier(mail, cfg.Server.FrontendURL)
+	stockNotificationService := service.NewStockNotificationService(stockNotificationRepo, productRepo, userRepo, stockNotifier, cfg.Inventory.AlertEmails)
+	productService := service.NewProductService(productRepo, categoryRepo, stockMovementRepo, stockNotificationService)
+	couponService := service.NewCouponService(couponRepo, productRepo, categoryRepo, cfg.Cart.ShippingFee)
+	guestCartStore := service.NewGuestCartStore(redis, cfg.Cart.GuestTTL)
+	cartService := service.NewCartService(cartRepo, productRepo, guestCartStore, cfg.Cart.TokenSecret, couponService)
+	inventoryService := service.NewInventoryService(reservationRepo, orderRepo, cfg.Inventory.ReservationTTL, stockNotificationService, couponService)
+	warehouseService := service.NewWarehouseService(warehouseRepo, stockMovementRepo, productRepo, orderRepo, reservationRepo)
+	orderService := service.NewOrderService(orderRepo, cartRepo, productRepo, userRepo, addressRepo, inventoryService, warehouseService, couponService, cfg.Auth.RequireEmailVerification)
+	downloadService := service.NewDownloadService(downloadRepo, orderRepo, productImageRepo, privateStorage, cfg.Download.SigningSecret, cfg.Server.BaseURL, cfg.Download.URLExpiry, cfg.Download.MaxDownloads)
+	licenseService := service.NewLicenseService(licenseRepo, productRepo, orderRepo, cfg.Server.BaseURL)
+	paymentService := service.NewPaymentService(paymentRepo, orderRepo, inventoryService, downloadService, licenseService, cfg.Stripe.SecretKey) // NEW
+	rbacService := service.NewRBACService(roleRepo, userRepo, tokenService)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, roleRepo)
+	addressService := service.NewAddressService(addressRepo)
+	privacyService := service.NewPrivacyService(userRepo, addressRepo, orderRepo, paymentRepo, cartRepo, reviewRepo, downloadRepo, licenseRepo, identityRepo, apiKeyRepo, loginAttemptRepo, dataErasureRepo, stockNotificationRepo, tokenService, mail)
+	categoryService := service.NewCategoryService(categoryRepo)
+	variantService := service.NewVariantService(variantRepo, productRepo, stockMovementRepo, stockNotificationService)
+	reviewService := service.NewReviewService(reviewRepo, productRepo)
+	productImageService := service.NewProductImageService(productImageRepo, productRepo, fileStorage, privateStorage, cfg.Storage.WatermarkText, cfg.Storage.MaxUploadSize)
+	productImportService := service.NewProductImportService(productImportRepo, productRepo, categoryRepo, productService)
+	stockMovementService := service.NewStockMovementService(stockMovementRepo, productRepo, warehouseRepo, orderRepo, stockNotificationService)
+	oauthService := service.NewOAuthService(oidcProviders, userRepo, identityRepo, oauthStateRepo, loginAttemptRepo, tokenService)
+
+	// 組み込みの権限と管理者ロールを作成
+	if err := rbacService.SeedDefaults(); err != nil {
+		log.Fatal("Failed to seed roles:", err)
+	}
+
+	// 商品検索用の全文検索・トライグラムインデックスを作成
+	if err := productRepo.EnsureSearchIndexes(); err != nil {
+		log.Fatal("Failed to create search indexes:", err)
+	}
+
+	// 文字列で保存されていた商品カテゴリをカテゴリテーブルに移行
+	if err := categoryService.MigrateLegacyCategories(); err != nil {
+		log.Fatal("Failed to migrate categories:", err)
+	}
+
+	// 一括インポート・エクスポートの照合に使う商品コードを既存の商品に割り当て
+	if err := productRepo.AssignMissingSKUs(); err != nil {
+		log.Fatal("Failed to assign product SKUs:", err)
+	}
+
+	// 前回の起動中に中断された商品インポートのジョブを失敗にする
+	if err := productImportService.FailInterruptedJobs(); err != nil {
+		log.Fatal("Failed to update product import jobs:", err)
+	}
+
+	// 既定の倉庫を作成し、倉庫ごとの管理を始める前の在庫・在庫予約を既定の倉庫に移行
+	if _, err := warehouseService.EnsureDefaultWarehouse(cfg.Inventory.DefaultWarehousePrefecture); err != nil {
+		log.Fatal("Failed to set up default warehouse:", err)
+	}
+
+	// 在庫の変動履歴の記録を始める前からある在庫を初期値として記録
+	if recorded, err := stockMovementService.RecordOpeningBalances(); err != nil {
+		log.Fatal("Failed to record opening stock balances:", err)
+	} else if recorded > 0 {
+		log.Printf("Recorded opening stock balances for %d products and variants", recorded)
+	}
+
+	// 透かし導入前にアップロードされた画像のプレビューを作り直し、元画像を非公開の保存先に移す
+	if err := productImageService.MigrateWatermarks(context.Background()); err != nil {
+		log.Fatal("Failed to migrate product images:", err)
+	}
+
+	// 決済期限を過ぎた注文の在庫予約を定期的に解放
+	go inventoryService.RunSweeper(context.Background(), cfg.Inventory.SweepInterval)
+
+	// 期限切れのリフレッシュトークン・失効エントリを定期的に削除
+	go tokenService.RunCleanup(context.Background(), cfg.JWT.CleanupInterval)
 
 	// ハンドラーの初期化
-	userHandler := handler.NewUserHandler(userService)
+	userHandler := handler.NewUserHandler(userService, cartService)
+	mfaHandler := handler.NewMFAHandler(mfaService, cartService)
 	productHandler := handler.NewProductHandler(productService)
-	cartHandler := handler.NewCartHandler(cartService)
+	cartHandler := handler.NewCartHandler(cartService, cfg.Cart.GuestTTL)
 	orderHandler := handler.NewOrderHandler(orderService, db)
-	paymentHandler := handler.NewPaymentHandler(paymentService, cfg.Stripe.WebhookSecret) // NEW
+	paymentHandler := handler.NewPaymentHandler(paymentService, cfg.Stripe.WebhookSecret, db) // NEW
+	roleHandler := handler.NewRoleHandler(rbacService)
+	oauthHandler := handler.NewOAuthHandler(oauthService, cartService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	jwksHandler := handler.NewJWKSHandler(jwtKeys)
+	addressHandler := handler.NewAddressHandler(addressService)
+	privacyHandler := handler.NewPrivacyHandler(privacyService)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+	variantHandler := handler.NewVariantHandler(variantService)
+	reviewHandler := handler.NewReviewHandler(reviewService)
+	productImageHandler := handler.NewProductImageHandler(productImageService, cfg.Storage.MaxUploadSize)
+	downloadHandler := handler.NewDownloadHandler(downloadService)
+	licenseHandler := handler.NewLicenseHandler(licenseService)
+	productImportHandler := handler.NewProductImportHandler(productImportService)
+	stockNotificationHandler := handler.NewStockNotificationHandler(stockNotificationService)
+	stockMovementHandler := handler.NewStockMovementHandler(stockMovementService)
+	warehouseHandler := handler.NewWarehouseHandler(warehouseService)
+	couponHandler := handler.NewCouponHandler(couponService)
 
 	// Ginルーターの初期化
 	router := gin.Default()
+	// ログイン試行の制限などで使う接続元IPを偽装されないよう、X-Forwarded-Forは設定したプロキシからのみ信頼する
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// ミドルウェアの設定
 	router.Use(middleware.CORSMiddleware())
@@ -84,6 +305,14 @@ func main() {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// localドライバーでアップロードされた画像の配信
+	if cfg.Storage.Driver == "local" {
+		router.Static("/uploads", cfg.Storage.LocalDir)
+	}
+
+	// アクセストークン検証用の公開鍵
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	// APIルート
 	api := router.Group("/api")
 	{
@@ -92,8 +321,26 @@ func main() {
 		{
 			auth.POST("/register", userHandler.Register)
 			auth.POST("/login", userHandler.Login)
+			auth.POST("/login/mfa", mfaHandler.VerifyLogin)
+			auth.POST("/refresh", userHandler.Refresh)
+			auth.POST("/forgot-password", userHandler.ForgotPassword)
+			auth.POST("/reset-password", userHandler.ResetPassword)
+			auth.POST("/verify-email", userHandler.VerifyEmail)
+			// ソーシャルログイン
+			auth.GET("/oauth/providers", oauthHandler.ListProviders)
+			auth.GET("/oauth/:provider/authorize", oauthHandler.Authorize)
+			auth.POST("/oauth/:provider/callback", oauthHandler.Callback)
 			// 認証が必要なルート
-			auth.GET("/me", middleware.AuthMiddleware(cfg.JWT.Secret), userHandler.GetProfile)
+			auth.GET("/me", middleware.AuthMiddleware(tokenService), userHandler.GetProfile)
+			auth.POST("/logout", middleware.AuthMiddleware(tokenService), userHandler.Logout)
+			auth.POST("/resend-verification", middleware.AuthMiddleware(tokenService), userHandler.ResendVerificationEmail)
+		}
+
+		// カテゴリ（認証不要）
+		categories := api.Group("/categories")
+		{
+			categories.GET("", categoryHandler.ListCategories)
+			categories.GET("/:id", categoryHandler.GetCategory)
 		}
 
 		// 商品関連（認証不要）
@@ -101,66 +348,241 @@ func main() {
 		{
 			products.GET("", productHandler.ListProducts)
 			products.GET("/:id", productHandler.GetProductByID)
+			products.GET("/:id/variants", variantHandler.ListVariants)
+			products.GET("/:id/images", productImageHandler.ListImages)
+			products.GET("/:id/licenses", licenseHandler.ListProductLicenses)
+			products.GET("/:id/reviews", reviewHandler.ListProductReviews)
 			products.GET("/category/:category", productHandler.GetProductsByCategory)
 			products.GET("/search", productHandler.SearchProducts)
 		}
 
+		// ライセンスIDの検証（認証不要）
+		api.GET("/licenses/:id/verify", licenseHandler.VerifyLicense)
+
+		// 入荷通知メールの配信停止リンク（認証不要）
+		api.POST("/stock-notifications/unsubscribe", stockNotificationHandler.UnsubscribeByToken)
+
+		// 購入した元画像のダウンロード（署名付きURLで認可するため認証不要）
+		api.GET("/downloads/:grantId/:imageId", downloadHandler.Download)
+
 		// Stripe Webhook（認証不要）NEW
-		api.POST("/webhooks/stripe", paymentHandler.HandleWebhook)  // StripeWebhook → HandleWebhook
+		api.POST("/webhooks/stripe", paymentHandler.HandleWebhook) // StripeWebhook → HandleWebhook
+
+		// カート関連（未ログインの場合はX-Cart-Tokenヘッダー・Cookieのトークンでゲストカートを操作）
+		cart := api.Group("/cart")
+		cart.Use(middleware.OptionalAuthMiddleware(tokenService))
+		{
+			cart.GET("", cartHandler.GetCart)
+			cart.POST("/items", cartHandler.AddToCart)
+			cart.PUT("/items/:id", cartHandler.UpdateCartItem)
+			cart.DELETE("/items/:id", cartHandler.RemoveFromCart)
+			cart.DELETE("", cartHandler.ClearCart)
+			cart.POST("/coupon", cartHandler.ApplyCoupon)
+			cart.DELETE("/coupon", cartHandler.RemoveCoupon)
+		}
 
 		// 認証が必要なルート
 		authenticated := api.Group("")
-		authenticated.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+		authenticated.Use(middleware.AuthMiddleware(tokenService))
 		{
 			// ユーザー関連
 			users := authenticated.Group("/users")
 			{
 				users.GET("/profile", userHandler.GetProfile)
 				users.PUT("/profile", userHandler.UpdateUser)
-			}
 
-			// カート関連
-			cart := authenticated.Group("/cart")
-			{
-				cart.GET("", cartHandler.GetCart)
-				cart.POST("/items", cartHandler.AddToCart)
-				cart.PUT("/items/:id", cartHandler.UpdateCartItem)
-				cart.DELETE("/items/:id", cartHandler.RemoveFromCart)
-				cart.DELETE("", cartHandler.ClearCart)
+				// 2段階認証
+				users.POST("/2fa/setup", mfaHandler.SetupTOTP)
+				users.POST("/2fa/enable", mfaHandler.EnableTOTP)
+				users.POST("/2fa/disable", mfaHandler.DisableTOTP)
+				users.POST("/2fa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+
+				// 外部アカウント連携
+				users.GET("/identities", oauthHandler.ListIdentities)
+				users.POST("/identities/:provider/authorize", oauthHandler.AuthorizeLink)
+				users.POST("/identities/:provider/callback", oauthHandler.LinkCallback)
+				users.DELETE("/identities/:id", oauthHandler.Unlink)
+
+				// APIキー
+				users.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+				users.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+				users.PUT("/api-keys/:id", apiKeyHandler.UpdateAPIKey)
+				users.DELETE("/api-keys/:id", apiKeyHandler.DeleteAPIKey)
+
+				// 住所録
+				users.GET("/addresses", addressHandler.ListAddresses)
+				users.POST("/addresses", addressHandler.CreateAddress)
+				users.GET("/addresses/:id", addressHandler.GetAddress)
+				users.PUT("/addresses/:id", addressHandler.UpdateAddress)
+				users.DELETE("/addresses/:id", addressHandler.DeleteAddress)
+				users.PUT("/addresses/:id/default", addressHandler.SetDefaultAddress)
+
+				// 個人データのエクスポート・削除依頼
+				users.GET("/data-export", privacyHandler.ExportData)
+				users.GET("/erasure-request", privacyHandler.GetErasureRequest)
+				users.POST("/erasure-request", privacyHandler.RequestErasure)
+				users.DELETE("/erasure-request", privacyHandler.CancelErasureRequest)
+
+				// 投稿したレビュー
+				users.GET("/reviews", reviewHandler.ListMyReviews)
 			}
 
-			// 注文関連
-			orders := authenticated.Group("/orders")
+			// レビュー関連
+			reviews := authenticated.Group("/reviews")
 			{
-				orders.POST("", orderHandler.CreateOrder)
-				orders.GET("", orderHandler.GetUserOrders)
-				orders.GET("/:id", orderHandler.GetOrderByID)
+				reviews.POST("", reviewHandler.CreateReview)
+				reviews.PUT("/:id", reviewHandler.UpdateReview)
+				reviews.DELETE("/:id", reviewHandler.DeleteReview)
+				reviews.POST("/:id/helpful", reviewHandler.VoteHelpful)
+				reviews.DELETE("/:id/helpful", reviewHandler.RemoveHelpfulVote)
 			}
 
+			// 在庫切れ商品の入荷通知
+			authenticated.POST("/products/:id/notify-me", stockNotificationHandler.Subscribe)
+			authenticated.DELETE("/products/:id/notify-me", stockNotificationHandler.Unsubscribe)
+
+			// ゲストカートのユーザーのカートへの統合
+			authenticated.POST("/cart/merge", cartHandler.MergeCart)
+
 			// 決済関連（NEW）
 			payment := authenticated.Group("/payment")
 			{
 				payment.POST("/create-intent", paymentHandler.CreatePaymentIntent)
 				payment.GET("/order", paymentHandler.GetPaymentByOrderID)
 			}
+		}
 
-			// 管理者専用ルート
-			admin := authenticated.Group("/admin")
-			admin.Use(middleware.AdminMiddleware())
+		// JWTまたはAPIキーで認証するルート（APIキーはスコープで制限）
+		integration := api.Group("")
+		integration.Use(middleware.AuthOrAPIKeyMiddleware(tokenService, apiKeyService))
+		{
+			// 注文関連
+			orders := integration.Group("/orders")
+			orders.Use(middleware.RequireScope(model.APIKeyScopeOrders))
+			{
+				orders.POST("", orderHandler.CreateOrder)
+				orders.GET("", orderHandler.GetUserOrders)
+				orders.GET("/:id", orderHandler.GetOrderByID)
+				orders.GET("/:id/downloads", downloadHandler.ListOrderDownloads)
+				orders.GET("/:id/licenses", licenseHandler.ListOrderLicenses)
+				orders.GET("/:id/licenses/:licenseId/certificate", licenseHandler.GetCertificate)
+			}
+
+			// 管理者専用ルート（ルートグループごとに必要な権限を指定する）
+			admin := integration.Group("/admin")
+			requireAdmin := func(permissions ...string) gin.HandlerFunc {
+				return middleware.AdminMiddleware(cfg.Auth.RequireAdminMFA, permissions...)
+			}
 			{
 				// ユーザー管理
-				admin.GET("/users", userHandler.ListUsers)
-				admin.GET("/users/:id", userHandler.GetUserByID)
-				admin.DELETE("/users/:id", userHandler.DeleteUser)
+				adminUsers := admin.Group("")
+				adminUsers.Use(middleware.RequireScope(model.APIKeyScopeAdmin))
+				{
+					usersRead := adminUsers.Group("", requireAdmin(model.PermissionUsersRead))
+					usersRead.GET("/users", userHandler.ListUsers)
+					usersRead.GET("/users/:id", userHandler.GetUserByID)
+					usersRead.GET("/users/:id/login-attempts", userHandler.ListLoginAttempts)
+					usersRead.GET("/erasure-requests", privacyHandler.ListErasureRequests)
+
+					usersWrite := adminUsers.Group("", requireAdmin(model.PermissionUsersWrite))
+					usersWrite.POST("/users/:id/unlock", userHandler.UnlockUser)
+
+					// ユーザーの削除・個人データ削除依頼の確認
+					usersDelete := adminUsers.Group("", requireAdmin(model.PermissionUsersDelete))
+					usersDelete.DELETE("/users/:id", userHandler.DeleteUser)
+					usersDelete.POST("/erasure-requests/:id/approve", privacyHandler.ApproveErasureRequest)
+					usersDelete.POST("/erasure-requests/:id/reject", privacyHandler.RejectErasureRequest)
+				}
 
 				// 商品管理
-				admin.POST("/products", productHandler.CreateProduct)
-				admin.PUT("/products/:id", productHandler.UpdateProduct)
-				admin.DELETE("/products/:id", productHandler.DeleteProduct)
+				adminProducts := admin.Group("")
+				adminProducts.Use(middleware.RequireScope(model.APIKeyScopeProducts, model.APIKeyScopeAdmin))
+				{
+					products := adminProducts.Group("", requireAdmin(model.PermissionProductsWrite))
+					products.POST("/products", productHandler.CreateProduct)
+					products.POST("/products/import", productImportHandler.ImportProducts)
+					products.GET("/products/import", productImportHandler.ListImportJobs)
+					products.GET("/products/import/:jobId", productImportHandler.GetImportJob)
+					products.GET("/products/export", productImportHandler.ExportProducts)
+					products.PUT("/products/:id", productHandler.UpdateProduct)
+					products.DELETE("/products/:id", productHandler.DeleteProduct)
+
+					// 選択肢・バリエーション（SKU）管理
+					products.PUT("/products/:id/options", variantHandler.SetOptions)
+					products.POST("/products/:id/variants", variantHandler.CreateVariant)
+					products.PUT("/products/:id/variants/:variantId", variantHandler.UpdateVariant)
+					products.DELETE("/products/:id/variants/:variantId", variantHandler.DeleteVariant)
+					products.POST("/products/:id/images", productImageHandler.UploadImages)
+					products.PUT("/products/:id/images/order", productImageHandler.ReorderImages)
+					products.PUT("/products/:id/images/:imageId", productImageHandler.UpdateImage)
+					products.DELETE("/products/:id/images/:imageId", productImageHandler.DeleteImage)
+					products.PUT("/products/:id/licenses", licenseHandler.SetLicenses)
+
+					// カテゴリ管理
+					products.POST("/categories", categoryHandler.CreateCategory)
+					products.PUT("/categories/:id", categoryHandler.UpdateCategory)
+					products.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+
+					// 在庫の調整・変動履歴
+					inventory := adminProducts.Group("", requireAdmin(model.PermissionInventoryWrite))
+					inventory.PUT("/products/:id/variants/:variantId/stock", variantHandler.UpdateVariantStock)
+					inventory.POST("/products/:id/stock/adjustments", stockMovementHandler.AdjustStock)
+					inventory.GET("/products/:id/stock/movements", stockMovementHandler.ListMovements)
+					inventory.GET("/stock/reconciliation", stockMovementHandler.Reconcile)
+
+					// 倉庫・倉庫ごとの在庫
+					inventory.GET("/warehouses", warehouseHandler.ListWarehouses)
+					inventory.POST("/warehouses", warehouseHandler.CreateWarehouse)
+					inventory.PUT("/warehouses/:id", warehouseHandler.UpdateWarehouse)
+					inventory.GET("/warehouses/:id/stock", warehouseHandler.ListWarehouseStock)
+					inventory.POST("/warehouses/transfers", warehouseHandler.TransferStock)
+					inventory.GET("/products/:id/warehouse-stock", warehouseHandler.ListProductStock)
+
+					// 在庫アラート
+					inventory.GET("/stock-alerts", stockNotificationHandler.ListAlerts)
+
+					// クーポン
+					coupons := adminProducts.Group("", requireAdmin(model.PermissionCouponsWrite))
+					coupons.GET("/coupons", couponHandler.ListCoupons)
+					coupons.POST("/coupons", couponHandler.CreateCoupon)
+					coupons.GET("/coupons/:id", couponHandler.GetCoupon)
+					coupons.PUT("/coupons/:id", couponHandler.UpdateCoupon)
+					coupons.DELETE("/coupons/:id", couponHandler.DeleteCoupon)
+
+					// レビューの確認
+					reviews := adminProducts.Group("", requireAdmin(model.PermissionReviewsModerate))
+					reviews.GET("/reviews", reviewHandler.ListReviews)
+					reviews.PUT("/reviews/:id/approve", reviewHandler.ApproveReview)
+					reviews.PUT("/reviews/:id/hide", reviewHandler.HideReview)
+				}
 
 				// 注文管理
-				admin.GET("/orders", orderHandler.GetAllOrders)
-				admin.PUT("/orders/:id/status", orderHandler.UpdateOrderStatus)
+				adminOrders := admin.Group("")
+				adminOrders.Use(middleware.RequireScope(model.APIKeyScopeOrders, model.APIKeyScopeAdmin))
+				{
+					ordersRead := adminOrders.Group("", requireAdmin(model.PermissionOrdersRead))
+					ordersRead.GET("/orders", orderHandler.GetAllOrders)
+					ordersRead.GET("/orders/:id/allocations", warehouseHandler.GetOrderAllocations)
+					ordersRead.GET("/downloads/logs", downloadHandler.ListDownloadLogs)
+
+					ordersUpdate := adminOrders.Group("", requireAdmin(model.PermissionOrdersUpdateStatus))
+					ordersUpdate.PUT("/orders/:id/status", orderHandler.UpdateOrderStatus)
+					ordersUpdate.POST("/licenses/:id/revoke", licenseHandler.RevokeLicense)
+				}
+
+				// ロール・権限管理
+				roles := admin.Group("")
+				roles.Use(middleware.RequireScope(model.APIKeyScopeAdmin), requireAdmin(model.PermissionRolesManage))
+				{
+					roles.GET("/permissions", roleHandler.ListPermissions)
+					roles.GET("/roles", roleHandler.ListRoles)
+					roles.GET("/roles/:id", roleHandler.GetRole)
+					roles.POST("/roles", roleHandler.CreateRole)
+					roles.PUT("/roles/:id", roleHandler.UpdateRole)
+					roles.DELETE("/roles/:id", roleHandler.DeleteRole)
+					roles.GET("/users/:id/roles", roleHandler.GetUserRoles)
+					roles.PUT("/users/:id/roles", roleHandler.SetUserRoles)
+				}
 			}
 		}
 	}
@@ -1,21 +1,35 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Stripe   StripeConfig
-	Env      string
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	Stripe    StripeConfig
+	Mail      MailConfig
+	Auth      AuthConfig
+	OAuth     OAuthConfig
+	Storage   StorageConfig
+	Download  DownloadConfig
+	Inventory InventoryConfig
+	Cart      CartConfig
+	Env       string
 }
 
 type ServerConfig struct {
-	Port string
+	Port        string
+	FrontendURL string // メール内リンクなどに使用するフロントエンドのURL
+	BaseURL     string // 署名付きダウンロードURLなどに使用するAPIのURL
+	// X-Forwarded-Forを信頼するリバースプロキシ（IP・CIDR）。未設定の場合は接続元のIPをそのまま使う
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -34,20 +48,109 @@ type RedisConfig struct {
 	DB       int
 }
 
+// 開発用のデフォルトシークレット（本番環境では起動を拒否する）
+const defaultJWTSecret = "your-secret-key"
+
 type JWTConfig struct {
-	Secret string
-	Expiry time.Duration
+	Secret          string        // HS256の共有シークレット（KeysDir未設定時のみ使用）
+	KeysDir         string        // RS256/EdDSAの鍵ディレクトリ
+	ActiveKeyID     string        // 署名に使用する鍵のkid
+	Issuer          string        // issクレーム
+	Expiry          time.Duration // アクセストークンの有効期限
+	RefreshExpiry   time.Duration // リフレッシュトークンの有効期限
+	CleanupInterval time.Duration // 期限切れのリフレッシュトークン・失効エントリを削除する間隔
 }
 
 type StripeConfig struct {
-	SecretKey      string
-	WebhookSecret  string
+	SecretKey     string
+	WebhookSecret string
+}
+
+type MailConfig struct {
+	Driver       string // smtp, file, memory
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	DropDir      string // fileドライバーの出力先
+}
+
+type AuthConfig struct {
+	RequireEmailVerification bool          // メール未確認ユーザーの注文をブロック
+	LoginMaxAttempts         int           // アカウントロックまでのログイン失敗回数
+	LoginMaxAttemptsPerIP    int           // IPブロックまでのログイン失敗回数
+	LoginAttemptWindow       time.Duration // 失敗回数を数える期間
+	LoginLockoutDuration     time.Duration // ロック期間
+	RequireAdminMFA          bool          // 管理者に2段階認証を必須化
+	MFAIssuer                string        // 認証アプリに表示する発行者名
+}
+
+type OAuthConfig struct {
+	RedirectURL string // プロバイダーからの戻り先（末尾にプロバイダー名を付与）
+	Providers   []OIDCProviderConfig
+}
+
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type StorageConfig struct {
+	Driver            string // local, s3
+	LocalDir          string // localドライバーの保存先（/uploadsとして配信）
+	PrivateDir        string // localドライバーの元画像の保存先（配信しない）
+	PublicURL         string // 公開URLのベース（未設定の場合はlocalは自身の/uploads、s3はバケットのURL）
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3PrivateBucket   string // 元画像用の非公開バケット（未設定の場合はS3Bucket）
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3UsePathStyle    bool   // MinIOなどのパス形式のエンドポイント
+	MaxUploadSize     int64  // 画像アップロードの上限（バイト）
+	WatermarkText     string // 公開用プレビューに入れる透かし文字
+}
+
+// 開発用のデフォルト署名鍵（本番環境では起動を拒否する）
+const defaultDownloadSigningSecret = "your-download-signing-secret"
+
+type DownloadConfig struct {
+	SigningSecret string        // ダウンロードURLのHMAC署名鍵
+	URLExpiry     time.Duration // ダウンロードURLの有効期限
+	MaxDownloads  int           // 1購入あたりのダウンロード回数の上限
+}
+
+type InventoryConfig struct {
+	ReservationTTL time.Duration // 注文作成から決済までの在庫の確保期間
+	SweepInterval  time.Duration // 期限切れの在庫予約を解放する間隔
+	AlertEmails    []string      // 在庫が発注点を下回った際の通知先
+	// 倉庫が未登録の場合に作成する既定の倉庫の所在地（都道府県）
+	DefaultWarehousePrefecture string
+}
+
+// 開発用のデフォルト署名鍵（本番環境では起動を拒否する）
+const defaultCartTokenSecret = "your-cart-token-secret"
+
+type CartConfig struct {
+	TokenSecret string        // ゲストカートのトークンのHMAC署名鍵
+	GuestTTL    time.Duration // 最後の操作からゲストカートを保持する期間
+	ShippingFee float64       // 注文ごとの送料（一律。送料無料のクーポンで値引きされる）
 }
 
 func Load() *Config {
+	frontendURL := getEnv("FRONTEND_URL", "http://localhost:3000")
+	port := getEnv("PORT", "8080")
+
 	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
+			Port:           port,
+			FrontendURL:    frontendURL,
+			BaseURL:        getEnv("API_BASE_URL", "http://localhost:"+port),
+			TrustedProxies: getEnvList("TRUSTED_PROXIES"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -64,20 +167,173 @@ func Load() *Config {
 			DB:       0,
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key"),
-			Expiry: 24 * time.Hour,
+			Secret:          getEnv("JWT_SECRET", defaultJWTSecret),
+			KeysDir:         getEnv("JWT_KEYS_DIR", ""),
+			ActiveKeyID:     getEnv("JWT_ACTIVE_KEY_ID", ""),
+			Issuer:          getEnv("JWT_ISSUER", "ec-site-api"),
+			Expiry:          getEnvDuration("JWT_EXPIRY", 15*time.Minute),
+			RefreshExpiry:   getEnvDuration("JWT_REFRESH_EXPIRY", 30*24*time.Hour),
+			CleanupInterval: getEnvDuration("JWT_CLEANUP_INTERVAL", time.Hour),
 		},
 		Stripe: StripeConfig{
 			SecretKey:     getEnv("STRIPE_SECRET_KEY", ""),
 			WebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
 		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "file"),
+			From:         getEnv("MAIL_FROM", "no-reply@example.com"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			DropDir:      getEnv("MAIL_DROP_DIR", "tmp/mail"),
+		},
+		Auth: AuthConfig{
+			RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+			LoginMaxAttempts:         getEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+			LoginMaxAttemptsPerIP:    getEnvInt("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+			LoginAttemptWindow:       getEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+			LoginLockoutDuration:     getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			RequireAdminMFA:          getEnvBool("REQUIRE_ADMIN_MFA", false),
+			MFAIssuer:                getEnv("MFA_ISSUER", "Shiba Image Store"),
+		},
+		OAuth: OAuthConfig{
+			RedirectURL: getEnv("OAUTH_REDIRECT_URL", frontendURL+"/auth/callback"),
+			Providers:   loadOIDCProviders(),
+		},
+		Storage: StorageConfig{
+			Driver:            getEnv("STORAGE_DRIVER", "local"),
+			LocalDir:          getEnv("STORAGE_LOCAL_DIR", "uploads"),
+			PrivateDir:        getEnv("STORAGE_PRIVATE_DIR", "private_uploads"),
+			PublicURL:         getEnv("STORAGE_PUBLIC_URL", ""),
+			S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+			S3Region:          getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:          getEnv("S3_BUCKET", ""),
+			S3PrivateBucket:   getEnv("S3_PRIVATE_BUCKET", ""),
+			S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+			S3UsePathStyle:    getEnvBool("S3_USE_PATH_STYLE", false),
+			MaxUploadSize:     int64(getEnvInt("IMAGE_MAX_UPLOAD_MB", 10)) << 20,
+			WatermarkText:     getEnv("WATERMARK_TEXT", "SAMPLE"),
+		},
+		Download: DownloadConfig{
+			SigningSecret: getEnv("DOWNLOAD_SIGNING_SECRET", defaultDownloadSigningSecret),
+			URLExpiry:     getEnvDuration("DOWNLOAD_URL_EXPIRY", 15*time.Minute),
+			MaxDownloads:  getEnvInt("DOWNLOAD_MAX_COUNT", 5),
+		},
+		Inventory: InventoryConfig{
+			ReservationTTL:             getEnvDuration("RESERVATION_TTL", 30*time.Minute),
+			SweepInterval:              getEnvDuration("RESERVATION_SWEEP_INTERVAL", time.Minute),
+			AlertEmails:                getEnvList("STOCK_ALERT_EMAILS"),
+			DefaultWarehousePrefecture: getEnv("DEFAULT_WAREHOUSE_PREFECTURE", "東京都"),
+		},
+		Cart: CartConfig{
+			TokenSecret: getEnv("CART_TOKEN_SECRET", defaultCartTokenSecret),
+			GuestTTL:    getEnvDuration("GUEST_CART_TTL", 7*24*time.Hour),
+			ShippingFee: float64(getEnvInt("SHIPPING_FEE", 0)),
+		},
 		Env: getEnv("ENV", "development"),
 	}
 }
 
+// 起動時の設定チェック
+func (c *Config) Validate() error {
+	if c.Env == "production" && c.JWT.KeysDir == "" {
+		if c.JWT.Secret == defaultJWTSecret {
+			return errors.New("JWT_SECRET must not be the default value in production; set JWT_KEYS_DIR or a strong JWT_SECRET")
+		}
+		if len(c.JWT.Secret) < 32 {
+			return errors.New("JWT_SECRET must be at least 32 characters in production")
+		}
+	}
+	if c.Env == "production" {
+		if c.Download.SigningSecret == defaultDownloadSigningSecret || len(c.Download.SigningSecret) < 32 {
+			return errors.New("DOWNLOAD_SIGNING_SECRET must be set to at least 32 characters in production")
+		}
+		if c.Cart.TokenSecret == defaultCartTokenSecret || len(c.Cart.TokenSecret) < 32 {
+			return errors.New("CART_TOKEN_SECRET must be set to at least 32 characters in production")
+		}
+	}
+	return nil
+}
+
+// クライアントIDが設定されているプロバイダーのみ有効にする
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+
+	if clientID := getEnv("GOOGLE_CLIENT_ID", ""); clientID != "" {
+		providers = append(providers, OIDCProviderConfig{
+			Name:         "google",
+			Issuer:       "https://accounts.google.com",
+			ClientID:     clientID,
+			ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+		})
+	}
+
+	if clientID := getEnv("LINE_CHANNEL_ID", ""); clientID != "" {
+		providers = append(providers, OIDCProviderConfig{
+			Name:         "line",
+			Issuer:       "https://access.line.me",
+			ClientID:     clientID,
+			ClientSecret: getEnv("LINE_CHANNEL_SECRET", ""),
+		})
+	}
+
+	// 汎用OIDCプロバイダー（ローカルのテスト用発行者にも使用できる）
+	if clientID := getEnv("OIDC_CLIENT_ID", ""); clientID != "" {
+		providers = append(providers, OIDCProviderConfig{
+			Name:         getEnv("OIDC_PROVIDER_NAME", "oidc"),
+			Issuer:       getEnv("OIDC_ISSUER", ""),
+			ClientID:     clientID,
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv("OIDC_SCOPES", "")),
+		})
+	}
+
+	return providers
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// カンマ区切りの値（空の要素は除く）
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
 }
\ No newline at end of file
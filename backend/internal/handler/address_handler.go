@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AddressHandler struct {
+	addressService service.AddressService
+}
+
+func NewAddressHandler(addressService service.AddressService) *AddressHandler {
+	return &AddressHandler{
+		addressService: addressService,
+	}
+}
+
+// AddressRequest 住所登録・更新リクエスト
+type AddressRequest struct {
+	RecipientName string `json:"recipient_name" binding:"required"`
+	PostalCode    string `json:"postal_code" binding:"required"`
+	Prefecture    string `json:"prefecture" binding:"required"`
+	City          string `json:"city" binding:"required"`
+	Line1         string `json:"line1" binding:"required"`
+	Line2         string `json:"line2"`
+	Phone         string `json:"phone" binding:"required"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+func (r *AddressRequest) toModel() *model.Address {
+	return &model.Address{
+		RecipientName: r.RecipientName,
+		PostalCode:    r.PostalCode,
+		Prefecture:    r.Prefecture,
+		City:          r.City,
+		Line1:         r.Line1,
+		Line2:         r.Line2,
+		Phone:         r.Phone,
+		IsDefault:     r.IsDefault,
+	}
+}
+
+// ListAddresses 住所一覧取得
+func (h *AddressHandler) ListAddresses(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	addresses, err := h.addressService.ListAddresses(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"addresses": addresses})
+}
+
+// GetAddress 住所取得
+func (h *AddressHandler) GetAddress(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	address, err := h.addressService.GetAddress(userID.(uint), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"address": address})
+}
+
+// CreateAddress 住所登録
+func (h *AddressHandler) CreateAddress(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := h.addressService.CreateAddress(userID.(uint), req.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Address created successfully",
+		"address": address,
+	})
+}
+
+// UpdateAddress 住所更新
+func (h *AddressHandler) UpdateAddress(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	var req AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address, err := h.addressService.UpdateAddress(userID.(uint), uint(id), req.toModel())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Address updated successfully",
+		"address": address,
+	})
+}
+
+// DeleteAddress 住所削除
+func (h *AddressHandler) DeleteAddress(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	if err := h.addressService.DeleteAddress(userID.(uint), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted successfully"})
+}
+
+// SetDefaultAddress デフォルトの住所に設定
+func (h *AddressHandler) SetDefaultAddress(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+
+	address, err := h.addressService.SetDefaultAddress(userID.(uint), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Default address updated successfully",
+		"address": address,
+	})
+}
@@ -1,27 +1,40 @@
 package handler
 
 import (
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/Naonao3/EC-site/backend/internal/model"
 	"github.com/Naonao3/EC-site/backend/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
+// ゲストカートのトークンを受け渡すヘッダー・Cookie
+const (
+	guestCartHeader = "X-Cart-Token"
+	guestCartCookie = "cart_token"
+)
+
 type CartHandler struct {
-	cartService service.CartService
+	cartService  service.CartService
+	guestCartTTL time.Duration
 }
 
-func NewCartHandler(cartService service.CartService) *CartHandler {
+func NewCartHandler(cartService service.CartService, guestCartTTL time.Duration) *CartHandler {
 	return &CartHandler{
-		cartService: cartService,
+		cartService:  cartService,
+		guestCartTTL: guestCartTTL,
 	}
 }
 
 // AddToCartRequest カート追加リクエスト
 type AddToCartRequest struct {
-	ProductID uint `json:"product_id" binding:"required"`
-	Quantity  int  `json:"quantity" binding:"required,min=1"`
+	ProductID   uint   `json:"product_id" binding:"required"`
+	VariantID   *uint  `json:"variant_id"`   // バリエーションのある商品の場合は必須
+	LicenseType string `json:"license_type"` // ライセンスのある商品の場合は必須（personal, commercial, extended）
+	Quantity    int    `json:"quantity" binding:"required,min=1"`
 }
 
 // UpdateCartItemRequest カートアイテム更新リクエスト
@@ -29,15 +42,20 @@ type UpdateCartItemRequest struct {
 	Quantity int `json:"quantity" binding:"required,min=1"`
 }
 
-// GetCart カート取得
+// ApplyCouponRequest クーポン適用リクエスト（コードの大文字・小文字は区別しない）
+type ApplyCouponRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GetCart カート取得（未ログインの場合はゲストカート）
 func (h *CartHandler) GetCart(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
+	var cart *model.Cart
+	var err error
+	if userID, exists := c.Get("user_id"); exists {
+		cart, err = h.cartService.GetCart(userID.(uint))
+	} else {
+		cart, err = h.cartService.GetGuestCart(guestCartToken(c))
 	}
-
-	cart, err := h.cartService.GetCart(userID.(uint))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -46,40 +64,44 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"cart": cart})
 }
 
-// AddToCart カートに追加
+// AddToCart カートに追加（未ログインの場合はゲストカートに追加し、そのトークンを返す）
 func (h *CartHandler) AddToCart(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
 	var req AddToCartRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	item, err := h.cartService.AddToCart(userID.(uint), req.ProductID, req.Quantity)
+	if userID, exists := c.Get("user_id"); exists {
+		item, err := h.cartService.AddToCart(userID.(uint), req.ProductID, req.VariantID, req.LicenseType, req.Quantity)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Item added to cart successfully",
+			"item":    item,
+		})
+		return
+	}
+
+	token, item, err := h.cartService.AddToGuestCart(guestCartToken(c), req.ProductID, req.VariantID, req.LicenseType, req.Quantity)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	h.setGuestCartToken(c, token)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Item added to cart successfully",
-		"item":    item,
+		"message":    "Item added to cart successfully",
+		"item":       item,
+		"cart_token": token,
 	})
 }
 
 // UpdateCartItem カートアイテム更新
 func (h *CartHandler) UpdateCartItem(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
 	cartItemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item ID"})
@@ -92,7 +114,12 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 		return
 	}
 
-	item, err := h.cartService.UpdateCartItem(userID.(uint), uint(cartItemID), req.Quantity)
+	var item *model.CartItem
+	if userID, exists := c.Get("user_id"); exists {
+		item, err = h.cartService.UpdateCartItem(userID.(uint), uint(cartItemID), req.Quantity)
+	} else {
+		item, err = h.cartService.UpdateGuestCartItem(guestCartToken(c), uint(cartItemID), req.Quantity)
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -106,19 +133,18 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 
 // RemoveFromCart カートから削除
 func (h *CartHandler) RemoveFromCart(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
 	cartItemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item ID"})
 		return
 	}
 
-	if err := h.cartService.RemoveFromCart(userID.(uint), uint(cartItemID)); err != nil {
+	if userID, exists := c.Get("user_id"); exists {
+		err = h.cartService.RemoveFromCart(userID.(uint), uint(cartItemID))
+	} else {
+		err = h.cartService.RemoveFromGuestCart(guestCartToken(c), uint(cartItemID))
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -128,16 +154,130 @@ func (h *CartHandler) RemoveFromCart(c *gin.Context) {
 
 // ClearCart カートをクリア
 func (h *CartHandler) ClearCart(c *gin.Context) {
+	if userID, exists := c.Get("user_id"); exists {
+		if err := h.cartService.ClearCart(userID.(uint)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else if token := guestCartToken(c); token != "" {
+		if err := h.cartService.ClearGuestCart(token); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		clearGuestCartToken(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared successfully"})
+}
+
+// ApplyCoupon カートにクーポンを適用（未ログインの場合はゲストカートに適用）
+func (h *CartHandler) ApplyCoupon(c *gin.Context) {
+	var req ApplyCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var cart *model.Cart
+	var err error
+	if userID, exists := c.Get("user_id"); exists {
+		cart, err = h.cartService.ApplyCoupon(userID.(uint), req.Code)
+	} else {
+		cart, err = h.cartService.ApplyGuestCoupon(guestCartToken(c), req.Code)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon applied successfully",
+		"cart":    cart,
+	})
+}
+
+// RemoveCoupon カートからクーポンを外す
+func (h *CartHandler) RemoveCoupon(c *gin.Context) {
+	if userID, exists := c.Get("user_id"); exists {
+		if err := h.cartService.RemoveCoupon(userID.(uint)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else if token := guestCartToken(c); token != "" {
+		if err := h.cartService.RemoveGuestCoupon(token); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon removed successfully"})
+}
+
+// MergeCart ゲストカートをログイン中のユーザーのカートに統合（ソーシャルログイン・2段階認証でのログイン後に使用）
+func (h *CartHandler) MergeCart(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	if err := h.cartService.ClearCart(userID.(uint)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	token := guestCartToken(c)
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart token is required"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared successfully"})
+	result, err := h.cartService.MergeGuestCart(userID.(uint), token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	clearGuestCartToken(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Guest cart merged successfully",
+		"cart_merge": result,
+	})
+}
+
+// ログイン・登録時にゲストカートがあればユーザーのカートに統合（失敗してもログインは成功させる）
+func mergeGuestCart(c *gin.Context, cartService service.CartService, userID uint) *model.CartMergeResult {
+	token := guestCartToken(c)
+	if token == "" {
+		return nil
+	}
+
+	result, err := cartService.MergeGuestCart(userID, token)
+	if err != nil {
+		log.Printf("Failed to merge guest cart for user %d: %v", userID, err)
+		return nil
+	}
+	clearGuestCartToken(c)
+	return result
+}
+
+// リクエストのゲストカートのトークン（ヘッダーを優先し、無い場合はCookie）
+func guestCartToken(c *gin.Context) string {
+	if token := c.GetHeader(guestCartHeader); token != "" {
+		return token
+	}
+	token, _ := c.Cookie(guestCartCookie)
+	return token
+}
+
+// 新しいゲストカートのトークンをヘッダーとCookieで返す
+func (h *CartHandler) setGuestCartToken(c *gin.Context, token string) {
+	c.Header(guestCartHeader, token)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(guestCartCookie, token, int(h.guestCartTTL.Seconds()), "/api", "", isSecureRequest(c), true)
+}
+
+func clearGuestCartToken(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(guestCartCookie, "", -1, "/api", "", isSecureRequest(c), true)
+}
+
+// HTTPSでのリクエストか（リバースプロキシでTLSを終端する場合はX-Forwarded-Protoで判定）
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
 }
\ No newline at end of file
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type CouponHandler struct {
+	couponService service.CouponService
+}
+
+func NewCouponHandler(couponService service.CouponService) *CouponHandler {
+	return &CouponHandler{couponService: couponService}
+}
+
+// CouponRequest クーポンの作成・更新リクエスト
+type CouponRequest struct {
+	Code         string     `json:"code" binding:"required"`
+	Description  string     `json:"description"`
+	Type         string     `json:"type" binding:"required"` // percentage, fixed_amount, free_shipping
+	Value        float64    `json:"value"`                   // 割合（%）または値引き額
+	MaxDiscount  float64    `json:"max_discount"`
+	MinSubtotal  float64    `json:"min_subtotal"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	UsageLimit   int        `json:"usage_limit"`
+	PerUserLimit int        `json:"per_user_limit"`
+	Active       *bool      `json:"active"`       // 省略した場合は有効
+	ProductIDs   []uint     `json:"product_ids"`  // 対象の商品
+	CategoryIDs  []uint     `json:"category_ids"` // 対象のカテゴリ（子孫カテゴリを含む）
+}
+
+func (req *CouponRequest) coupon() *model.Coupon {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return &model.Coupon{
+		Code:         req.Code,
+		Description:  req.Description,
+		Type:         req.Type,
+		Value:        req.Value,
+		MaxDiscount:  req.MaxDiscount,
+		MinSubtotal:  req.MinSubtotal,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		UsageLimit:   req.UsageLimit,
+		PerUserLimit: req.PerUserLimit,
+		Active:       active,
+		ProductIDs:   req.ProductIDs,
+		CategoryIDs:  req.CategoryIDs,
+	}
+}
+
+// ListCoupons クーポン一覧
+func (h *CouponHandler) ListCoupons(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	coupons, total, err := h.couponService.ListCoupons(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coupons":   coupons,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetCoupon クーポン詳細
+func (h *CouponHandler) GetCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	coupon, err := h.couponService.GetCoupon(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"coupon": coupon})
+}
+
+// CreateCoupon クーポン作成
+func (h *CouponHandler) CreateCoupon(c *gin.Context) {
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coupon := req.coupon()
+	if err := h.couponService.CreateCoupon(coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Coupon created successfully",
+		"coupon":  coupon,
+	})
+}
+
+// UpdateCoupon クーポン更新
+func (h *CouponHandler) UpdateCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	var req CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coupon := req.coupon()
+	coupon.ID = uint(id)
+	if err := h.couponService.UpdateCoupon(coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon updated successfully",
+		"coupon":  coupon,
+	})
+}
+
+// DeleteCoupon クーポン削除（注文で利用されたクーポンは削除できない）
+func (h *CouponHandler) DeleteCoupon(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	if err := h.couponService.DeleteCoupon(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted successfully"})
+}
\ No newline at end of file
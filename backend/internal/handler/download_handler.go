@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type DownloadHandler struct {
+	downloadService service.DownloadService
+}
+
+func NewDownloadHandler(downloadService service.DownloadService) *DownloadHandler {
+	return &DownloadHandler{
+		downloadService: downloadService,
+	}
+}
+
+// ListOrderDownloads 購入した画像の署名付きダウンロードURL一覧取得
+func (h *DownloadHandler) ListOrderDownloads(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	downloads, err := h.downloadService.ListOrderDownloads(userID.(uint), uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"downloads": downloads})
+}
+
+// Download 署名付きURLによる元画像のダウンロード（認証不要）
+func (h *DownloadHandler) Download(c *gin.Context) {
+	grantID, err := strconv.ParseUint(c.Param("grantId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid download ID"})
+		return
+	}
+
+	imageID, err := strconv.ParseUint(c.Param("imageId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	file, err := h.downloadService.Download(
+		c.Request.Context(),
+		uint(grantID),
+		uint(imageID),
+		c.Query("expires"),
+		c.Query("signature"),
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDownloadInvalidSignature):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrDownloadExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrDownloadLimitExceeded):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrDownloadUnavailable):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download file"})
+		}
+		return
+	}
+	defer file.Body.Close()
+
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, file.Body, map[string]string{
+		"Content-Disposition":    `attachment; filename="` + file.FileName + `"`,
+		"Cache-Control":          "private, no-store",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// ListDownloadLogs ダウンロードのアクセスログ一覧（管理者用）
+func (h *DownloadHandler) ListDownloadLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	var orderID uint64
+	if value := c.Query("order_id"); value != "" {
+		var err error
+		orderID, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+			return
+		}
+	}
+
+	logs, total, err := h.downloadService.ListLogs(uint(orderID), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":      logs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
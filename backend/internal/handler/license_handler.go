@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type LicenseHandler struct {
+	licenseService service.LicenseService
+}
+
+func NewLicenseHandler(licenseService service.LicenseService) *LicenseHandler {
+	return &LicenseHandler{
+		licenseService: licenseService,
+	}
+}
+
+// ProductLicenseRequest 商品のライセンス
+type ProductLicenseRequest struct {
+	Type  string  `json:"type" binding:"required"`
+	Price float64 `json:"price" binding:"required"`
+	Terms string  `json:"terms"`
+}
+
+// SetLicensesRequest ライセンスの設定リクエスト（指定しなかった種類は販売停止）
+type SetLicensesRequest struct {
+	Licenses []ProductLicenseRequest `json:"licenses"`
+}
+
+// RevokeLicenseRequest 証明書の取り消しリクエスト
+type RevokeLicenseRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListProductLicenses 商品のライセンス一覧取得
+func (h *LicenseHandler) ListProductLicenses(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	licenses, err := h.licenseService.ListLicenses(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"licenses": licenses})
+}
+
+// SetLicenses 商品のライセンス設定
+func (h *LicenseHandler) SetLicenses(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req SetLicensesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	licenses := make([]model.ProductLicense, 0, len(req.Licenses))
+	for _, license := range req.Licenses {
+		licenses = append(licenses, model.ProductLicense{Type: license.Type, Price: license.Price, Terms: license.Terms})
+	}
+
+	result, err := h.licenseService.SetLicenses(uint(productID), licenses)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Product licenses updated successfully",
+		"licenses": result,
+	})
+}
+
+// ListOrderLicenses 注文のライセンス証明書一覧取得
+func (h *LicenseHandler) ListOrderLicenses(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	certificates, err := h.licenseService.ListOrderCertificates(userID.(uint), uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"licenses": certificates})
+}
+
+// GetCertificate ライセンス証明書のダウンロード（format=textまたはpdf）
+func (h *LicenseHandler) GetCertificate(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	certificate, err := h.licenseService.GetCertificate(userID.(uint), uint(orderID), c.Param("licenseId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch format := c.DefaultQuery("format", "text"); format {
+	case "text":
+		c.Header("Content-Disposition", `attachment; filename="`+certificate.LicenseID+`.txt"`)
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(h.licenseService.RenderText(certificate)))
+	case "pdf":
+		c.Header("Content-Disposition", `attachment; filename="`+certificate.LicenseID+`.pdf"`)
+		c.Data(http.StatusOK, "application/pdf", h.licenseService.RenderPDF(certificate))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format (text or pdf)"})
+	}
+}
+
+// VerifyLicense ライセンスIDの検証（認証不要）
+func (h *LicenseHandler) VerifyLicense(c *gin.Context) {
+	verification, err := h.licenseService.Verify(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"valid": false,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"license": verification})
+}
+
+// RevokeLicense ライセンス証明書の取り消し（管理者用）
+func (h *LicenseHandler) RevokeLicense(c *gin.Context) {
+	var req RevokeLicenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	certificate, err := h.licenseService.RevokeCertificate(c.Param("id"), req.Reason)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "License revoked successfully",
+		"license": certificate,
+	})
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthHandler struct {
+	oauthService service.OAuthService
+	cartService  service.CartService
+}
+
+func NewOAuthHandler(oauthService service.OAuthService, cartService service.CartService) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		cartService:  cartService,
+	}
+}
+
+// OAuthCallbackRequest プロバイダーから戻ってきた際の認可コードとstate
+type OAuthCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// ListProviders 利用可能なプロバイダー一覧
+func (h *OAuthHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.oauthService.Providers()})
+}
+
+// Authorize ソーシャルログインの開始（認可URLを返す）
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	authorizationURL, err := h.oauthService.AuthorizationURL(c.Param("provider"), nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authorizationURL})
+}
+
+// Callback ソーシャルログインの完了（通常のログインと同じトークンを返す）
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	var req OAuthCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.oauthService.Login(c.Param("provider"), req.Code, req.State, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if errors.Is(err, service.ErrAccountLinkRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		respondLoginError(c, err)
+		return
+	}
+
+	// 2段階認証が必要な場合はMFAトークンのみ返す
+	if result.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    result.MFAToken,
+		})
+		return
+	}
+
+	response := gin.H{
+		"message":       "Login successful",
+		"token":         result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+		"user":          result.User,
+	}
+	// ログイン前にカートに入れた商品を引き継ぐ
+	if merged := mergeGuestCart(c, h.cartService, result.User.ID); merged != nil {
+		response["cart_merge"] = merged
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ListIdentities 連携済みの外部アカウント一覧
+func (h *OAuthHandler) ListIdentities(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	identities, err := h.oauthService.ListIdentities(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// AuthorizeLink 外部アカウント連携の開始
+func (h *OAuthHandler) AuthorizeLink(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	id := userID.(uint)
+
+	authorizationURL, err := h.oauthService.AuthorizationURL(c.Param("provider"), &id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authorizationURL})
+}
+
+// LinkCallback 外部アカウント連携の完了
+func (h *OAuthHandler) LinkCallback(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req OAuthCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	identity, err := h.oauthService.Link(userID.(uint), c.Param("provider"), req.Code, req.State)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Account linked successfully",
+		"identity": identity,
+	})
+}
+
+// Unlink 外部アカウント連携の解除
+func (h *OAuthHandler) Unlink(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid identity ID"})
+		return
+	}
+
+	if err := h.oauthService.Unlink(userID.(uint), uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlinked successfully"})
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -21,6 +23,11 @@ func NewOrderHandler(orderService service.OrderService, db *gorm.DB) *OrderHandl
 	}
 }
 
+// CreateOrderRequest 注文作成リクエスト（address_id省略時はデフォルトの住所）
+type CreateOrderRequest struct {
+	AddressID *uint `json:"address_id"`
+}
+
 // UpdateOrderStatusRequest 注文ステータス更新リクエスト
 type UpdateOrderStatusRequest struct {
 	Status string `json:"status" binding:"required"`
@@ -34,7 +41,14 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	order, err := h.orderService.CreateOrder(userID.(uint), h.db)
+	// ボディは省略可能
+	var req CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.orderService.CreateOrder(userID.(uint), req.AddressID, h.db)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -127,7 +141,7 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.orderService.UpdateOrderStatus(uint(orderID), req.Status); err != nil {
+	if err := h.orderService.UpdateOrderStatus(uint(orderID), req.Status, h.db); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
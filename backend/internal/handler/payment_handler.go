@@ -9,17 +9,20 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/webhook"
+	"gorm.io/gorm"
 )
 
 type PaymentHandler struct {
 	paymentService service.PaymentService
 	webhookSecret  string
+	db             *gorm.DB
 }
 
-func NewPaymentHandler(paymentService service.PaymentService, webhookSecret string) *PaymentHandler {
+func NewPaymentHandler(paymentService service.PaymentService, webhookSecret string, db *gorm.DB) *PaymentHandler {
 	return &PaymentHandler{
 		paymentService: paymentService,
 		webhookSecret:  webhookSecret,
+		db:             db,
 	}
 }
 
@@ -82,7 +85,7 @@ func (h *PaymentHandler) HandleWebhook(c *gin.Context) {
 		}
 
 		// 決済成功処理
-		if err := h.paymentService.HandlePaymentSuccess(paymentIntent.ID); err != nil {
+		if err := h.paymentService.HandlePaymentSuccess(paymentIntent.ID, h.db); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
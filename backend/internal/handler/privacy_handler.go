@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyHandler struct {
+	privacyService service.PrivacyService
+}
+
+func NewPrivacyHandler(privacyService service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+	}
+}
+
+// ErasureRequestRequest 個人データ削除依頼リクエスト
+type ErasureRequestRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReviewErasureRequest 削除依頼の承認・却下リクエスト
+type ReviewErasureRequest struct {
+	Note string `json:"note"`
+}
+
+// ExportData 本人データのダウンロード（format=zip（デフォルト）またはjson）
+func (h *PrivacyHandler) ExportData(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	filename := fmt.Sprintf("my-data-%s", time.Now().Format("20060102"))
+
+	switch c.DefaultQuery("format", "zip") {
+	case "json":
+		export, err := h.privacyService.ExportData(userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		c.JSON(http.StatusOK, export)
+	case "zip":
+		archive, err := h.privacyService.ExportArchive(userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+		c.Data(http.StatusOK, "application/zip", archive)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
+	}
+}
+
+// RequestErasure 個人データ削除の依頼
+func (h *PrivacyHandler) RequestErasure(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req ErasureRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.privacyService.RequestErasure(userID.(uint), req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Erasure request submitted successfully",
+		"request": request,
+	})
+}
+
+// GetErasureRequest 個人データ削除依頼の状況
+func (h *PrivacyHandler) GetErasureRequest(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	request, err := h.privacyService.GetErasureRequest(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"request": request})
+}
+
+// CancelErasureRequest 個人データ削除依頼の取り下げ
+func (h *PrivacyHandler) CancelErasureRequest(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.privacyService.CancelErasureRequest(userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Erasure request cancelled successfully"})
+}
+
+// ListErasureRequests 削除依頼一覧（管理者用）
+func (h *PrivacyHandler) ListErasureRequests(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	requests, total, err := h.privacyService.ListErasureRequests(c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests":  requests,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ApproveErasureRequest 削除依頼の承認（個人データを匿名化）
+func (h *PrivacyHandler) ApproveErasureRequest(c *gin.Context) {
+	h.review(c, h.privacyService.ApproveErasureRequest, "Erasure request approved and personal data anonymized")
+}
+
+// RejectErasureRequest 削除依頼の却下
+func (h *PrivacyHandler) RejectErasureRequest(c *gin.Context) {
+	h.review(c, h.privacyService.RejectErasureRequest, "Erasure request rejected")
+}
+
+func (h *PrivacyHandler) review(c *gin.Context, action func(id, reviewerID uint, note string) (*model.DataErasureRequest, error), message string) {
+	reviewerID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	var req ReviewErasureRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := action(uint(id), reviewerID.(uint), req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"request": request,
+	})
+}
@@ -27,7 +27,7 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		return
 	}
 
-	if err := h.productService.CreateProduct(&product); err != nil {
+	if err := h.productService.CreateProduct(&product, stockMovementSource(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -71,7 +71,7 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 
 	product.ID = uint(id)
 
-	if err := h.productService.UpdateProduct(&product); err != nil {
+	if err := h.productService.UpdateProduct(&product, stockMovementSource(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -103,7 +103,20 @@ func (h *ProductHandler) ListProducts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	products, total, err := h.productService.ListProducts(page, pageSize)
+	var products []model.Product
+	var total int64
+	var err error
+	// category_id指定時は子孫カテゴリを含めて絞り込む
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		id, parseErr := strconv.ParseUint(categoryID, 10, 32)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+			return
+		}
+		products, total, err = h.productService.GetProductsByCategoryID(uint(id), page, pageSize)
+	} else {
+		products, total, err = h.productService.ListProducts(page, pageSize)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -138,28 +151,70 @@ func (h *ProductHandler) GetProductsByCategory(c *gin.Context) {
 	})
 }
 
-// SearchProducts 商品検索
+// SearchProducts 商品検索（category_id, min_price, max_price, in_stock, sortで絞り込み・並び替え）
 func (h *ProductHandler) SearchProducts(c *gin.Context) {
-	keyword := c.Query("keyword")
-	if keyword == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "keyword is required"})
-		return
-	}
-
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	products, total, err := h.productService.SearchProducts(keyword, page, pageSize)
+	params := model.ProductSearchParams{
+		Keyword:  c.Query("keyword"),
+		Sort:     c.Query("sort"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	if value := c.Query("category_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+			return
+		}
+		categoryID := uint(id)
+		params.CategoryID = &categoryID
+	}
+	if value := c.Query("min_price"); value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_price"})
+			return
+		}
+		params.MinPrice = &price
+	}
+	if value := c.Query("max_price"); value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_price"})
+			return
+		}
+		params.MaxPrice = &price
+	}
+	if value := c.Query("in_stock"); value != "" {
+		inStock, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid in_stock"})
+			return
+		}
+		params.InStock = inStock
+	}
+
+	if params.Keyword == "" && params.CategoryID == nil && params.MinPrice == nil && params.MaxPrice == nil && !params.InStock {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyword or filter is required"})
+		return
+	}
+
+	result, err := h.productService.SearchProducts(params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"products":  products,
-		"total":     total,
+		"products":  result.Products,
+		"total":     result.Total,
+		"facets":    result.Facets,
 		"page":      page,
 		"page_size": pageSize,
-		"keyword":   keyword,
+		"keyword":   params.Keyword,
+		"sort":      result.Sort,
 	})
 }
\ No newline at end of file
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 1リクエストでアップロードできる画像の数
+const maxImagesPerUpload = 10
+
+type ProductImageHandler struct {
+	imageService  service.ProductImageService
+	maxUploadSize int64
+}
+
+func NewProductImageHandler(imageService service.ProductImageService, maxUploadSize int64) *ProductImageHandler {
+	return &ProductImageHandler{
+		imageService:  imageService,
+		maxUploadSize: maxUploadSize,
+	}
+}
+
+// UpdateImageRequest 画像の代替テキスト更新リクエスト
+type UpdateImageRequest struct {
+	AltText string `json:"alt_text"`
+}
+
+// ReorderImagesRequest 画像の並び替えリクエスト
+type ReorderImagesRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required"`
+}
+
+// ListImages 商品画像一覧取得
+func (h *ProductImageHandler) ListImages(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	images, err := h.imageService.ListImages(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"images": images})
+}
+
+// UploadImages 商品画像のアップロード（multipart/form-dataのimageまたはimagesフィールド）
+func (h *ProductImageHandler) UploadImages(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	// リクエスト全体のサイズを制限（フォームの他のフィールド分の余裕を持たせる）
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadSize*maxImagesPerUpload+1<<20)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		return
+	}
+
+	files := append(form.File["image"], form.File["images"]...)
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
+		return
+	}
+	if len(files) > maxImagesPerUpload {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d images can be uploaded at once", maxImagesPerUpload)})
+		return
+	}
+
+	altText := c.PostForm("alt_text")
+	images := make([]*model.ProductImage, 0, len(files))
+	for _, file := range files {
+		if file.Size > h.maxUploadSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":  fmt.Sprintf("%s exceeds the maximum size of %d MB", file.Filename, h.maxUploadSize>>20),
+				"images": images,
+			})
+			return
+		}
+
+		data, err := readFormFile(file, h.maxUploadSize)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "images": images})
+			return
+		}
+
+		image, err := h.imageService.UploadImage(c.Request.Context(), uint(productID), data, altText)
+		if err != nil {
+			// 途中まで成功した画像は保存済みのため結果に含める
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  fmt.Sprintf("%s: %s", file.Filename, err.Error()),
+				"images": images,
+			})
+			return
+		}
+		images = append(images, image)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Images uploaded successfully",
+		"images":  images,
+	})
+}
+
+// UpdateImage 商品画像の代替テキスト更新
+func (h *ProductImageHandler) UpdateImage(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	imageID, err := strconv.ParseUint(c.Param("imageId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	var req UpdateImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := h.imageService.UpdateImage(uint(productID), uint(imageID), req.AltText)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Image updated successfully",
+		"image":   image,
+	})
+}
+
+// ReorderImages 商品画像の並び替え
+func (h *ProductImageHandler) ReorderImages(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req ReorderImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	images, err := h.imageService.ReorderImages(uint(productID), req.ImageIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Images reordered successfully",
+		"images":  images,
+	})
+}
+
+// DeleteImage 商品画像削除
+func (h *ProductImageHandler) DeleteImage(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	imageID, err := strconv.ParseUint(c.Param("imageId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+
+	if err := h.imageService.DeleteImage(c.Request.Context(), uint(productID), uint(imageID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
+}
+
+// アップロードされたファイルを上限サイズまで読み込む
+func readFormFile(file *multipart.FileHeader, maxSize int64) ([]byte, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%s exceeds the maximum size of %d MB", file.Filename, maxSize>>20)
+	}
+	return data, nil
+}
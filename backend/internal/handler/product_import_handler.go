@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// インポートするファイルの上限
+const maxImportFileSize = 50 << 20
+
+type ProductImportHandler struct {
+	importService service.ProductImportService
+}
+
+func NewProductImportHandler(importService service.ProductImportService) *ProductImportHandler {
+	return &ProductImportHandler{importService: importService}
+}
+
+// ImportProducts 商品の一括インポート（multipart/form-dataのfileフィールド、またはリクエストボディにCSV・JSON Lines）
+// dry_run=trueで検証のみ、async=trueまたは大きなファイルはジョブとして非同期で処理
+func (h *ProductImportHandler) ImportProducts(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	async, _ := strconv.ParseBool(c.DefaultQuery("async", "false"))
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize+1<<20)
+
+	var body io.Reader = c.Request.Body
+	format := c.Query("format")
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if contentType == "multipart/form-data" {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			if isBodyTooLarge(err) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No import file provided"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read import file"})
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+	} else if format == "" {
+		format = importFormatFromContentType(contentType)
+	}
+
+	job, err := h.importService.Import(body, format, dryRun, async, userID.(uint))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if job.Status != model.ImportJobStatusCompleted && job.Status != model.ImportJobStatusFailed {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Import job started",
+			"job":     job,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// ListImportJobs インポートジョブ一覧
+func (h *ProductImportHandler) ListImportJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	jobs, total, err := h.importService.ListJobs(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":      jobs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetImportJob インポートジョブの進捗・結果取得
+func (h *ProductImportHandler) GetImportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("jobId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import job ID"})
+		return
+	}
+
+	job, err := h.importService.GetJob(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+// ExportProducts 全商品のエクスポート（インポートと同じ形式で逐次書き出す）
+func (h *ProductImportHandler) ExportProducts(c *gin.Context) {
+	format, err := service.ParseProductFileFormat(c.DefaultQuery("format", model.ProductFileFormatCSV))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == model.ProductFileFormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	filename := "products-" + time.Now().Format("20060102") + "." + format
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// 書き出し開始後はステータスを変更できないため、失敗はログに残して中断する
+	if err := h.importService.Export(c.Writer, format); err != nil {
+		log.Printf("Failed to export products: %v", err)
+	}
+}
+
+// Content-Typeからインポートの形式を判定
+func importFormatFromContentType(contentType string) string {
+	switch contentType {
+	case "text/csv", "application/csv":
+		return model.ProductFileFormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return model.ProductFileFormatJSONL
+	default:
+		return ""
+	}
+}
+
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
\ No newline at end of file
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	reviewService service.ReviewService
+}
+
+func NewReviewHandler(reviewService service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+	}
+}
+
+// CreateReviewRequest レビュー投稿リクエスト
+type CreateReviewRequest struct {
+	ProductID uint   `json:"product_id" binding:"required"`
+	Rating    int    `json:"rating" binding:"required,min=1,max=5"`
+	Title     string `json:"title"`
+	Comment   string `json:"comment"`
+}
+
+// UpdateReviewRequest レビュー編集リクエスト
+type UpdateReviewRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Title   string `json:"title"`
+	Comment string `json:"comment"`
+}
+
+// ModerateReviewRequest レビューの承認・非表示リクエスト
+type ModerateReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// ListProductReviews 商品の公開中のレビュー一覧と評価の集計
+func (h *ReviewHandler) ListProductReviews(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	reviews, total, summary, err := h.reviewService.ListProductReviews(uint(productID), c.Query("sort"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":   reviews,
+		"summary":   summary,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ListMyReviews 自分が投稿したレビュー一覧
+func (h *ReviewHandler) ListMyReviews(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	reviews, err := h.reviewService.ListUserReviews(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+// CreateReview レビュー投稿
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.reviewService.CreateReview(userID.(uint), req.ProductID, req.Rating, req.Title, req.Comment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Review submitted and awaiting moderation",
+		"review":  review,
+	})
+}
+
+// UpdateReview レビュー編集
+func (h *ReviewHandler) UpdateReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var req UpdateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.reviewService.UpdateReview(userID.(uint), uint(id), req.Rating, req.Title, req.Comment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Review updated and awaiting moderation",
+		"review":  review,
+	})
+}
+
+// DeleteReview レビュー削除
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	if err := h.reviewService.DeleteReview(userID.(uint), uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully"})
+}
+
+// VoteHelpful 「参考になった」に投票
+func (h *ReviewHandler) VoteHelpful(c *gin.Context) {
+	h.vote(c, h.reviewService.VoteHelpful, "Vote recorded")
+}
+
+// RemoveHelpfulVote 「参考になった」の投票を取り消し
+func (h *ReviewHandler) RemoveHelpfulVote(c *gin.Context) {
+	h.vote(c, h.reviewService.RemoveHelpfulVote, "Vote removed")
+}
+
+func (h *ReviewHandler) vote(c *gin.Context, action func(userID, reviewID uint) (*model.Review, error), message string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	review, err := action(userID.(uint), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"review":  review,
+	})
+}
+
+// ListReviews レビュー一覧（管理者用、statusで絞り込み）
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	reviews, total, err := h.reviewService.ListReviews(c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":   reviews,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ApproveReview レビューの承認（公開）
+func (h *ReviewHandler) ApproveReview(c *gin.Context) {
+	h.moderate(c, h.reviewService.ApproveReview, "Review approved")
+}
+
+// HideReview レビューの非表示
+func (h *ReviewHandler) HideReview(c *gin.Context) {
+	h.moderate(c, h.reviewService.HideReview, "Review hidden")
+}
+
+func (h *ReviewHandler) moderate(c *gin.Context, action func(reviewID, moderatorID uint, note string) (*model.Review, error), message string) {
+	moderatorID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	var req ModerateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := action(uint(id), moderatorID.(uint), req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"review":  review,
+	})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type StockMovementHandler struct {
+	stockMovementService service.StockMovementService
+}
+
+func NewStockMovementHandler(stockMovementService service.StockMovementService) *StockMovementHandler {
+	return &StockMovementHandler{stockMovementService: stockMovementService}
+}
+
+// AdjustStockRequest 在庫の調整リクエスト（バリエーションのある商品はvariant_idを指定）
+type AdjustStockRequest struct {
+	VariantID   *uint  `json:"variant_id"`
+	WarehouseID *uint  `json:"warehouse_id"` // 省略した場合は既定の倉庫
+	Quantity    int    `json:"quantity" binding:"required"`
+	Reason      string `json:"reason" binding:"required"`
+	Note        string `json:"note"`
+	OrderID     *uint  `json:"order_id"` // 返品など注文に関係する調整の場合
+}
+
+// AdjustStock 理由を指定して在庫を増減
+func (h *StockMovementHandler) AdjustStock(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req AdjustStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source := stockMovementSource(c)
+	source.Reason = req.Reason
+	source.Note = req.Note
+	source.ReferenceID = req.OrderID
+	source.WarehouseID = req.WarehouseID
+
+	movement, err := h.stockMovementService.AdjustStock(uint(productID), req.VariantID, req.Quantity, source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Stock adjusted successfully",
+		"movement": movement,
+	})
+}
+
+// ListMovements 商品の在庫の変動履歴
+func (h *StockMovementHandler) ListMovements(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	movements, total, err := h.stockMovementService.ListMovements(uint(productID), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"movements": movements,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// Reconcile 変動履歴の合計と在庫数、倉庫ごとの在庫の合計と在庫数の照合
+func (h *StockMovementHandler) Reconcile(c *gin.Context) {
+	result, err := h.stockMovementService.Reconcile()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reconciliation": result})
+}
+
+// 在庫を変動させた操作者（認証済みのユーザー）
+func stockMovementSource(c *gin.Context) model.StockMovementSource {
+	var source model.StockMovementSource
+	if userID, exists := c.Get("user_id"); exists {
+		actorID := userID.(uint)
+		source.ActorID = &actorID
+	}
+	return source
+}
\ No newline at end of file
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type StockNotificationHandler struct {
+	stockNotificationService service.StockNotificationService
+}
+
+func NewStockNotificationHandler(stockNotificationService service.StockNotificationService) *StockNotificationHandler {
+	return &StockNotificationHandler{stockNotificationService: stockNotificationService}
+}
+
+type UnsubscribeStockNotificationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// Subscribe 在庫切れ商品の入荷通知を登録
+func (h *StockNotificationHandler) Subscribe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	subscription, err := h.stockNotificationService.Subscribe(userID.(uint), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Subscribed to back-in-stock notification",
+		"subscription": subscription,
+	})
+}
+
+// Unsubscribe 入荷通知の登録を解除
+func (h *StockNotificationHandler) Unsubscribe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if err := h.stockNotificationService.Unsubscribe(userID.(uint), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed from back-in-stock notification"})
+}
+
+// UnsubscribeByToken メールの配信停止リンクから入荷通知の登録を解除（ログイン不要）
+func (h *StockNotificationHandler) UnsubscribeByToken(c *gin.Context) {
+	var req UnsubscribeStockNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.stockNotificationService.UnsubscribeByToken(req.Token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed from back-in-stock notification"})
+}
+
+// ListAlerts 在庫アラート一覧（管理者用、open=trueで未解消のみ）
+func (h *StockNotificationHandler) ListAlerts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	openOnly, _ := strconv.ParseBool(c.DefaultQuery("open", "false"))
+
+	alerts, total, err := h.stockNotificationService.ListAlerts(openOnly, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts":    alerts,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
\ No newline at end of file
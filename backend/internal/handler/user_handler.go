@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -11,11 +13,13 @@ import (
 
 type UserHandler struct {
 	userService service.UserService
+	cartService service.CartService
 }
 
-func NewUserHandler(userService service.UserService) *UserHandler {
+func NewUserHandler(userService service.UserService, cartService service.CartService) *UserHandler {
 	return &UserHandler{
 		userService: userService,
+		cartService: cartService,
 	}
 }
 
@@ -32,6 +36,27 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// RefreshTokenRequest トークン更新リクエスト
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ForgotPasswordRequest パスワードリセット要求リクエスト
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest パスワード再設定リクエスト
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// VerifyEmailRequest メールアドレス確認リクエスト
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
 // Register ユーザー登録
 func (h *UserHandler) Register(c *gin.Context) {
 	var req RegisterRequest
@@ -47,17 +72,24 @@ func (h *UserHandler) Register(c *gin.Context) {
 	}
 
 	// 登録後、自動的にログイントークンを生成
-	token, err := h.userService.Login(req.Email, req.Password)
-	if err != nil {
+	result, err := h.userService.Login(req.Email, req.Password, c.ClientIP(), c.Request.UserAgent())
+	if err != nil || result.Tokens == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User registered but login failed"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"token":   token,
-		"user":    user,
-	})
+	response := gin.H{
+		"message":       "User registered successfully",
+		"token":         result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+		"user":          user,
+	}
+	// ログイン前にカートに入れた商品を引き継ぐ
+	if merged := mergeGuestCart(c, h.cartService, user.ID); merged != nil {
+		response["cart_merge"] = merged
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 // Login ログイン
@@ -68,12 +100,23 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.userService.Login(req.Email, req.Password)
+	result, err := h.userService.Login(req.Email, req.Password, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondLoginError(c, err)
 		return
 	}
 
+	// 2段階認証が必要な場合はMFAトークンのみ返す
+	if result.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    result.MFAToken,
+		})
+		return
+	}
+	tokens := result.Tokens
+
 	// ユーザー情報を取得
 	user, err := h.userService.GetUserByEmail(req.Email)
 	if err != nil {
@@ -81,13 +124,134 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	response := gin.H{
+		"message":       "Login successful",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	}
+	// ログイン前にカートに入れた商品を引き継ぐ
+	if merged := mergeGuestCart(c, h.cartService, user.ID); merged != nil {
+		response["cart_merge"] = merged
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ログインエラーのレスポンス（試行回数制限中は429とRetry-Afterを返す）
+func respondLoginError(c *gin.Context, err error) {
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
+
+// Refresh トークン更新（リフレッシュトークンのローテーション）
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.userService.RefreshToken(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
-		"user":    user,
+		"message":       "Token refreshed successfully",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
 
+// Logout ログアウト（トークンの失効）
+func (h *UserHandler) Logout(c *gin.Context) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.userService.Logout(claims.(*service.AccessClaims)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// ForgotPassword パスワードリセットメール送信
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send password reset email"})
+		return
+	}
+
+	// 登録の有無に関わらず同じレスポンスを返す
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+// ResetPassword パスワード再設定
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.ResetPassword(req.Token, req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// VerifyEmail メールアドレス確認
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.VerifyEmail(req.Token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerificationEmail 確認メール再送信
+func (h *UserHandler) ResendVerificationEmail(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.userService.SendVerificationEmail(userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
 // GetProfile プロフィール取得
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -182,4 +346,45 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		"page":      page,
 		"page_size": pageSize,
 	})
+}
+
+// UnlockUser アカウントロック解除（管理者用）
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.userService.UnlockUser(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
+// ListLoginAttempts ログイン試行履歴取得（管理者用）
+func (h *UserHandler) ListLoginAttempts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	attempts, total, err := h.userService.ListLoginAttempts(uint(id), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"login_attempts": attempts,
+		"total":          total,
+		"page":           page,
+		"page_size":      pageSize,
+	})
 }
\ No newline at end of file
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type VariantHandler struct {
+	variantService service.VariantService
+}
+
+func NewVariantHandler(variantService service.VariantService) *VariantHandler {
+	return &VariantHandler{
+		variantService: variantService,
+	}
+}
+
+// ProductOptionRequest 商品の選択肢
+type ProductOptionRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Values []string `json:"values" binding:"required"`
+}
+
+// SetOptionsRequest 選択肢の置き換えリクエスト
+type SetOptionsRequest struct {
+	Options []ProductOptionRequest `json:"options"`
+}
+
+// VariantRequest バリエーション作成・更新リクエスト
+type VariantRequest struct {
+	SKU      string            `json:"sku" binding:"required"`
+	Name     string            `json:"name"`
+	Options  map[string]string `json:"options"`
+	Price    float64           `json:"price" binding:"required"`
+	Stock    int               `json:"stock"`
+	ImageURL string            `json:"image_url"`
+	Position int               `json:"position"`
+}
+
+// UpdateVariantStockRequest 在庫増減リクエスト（理由を省略した場合は誤りの訂正として記録）
+type UpdateVariantStockRequest struct {
+	Quantity int    `json:"quantity" binding:"required"`
+	Reason   string `json:"reason"`
+	Note     string `json:"note"`
+}
+
+func (r *VariantRequest) toModel() *model.ProductVariant {
+	return &model.ProductVariant{
+		SKU:      r.SKU,
+		Name:     r.Name,
+		Options:  r.Options,
+		Price:    r.Price,
+		Stock:    r.Stock,
+		ImageURL: r.ImageURL,
+		Position: r.Position,
+	}
+}
+
+// ListVariants 商品の選択肢とバリエーション一覧取得
+func (h *VariantHandler) ListVariants(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	options, err := h.variantService.ListOptions(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	variants, err := h.variantService.ListVariants(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"options":  options,
+		"variants": variants,
+	})
+}
+
+// SetOptions 商品の選択肢を置き換え
+func (h *VariantHandler) SetOptions(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req SetOptionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	options := make([]model.ProductOption, 0, len(req.Options))
+	for _, option := range req.Options {
+		options = append(options, model.ProductOption{Name: option.Name, Values: option.Values})
+	}
+
+	result, err := h.variantService.SetOptions(uint(productID), options)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product options updated successfully",
+		"options": result,
+	})
+}
+
+// CreateVariant バリエーション作成
+func (h *VariantHandler) CreateVariant(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	var req VariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant := req.toModel()
+	if err := h.variantService.CreateVariant(uint(productID), variant, stockMovementSource(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Variant created successfully",
+		"variant": variant,
+	})
+}
+
+// UpdateVariant バリエーション更新
+func (h *VariantHandler) UpdateVariant(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	variantID, err := strconv.ParseUint(c.Param("variantId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	var req VariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant := req.toModel()
+	variant.ID = uint(variantID)
+	if err := h.variantService.UpdateVariant(uint(productID), variant, stockMovementSource(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Variant updated successfully",
+		"variant": variant,
+	})
+}
+
+// UpdateVariantStock バリエーションの在庫増減
+func (h *VariantHandler) UpdateVariantStock(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	variantID, err := strconv.ParseUint(c.Param("variantId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	var req UpdateVariantStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source := stockMovementSource(c)
+	source.Reason = req.Reason
+	source.Note = req.Note
+
+	variant, err := h.variantService.UpdateVariantStock(uint(productID), uint(variantID), req.Quantity, source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Variant stock updated successfully",
+		"variant": variant,
+	})
+}
+
+// DeleteVariant バリエーション削除
+func (h *VariantHandler) DeleteVariant(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	variantID, err := strconv.ParseUint(c.Param("variantId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	if err := h.variantService.DeleteVariant(uint(productID), uint(variantID), stockMovementSource(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted successfully"})
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type WarehouseHandler struct {
+	warehouseService service.WarehouseService
+}
+
+func NewWarehouseHandler(warehouseService service.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{warehouseService: warehouseService}
+}
+
+// WarehouseRequest 倉庫の作成・更新リクエスト
+type WarehouseRequest struct {
+	Code       string `json:"code" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	Prefecture string `json:"prefecture" binding:"required"`
+	Priority   int    `json:"priority"`
+	Active     *bool  `json:"active"` // 省略した場合は出荷できる倉庫
+	IsDefault  bool   `json:"is_default"`
+}
+
+// TransferStockRequest 倉庫間の在庫の移動リクエスト（バリエーションのある商品はvariant_idを指定）
+type TransferStockRequest struct {
+	FromWarehouseID uint   `json:"from_warehouse_id" binding:"required"`
+	ToWarehouseID   uint   `json:"to_warehouse_id" binding:"required"`
+	ProductID       uint   `json:"product_id" binding:"required"`
+	VariantID       *uint  `json:"variant_id"`
+	Quantity        int    `json:"quantity" binding:"required"`
+	Note            string `json:"note"`
+}
+
+func (req *WarehouseRequest) warehouse() *model.Warehouse {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return &model.Warehouse{
+		Code:       req.Code,
+		Name:       req.Name,
+		Prefecture: req.Prefecture,
+		Priority:   req.Priority,
+		Active:     active,
+		IsDefault:  req.IsDefault,
+	}
+}
+
+// ListWarehouses 倉庫一覧
+func (h *WarehouseHandler) ListWarehouses(c *gin.Context) {
+	warehouses, err := h.warehouseService.ListWarehouses()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"warehouses": warehouses})
+}
+
+// CreateWarehouse 倉庫作成
+func (h *WarehouseHandler) CreateWarehouse(c *gin.Context) {
+	var req WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	warehouse := req.warehouse()
+	if err := h.warehouseService.CreateWarehouse(warehouse); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Warehouse created successfully",
+		"warehouse": warehouse,
+	})
+}
+
+// UpdateWarehouse 倉庫更新
+func (h *WarehouseHandler) UpdateWarehouse(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse ID"})
+		return
+	}
+
+	var req WarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	warehouse := req.warehouse()
+	warehouse.ID = uint(id)
+	if err := h.warehouseService.UpdateWarehouse(warehouse); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Warehouse updated successfully",
+		"warehouse": warehouse,
+	})
+}
+
+// ListWarehouseStock 倉庫の在庫一覧
+func (h *WarehouseHandler) ListWarehouseStock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid warehouse ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	stocks, total, err := h.warehouseService.ListWarehouseStock(uint(id), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stocks":    stocks,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// ListProductStock 商品の倉庫ごとの在庫
+func (h *WarehouseHandler) ListProductStock(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	stocks, err := h.warehouseService.ListProductStock(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stocks": stocks})
+}
+
+// TransferStock 倉庫間で在庫を移動（移動元で未決済の注文に確保されている分は移動できない）
+func (h *WarehouseHandler) TransferStock(c *gin.Context) {
+	var req TransferStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source := stockMovementSource(c)
+	source.Note = req.Note
+
+	movements, err := h.warehouseService.TransferStock(req.FromWarehouseID, req.ToWarehouseID, req.ProductID, req.VariantID, req.Quantity, source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Stock transferred successfully",
+		"movements": movements,
+	})
+}
+
+// GetOrderAllocations 注文明細ごとの出荷元の倉庫
+func (h *WarehouseHandler) GetOrderAllocations(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	allocations, err := h.warehouseService.ListOrderAllocations(uint(orderID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"allocations": allocations})
+}
\ No newline at end of file
@@ -4,11 +4,12 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+func AuthMiddleware(tokenService service.TokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Authorizationヘッダーからトークンを取得
 		authHeader := c.GetHeader("Authorization")
@@ -26,75 +27,158 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
-		// トークンの検証
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// 署名方式の確認
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		// トークンの検証（署名・有効期限・失効リスト）
+		claims, err := tokenService.ParseAccessToken(tokenString)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		// クレームの取得
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
+		// ユーザー情報をコンテキストに保存
+		c.Set("user_id", claims.UserID)
+		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+		c.Set("permissions", claims.Permissions)
+		c.Set("claims", claims)
 
-		// ユーザー情報をコンテキストに保存（安全な型アサーション）
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user_id in token"})
-			c.Abort()
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware Authorizationヘッダーがある場合のみ認証する（ゲストも利用できるルート用）
+// ヘッダーがあって無効な場合はゲストとして扱わずに拒否する
+func OptionalAuthMiddleware(tokenService service.TokenService) gin.HandlerFunc {
+	auth := AuthMiddleware(tokenService)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
 			return
 		}
+		auth(c)
+	}
+}
 
-		email, ok := claims["email"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email in token"})
-			c.Abort()
+// AuthOrAPIKeyMiddleware Bearer JWTに加えてAPIキーでの認証も受け付ける
+// APIキーはX-API-Keyヘッダー、または"Bearer sk_..."の形式で指定する
+func AuthOrAPIKeyMiddleware(tokenService service.TokenService, apiKeyService service.APIKeyService) gin.HandlerFunc {
+	jwtAuth := AuthMiddleware(tokenService)
+
+	return func(c *gin.Context) {
+		rawKey := c.GetHeader("X-API-Key")
+		if rawKey == "" {
+			if bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(bearer, "sk_") {
+				rawKey = bearer
+			}
+		}
+		if rawKey == "" {
+			jwtAuth(c)
 			return
 		}
 
-		role, ok := claims["role"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid role in token"})
+		key, permissions, err := apiKeyService.Authenticate(rawKey)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user_id", uint(userID))
-		c.Set("email", email)
-		c.Set("role", role)
+		// ユーザー情報をコンテキストに保存（権限はリクエスト時点のロールから算出）
+		c.Set("user_id", key.UserID)
+		c.Set("email", key.User.Email)
+		c.Set("role", key.User.Role)
+		c.Set("permissions", permissions)
+		c.Set("api_key", key)
 
 		c.Next()
 	}
 }
 
-// AdminMiddleware 管理者権限チェック
-func AdminMiddleware() gin.HandlerFunc {
+// RequireScope APIキーでのアクセスの場合、指定したスコープのいずれかを持つキーのみ許可
+// JWTでのアクセスはスコープの制限を受けない
+func RequireScope(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
+		value, exists := c.Get("api_key")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			c.Abort()
+			c.Next()
 			return
 		}
 
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
+		key := value.(*model.APIKey)
+		for _, scope := range scopes {
+			if key.HasScope(scope) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key scope required: " + strings.Join(scopes, " or ")})
+		c.Abort()
+	}
+}
+
+// AdminMiddleware 管理APIへのアクセスチェック（指定した権限を全て持つユーザーのみ許可）
+// 権限を指定しない管理APIは作れない（何らかの権限を持つだけでは通さない）
+// requireMFAが有効な場合、2段階認証を経ていないセッションは拒否する
+func AdminMiddleware(requireMFA bool, required ...string) gin.HandlerFunc {
+	if len(required) == 0 {
+		panic("AdminMiddleware requires at least one permission")
+	}
+
+	return func(c *gin.Context) {
+		if !checkPermissions(c, required) {
 			return
 		}
 
+		if requireMFA {
+			// APIキーの場合は作成時のセッションが2段階認証済みであったかで判定
+			verified := false
+			if key, ok := c.Get("api_key"); ok {
+				verified = key.(*model.APIKey).MFAVerified
+			} else if claims, ok := c.Get("claims"); ok {
+				verified = claims.(*service.AccessClaims).MFA
+			}
+			if !verified {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication required for admin access"})
+				c.Abort()
+				return
+			}
+		}
+
 		c.Next()
 	}
+}
+
+// RequirePermission 指定した権限を全て持つユーザーのみ許可
+func RequirePermission(required ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if checkPermissions(c, required) {
+			c.Next()
+		}
+	}
+}
+
+// 指定した権限を全て持つか確認（持たない場合はエラーを返してリクエストを中断）
+func checkPermissions(c *gin.Context, required []string) bool {
+	value, exists := c.Get("permissions")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return false
+	}
+
+	granted := make(map[string]bool)
+	for _, permission := range value.([]string) {
+		granted[permission] = true
+	}
+
+	for _, permission := range required {
+		if !granted[permission] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied: " + permission})
+			c.Abort()
+			return false
+		}
+	}
+	return true
 }
\ No newline at end of file
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		permissions []string
+		mfa         bool
+		wantStatus  int
+	}{
+		{name: "required permission", permissions: []string{model.PermissionCouponsWrite}, mfa: true, wantStatus: http.StatusOK},
+		{name: "other admin permission", permissions: []string{model.PermissionReviewsModerate}, mfa: true, wantStatus: http.StatusForbidden},
+		{name: "no permissions", mfa: true, wantStatus: http.StatusForbidden},
+		{name: "without two-factor authentication", permissions: []string{model.PermissionCouponsWrite}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Set("permissions", tt.permissions)
+				c.Set("claims", &service.AccessClaims{MFA: tt.mfa})
+			})
+			router.GET("/admin/coupons", AdminMiddleware(true, model.PermissionCouponsWrite), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/coupons", nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAdminMiddlewareRequiresPermission(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("AdminMiddleware() without permissions did not panic")
+		}
+	}()
+	AdminMiddleware(false)
+}
\ No newline at end of file
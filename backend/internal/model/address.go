@@ -0,0 +1,59 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Address ユーザーの配送先住所
+type Address struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	UserID        uint           `gorm:"not null;index" json:"user_id"`
+	RecipientName string         `gorm:"size:100;not null" json:"recipient_name"`
+	PostalCode    string         `gorm:"size:8;not null" json:"postal_code"` // 123-4567
+	Prefecture    string         `gorm:"size:10;not null" json:"prefecture"`
+	City          string         `gorm:"size:100;not null" json:"city"`
+	Line1         string         `gorm:"size:255;not null" json:"line1"` // 町名・番地
+	Line2         string         `gorm:"size:255" json:"line2"`          // 建物名・部屋番号
+	Phone         string         `gorm:"size:20;not null" json:"phone"`
+	IsDefault     bool           `gorm:"default:false" json:"is_default"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// AddressSnapshot 注文時点の配送先（住所録の変更・削除の影響を受けない）
+type AddressSnapshot struct {
+	RecipientName string `gorm:"size:100" json:"recipient_name"`
+	PostalCode    string `gorm:"size:8" json:"postal_code"`
+	Prefecture    string `gorm:"size:10" json:"prefecture"`
+	City          string `gorm:"size:100" json:"city"`
+	Line1         string `gorm:"size:255" json:"line1"`
+	Line2         string `gorm:"size:255" json:"line2"`
+	Phone         string `gorm:"size:20" json:"phone"`
+}
+
+// 注文に保存する配送先のスナップショット
+func (a *Address) Snapshot() AddressSnapshot {
+	return AddressSnapshot{
+		RecipientName: a.RecipientName,
+		PostalCode:    a.PostalCode,
+		Prefecture:    a.Prefecture,
+		City:          a.City,
+		Line1:         a.Line1,
+		Line2:         a.Line2,
+		Phone:         a.Phone,
+	}
+}
+
+// 1行の住所表記（〒123-4567 東京都千代田区... 建物名 氏名）
+func (s AddressSnapshot) String() string {
+	parts := []string{"〒" + s.PostalCode, s.Prefecture + s.City + s.Line1}
+	if s.Line2 != "" {
+		parts = append(parts, s.Line2)
+	}
+	parts = append(parts, s.RecipientName)
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+)
+
+// APIキーのスコープ（アクセスできるルートグループ）
+const (
+	APIKeyScopeProducts = "products" // 商品管理
+	APIKeyScopeOrders   = "orders"   // 注文の作成・閲覧・ステータス更新
+	APIKeyScopeAdmin    = "admin"    // 全ての管理者用API
+)
+
+// APIKeyScopes 指定可能なスコープ
+var APIKeyScopes = []string{APIKeyScopeProducts, APIKeyScopeOrders, APIKeyScopeAdmin}
+
+// APIKey 連携・運用スクリプト用の個人APIキー（ハッシュ値のみ保存）
+type APIKey struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	Prefix      string     `gorm:"size:16;not null" json:"prefix"` // 識別用にキーの先頭を平文で保存
+	KeyHash     string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Scopes      []string   `gorm:"serializer:json;type:text" json:"scopes"`
+	MFAVerified bool       `gorm:"default:false" json:"mfa_verified"` // 2段階認証済みのセッションで作成されたか
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// リレーション
+	User *User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// スコープを持つか
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// 有効期限切れか
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
@@ -1,25 +1,124 @@
 package model
 
 import (
+	"errors"
 	"time"
 )
 
+// ErrVariantWithLicense バリエーションとライセンスは同じ明細で選択できない
+// （どちらの価格で販売するか定まらないため。商品にも両方は登録できない）
+var ErrVariantWithLicense = errors.New("a variant and a license cannot be selected for the same item")
+
 type CartItem struct {
 	ID        uint      `gorm:"primarykey" json:"id"`
 	UserID    uint      `gorm:"not null;index" json:"user_id"`
 	ProductID uint      `gorm:"not null" json:"product_id"`
+	VariantID *uint     `gorm:"index" json:"variant_id,omitempty"` // バリエーションのある商品の場合は必須
+	LicenseID *uint     `gorm:"index" json:"license_id,omitempty"` // ライセンスのある商品の場合は必須
 	Quantity  int       `gorm:"not null" json:"quantity"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
 	// リレーション
-	User    User    `gorm:"foreignKey:UserID" json:"-"`
-	Product Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	User    User            `gorm:"foreignKey:UserID" json:"-"`
+	Product Product         `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Variant *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+	License *ProductLicense `gorm:"foreignKey:LicenseID" json:"license,omitempty"`
+}
+
+// 単価（カートと注文で同じ規則を使う）
+func (i *CartItem) UnitPrice() (float64, error) {
+	return ItemPrice(&i.Product, i.Variant, i.License)
+}
+
+// ValidateSelection 明細で選択したバリエーション・ライセンスの組み合わせを確認
+func ValidateSelection(variant *ProductVariant, license *ProductLicense) error {
+	if variant != nil && license != nil {
+		return ErrVariantWithLicense
+	}
+	return nil
+}
+
+// ItemPrice 明細の単価（ライセンスまたはバリエーションを選択した場合はその価格）
+func ItemPrice(product *Product, variant *ProductVariant, license *ProductLicense) (float64, error) {
+	if err := ValidateSelection(variant, license); err != nil {
+		return 0, err
+	}
+	if license != nil {
+		return license.Price, nil
+	}
+	if variant != nil {
+		return variant.Price, nil
+	}
+	return product.Price, nil
 }
 
 // カート全体の情報を返す構造体
 type Cart struct {
 	Items      []CartItem `json:"items"`
 	TotalItems int        `json:"total_items"`
-	TotalPrice float64    `json:"total_price"`
+	TotalPrice float64    `json:"total_price"` // 商品の小計
+	CartPricing
+	CouponCode  string `json:"coupon_code,omitempty"`
+	CouponError string `json:"coupon_error,omitempty"` // 適用中のクーポンが使えない理由（注文時にエラーになる）
+}
+
+// GuestCart ログイン前のカート（Redisに保存し、ログイン時にユーザーのカートに統合する）
+type GuestCart struct {
+	Items      []GuestCartItem `json:"items"`
+	NextItemID uint            `json:"next_item_id"`
+	CouponCode string          `json:"coupon_code,omitempty"` // 適用中のクーポン（ログイン時にユーザーのカートに引き継ぐ）
+}
+
+// GuestCartItem ゲストカートのアイテム（IDはゲストカート内での連番）
+type GuestCartItem struct {
+	ID        uint      `json:"id"`
+	ProductID uint      `json:"product_id"`
+	VariantID *uint     `json:"variant_id,omitempty"`
+	LicenseID *uint     `json:"license_id,omitempty"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 同じ商品（バリエーション・ライセンス）のアイテム
+func (c *GuestCart) FindItem(productID uint, variantID, licenseID *uint) *GuestCartItem {
+	for i := range c.Items {
+		item := &c.Items[i]
+		if item.ProductID == productID && equalID(item.VariantID, variantID) && equalID(item.LicenseID, licenseID) {
+			return item
+		}
+	}
+	return nil
+}
+
+func (c *GuestCart) FindItemByID(id uint) *GuestCartItem {
+	for i := range c.Items {
+		if c.Items[i].ID == id {
+			return &c.Items[i]
+		}
+	}
+	return nil
+}
+
+// CartMergeItem ゲストカートのアイテムを統合した結果（在庫が足りない分は統合しない）
+type CartMergeItem struct {
+	ProductID uint   `json:"product_id"`
+	VariantID *uint  `json:"variant_id,omitempty"`
+	LicenseID *uint  `json:"license_id,omitempty"`
+	Requested int    `json:"requested"`        // ゲストカートの数量
+	Merged    int    `json:"merged"`           // ユーザーのカートに追加した数量
+	Reason    string `json:"reason,omitempty"` // 全てを追加できなかった理由
+}
+
+// CartMergeResult ゲストカートの統合結果
+type CartMergeResult struct {
+	Items      []CartMergeItem `json:"items"`
+	CouponCode string          `json:"coupon_code,omitempty"` // ユーザーのカートに引き継いだクーポン
+}
+
+func equalID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
 }
\ No newline at end of file
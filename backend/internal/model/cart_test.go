@@ -0,0 +1,43 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestItemPrice(t *testing.T) {
+	product := &Product{Price: 1000}
+	variant := &ProductVariant{Price: 1200}
+	license := &ProductLicense{Price: 5000}
+
+	tests := []struct {
+		name    string
+		variant *ProductVariant
+		license *ProductLicense
+		want    float64
+		wantErr error
+	}{
+		{name: "product price", want: 1000},
+		{name: "variant price", variant: variant, want: 1200},
+		{name: "license price", license: license, want: 5000},
+		{name: "variant and license", variant: variant, license: license, wantErr: ErrVariantWithLicense},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ItemPrice(product, tt.variant, tt.license)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ItemPrice() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ItemPrice() = %v, want %v", got, tt.want)
+			}
+
+			item := &CartItem{Product: *product, Variant: tt.variant, License: tt.license}
+			unitPrice, err := item.UnitPrice()
+			if unitPrice != got || !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnitPrice() = %v, %v, want %v, %v", unitPrice, err, got, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+)
+
+// Category 商品カテゴリ（parent_idによる階層構造）
+type Category struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	Slug      string    `gorm:"size:100;uniqueIndex;not null" json:"slug"`
+	ParentID  *uint     `gorm:"index" json:"parent_id,omitempty"`
+	SortOrder int       `gorm:"default:0" json:"sort_order"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// リレーション
+	Children []*Category `gorm:"-" json:"children,omitempty"` // ツリー表示用（DBからは組み立てて設定）
+}
+
+// カテゴリ一覧から親子関係のツリーを組み立てる（親が見つからないものはルート扱い）
+func BuildCategoryTree(categories []Category) []*Category {
+	nodes := make(map[uint]*Category, len(categories))
+	for i := range categories {
+		category := categories[i]
+		category.Children = nil
+		nodes[category.ID] = &category
+	}
+
+	roots := make([]*Category, 0)
+	for i := range categories {
+		node := nodes[categories[i].ID]
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
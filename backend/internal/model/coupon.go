@@ -0,0 +1,125 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// クーポンの種類
+const (
+	CouponTypePercentage   = "percentage"    // 対象商品の小計から割合で値引き
+	CouponTypeFixedAmount  = "fixed_amount"  // 対象商品の小計から定額で値引き
+	CouponTypeFreeShipping = "free_shipping" // 送料を無料にする
+)
+
+// CouponTypes 作成できるクーポンの種類
+var CouponTypes = []string{CouponTypePercentage, CouponTypeFixedAmount, CouponTypeFreeShipping}
+
+// クーポンの利用状況
+const (
+	CouponRedemptionRedeemed = "redeemed" // 注文で利用中
+	CouponRedemptionReleased = "released" // 注文のキャンセルで利用を取り消した（利用回数に数えない）
+)
+
+// Coupon 管理者が発行するクーポン
+// 対象の商品・カテゴリを指定しない場合はカート内の全商品が対象
+type Coupon struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	Code         string     `gorm:"size:32;not null;uniqueIndex" json:"code"` // 大文字で保存（入力は大文字・小文字を区別しない）
+	Description  string     `gorm:"size:500" json:"description"`
+	Type         string     `gorm:"size:20;not null" json:"type"`
+	Value        float64    `gorm:"not null;default:0" json:"value"`        // 割合（%）または値引き額
+	MaxDiscount  float64    `gorm:"not null;default:0" json:"max_discount"` // 割合での値引きの上限（0は上限なし）
+	MinSubtotal  float64    `gorm:"not null;default:0" json:"min_subtotal"` // 対象商品の小計の下限
+	StartsAt     *time.Time `json:"starts_at,omitempty"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	UsageLimit   int        `gorm:"not null;default:0" json:"usage_limit"`    // 全体での利用回数の上限（0は無制限）
+	PerUserLimit int        `gorm:"not null;default:0" json:"per_user_limit"` // 1ユーザーあたりの利用回数の上限（0は無制限）
+	UsedCount    int        `gorm:"not null;default:0" json:"used_count"`
+	Active       bool       `gorm:"not null" json:"active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// リレーション（対象の商品・カテゴリ。カテゴリは子孫カテゴリの商品も対象）
+	Products    []CouponProduct  `gorm:"foreignKey:CouponID" json:"-"`
+	Categories  []CouponCategory `gorm:"foreignKey:CouponID" json:"-"`
+	ProductIDs  []uint           `gorm:"-" json:"product_ids"`
+	CategoryIDs []uint           `gorm:"-" json:"category_ids"`
+}
+
+// CouponProduct クーポンの対象商品
+type CouponProduct struct {
+	CouponID  uint `gorm:"primaryKey;autoIncrement:false"`
+	ProductID uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+// CouponCategory クーポンの対象カテゴリ
+type CouponCategory struct {
+	CouponID   uint `gorm:"primaryKey;autoIncrement:false"`
+	CategoryID uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+func (c *Coupon) AfterFind(tx *gorm.DB) error {
+	c.ProductIDs = make([]uint, 0, len(c.Products))
+	for _, product := range c.Products {
+		c.ProductIDs = append(c.ProductIDs, product.ProductID)
+	}
+	c.CategoryIDs = make([]uint, 0, len(c.Categories))
+	for _, category := range c.Categories {
+		c.CategoryIDs = append(c.CategoryIDs, category.CategoryID)
+	}
+	return nil
+}
+
+// HasScope 対象の商品・カテゴリが指定されているか
+func (c *Coupon) HasScope() bool {
+	return len(c.ProductIDs) > 0 || len(c.CategoryIDs) > 0
+}
+
+// CouponRedemption 注文でのクーポンの利用（注文ごとに1件）
+type CouponRedemption struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	CouponID       uint      `gorm:"not null;index:idx_coupon_redemption_user" json:"coupon_id"`
+	UserID         uint      `gorm:"not null;index:idx_coupon_redemption_user" json:"user_id"`
+	OrderID        uint      `gorm:"not null;uniqueIndex" json:"order_id"`
+	DiscountAmount float64   `gorm:"not null" json:"discount_amount"`
+	Status         string    `gorm:"size:20;not null" json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CartCoupon ユーザーのカートに適用中のクーポン（注文時に利用する）
+type CartCoupon struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	CouponID  uint      `gorm:"not null;index" json:"coupon_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrderDiscount 注文時点の値引きの内訳（クーポンの変更・削除が過去の注文に影響しないようコピーして保存）
+type OrderDiscount struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	OrderID     uint      `gorm:"not null;index" json:"order_id"`
+	CouponID    *uint     `gorm:"index" json:"coupon_id,omitempty"`
+	Code        string    `gorm:"size:32" json:"code"`
+	Type        string    `gorm:"size:20;not null" json:"type"`
+	Description string    `gorm:"size:500" json:"description"`
+	Amount      float64   `gorm:"not null" json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DiscountLine 値引きの計算に使う明細（単価×数量）
+type DiscountLine struct {
+	ProductID  uint
+	CategoryID *uint
+	Amount     float64
+}
+
+// CartPricing 送料・値引きを含むカート・注文の金額
+type CartPricing struct {
+	ShippingFee float64         `json:"shipping_fee"`
+	Discount    float64         `json:"discount"`
+	Total       float64         `json:"total"`
+	Discounts   []OrderDiscount `json:"discounts"`
+}
\ No newline at end of file
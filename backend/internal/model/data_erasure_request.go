@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+)
+
+// 削除依頼のステータス
+const (
+	ErasureStatusPending   = "pending"   // 管理者の確認待ち
+	ErasureStatusRejected  = "rejected"  // 却下（未払いの注文がある場合など）
+	ErasureStatusCompleted = "completed" // 承認され匿名化済み
+	ErasureStatusCancelled = "cancelled" // 本人が取り下げ
+)
+
+// DataErasureRequest 個人データ削除（匿名化）の依頼
+type DataErasureRequest struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Status      string     `gorm:"size:20;not null;index;default:'pending'" json:"status"`
+	Reason      string     `gorm:"type:text" json:"reason"`
+	ReviewedBy  *uint      `json:"reviewed_by,omitempty"`
+	ReviewNote  string     `gorm:"type:text" json:"review_note,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// リレーション
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// UserDataExport 本人データのエクスポート内容
+type UserDataExport struct {
+	ExportedAt         time.Time            `json:"exported_at"`
+	Profile            *User                `json:"profile"`
+	Addresses          []Address            `json:"addresses"`
+	Orders             []Order              `json:"orders"`
+	Payments           []Payment            `json:"payments"`
+	Cart               []CartItem           `json:"cart"`
+	Reviews            []Review             `json:"reviews"`
+	DownloadLogs       []DownloadLog        `json:"download_logs"`
+	Licenses           []LicenseCertificate `json:"licenses"`
+	Identities         []Identity           `json:"identities"`
+	APIKeys            []APIKey             `json:"api_keys"`
+	LoginAttempts      []LoginAttempt       `json:"login_attempts"`
+	StockSubscriptions []StockSubscription  `json:"stock_subscriptions"`
+}
\ No newline at end of file
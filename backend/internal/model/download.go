@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+)
+
+// ダウンロードのアクセスログの結果
+const (
+	DownloadResultSuccess          = "success"
+	DownloadResultInvalidSignature = "invalid_signature"
+	DownloadResultExpired          = "expired"
+	DownloadResultLimitExceeded    = "limit_exceeded"
+	DownloadResultUnavailable      = "unavailable" // 注文のキャンセル・画像の削除など
+	DownloadResultError            = "error"
+)
+
+// DownloadGrant 購入した画像の元データをダウンロードする権利（注文明細ごとに決済完了時に付与）
+type DownloadGrant struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	OrderID       uint      `gorm:"not null;index" json:"order_id"`
+	OrderItemID   uint      `gorm:"not null;uniqueIndex" json:"order_item_id"`
+	UserID        uint      `gorm:"not null;index" json:"user_id"`
+	ProductID     uint      `gorm:"not null;index" json:"product_id"`
+	DownloadCount int       `gorm:"not null;default:0" json:"download_count"`
+	MaxDownloads  int       `gorm:"not null" json:"max_downloads"` // 付与時点の上限
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DownloadLog ダウンロードのアクセスログ（拒否されたアクセスも記録）
+type DownloadLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	GrantID   uint      `gorm:"index" json:"grant_id"`
+	OrderID   uint      `gorm:"index" json:"order_id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	ImageID   uint      `json:"image_id"`
+	Result    string    `gorm:"size:30;not null" json:"result"`
+	IPAddress string    `gorm:"size:45" json:"ip_address"`
+	UserAgent string    `gorm:"size:500" json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrderDownload 注文明細ごとのダウンロード情報
+type OrderDownload struct {
+	GrantID            uint           `json:"grant_id"`
+	OrderItemID        uint           `json:"order_item_id"`
+	ProductID          uint           `json:"product_id"`
+	ProductName        string         `json:"product_name"`
+	DownloadCount      int            `json:"download_count"`
+	MaxDownloads       int            `json:"max_downloads"`
+	RemainingDownloads int            `json:"remaining_downloads"`
+	Files              []DownloadFile `json:"files"`
+}
+
+// DownloadFile 元画像の署名付きダウンロードURL
+type DownloadFile struct {
+	ImageID      uint      `json:"image_id"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Size         int64     `json:"size"`
+	URL          string    `json:"url"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// Identity 外部IDプロバイダーのアカウントとユーザーの紐付け
+type Identity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Provider  string    `gorm:"size:50;not null;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_identity_provider_subject" json:"-"` // プロバイダー側のユーザーID（sub）
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuthState 認可リクエストの状態（stateのハッシュ値で引く。コールバックで一度だけ使用）
+type OAuthState struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	StateHash    string    `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Provider     string    `gorm:"size:50;not null" json:"provider"`
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"` // PKCE
+	Nonce        string    `gorm:"size:128;not null" json:"-"`
+	UserID       *uint     `json:"user_id,omitempty"` // アカウント連携の場合のログイン中ユーザー
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+)
+
+// 在庫予約の状態
+const (
+	ReservationStatusActive    = "active"    // 決済待ちの注文で確保中
+	ReservationStatusCommitted = "committed" // 決済完了で在庫から引き当て済み
+	ReservationStatusReleased  = "released"  // 期限切れ・キャンセルで解放済み
+	ReservationStatusRestocked = "restocked" // 引き当て後に注文がキャンセルされ在庫に戻し済み
+)
+
+// 在庫予約を解放した理由
+const (
+	ReservationReleaseExpired   = "expired"
+	ReservationReleaseCancelled = "cancelled"
+)
+
+// InventoryReservation 未決済の注文のために確保した在庫（注文明細・出荷元の倉庫ごと）
+type InventoryReservation struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	OrderID       uint       `gorm:"not null;index" json:"order_id"`
+	OrderItemID   uint       `gorm:"not null;uniqueIndex:idx_reservation_item_warehouse" json:"order_item_id"`
+	WarehouseID   *uint      `gorm:"uniqueIndex:idx_reservation_item_warehouse" json:"warehouse_id,omitempty"` // 出荷元の倉庫
+	ProductID     uint       `gorm:"not null;index" json:"product_id"`
+	VariantID     *uint      `gorm:"index" json:"variant_id,omitempty"`
+	Quantity      int        `gorm:"not null" json:"quantity"`
+	Status        string     `gorm:"size:20;not null;index:idx_reservation_status_expires" json:"status"`
+	ExpiresAt     time.Time  `gorm:"not null;index:idx_reservation_status_expires" json:"expires_at"`
+	CommittedAt   *time.Time `json:"committed_at,omitempty"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	ReleaseReason string     `gorm:"size:20" json:"release_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Warehouse     *Warehouse `gorm:"foreignKey:WarehouseID" json:"warehouse,omitempty"`
+}
\ No newline at end of file
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+)
+
+// ライセンスの種類
+const (
+	LicenseTypePersonal   = "personal"   // 個人利用
+	LicenseTypeCommercial = "commercial" // 商用利用
+	LicenseTypeExtended   = "extended"   // 商用利用（再販・大量配布を含む）
+)
+
+// LicenseTypes 選択できるライセンスの種類（表示順）
+var LicenseTypes = []string{LicenseTypePersonal, LicenseTypeCommercial, LicenseTypeExtended}
+
+// ProductLicense 商品ごとのライセンスと価格
+type ProductLicense struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ProductID uint      `gorm:"not null;uniqueIndex:idx_product_license_type" json:"product_id"`
+	Type      string    `gorm:"size:20;not null;uniqueIndex:idx_product_license_type" json:"type"`
+	Price     float64   `gorm:"not null" json:"price"`
+	Terms     string    `gorm:"type:text" json:"terms"` // 利用条件（証明書に記載）
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LicenseCertificate 購入ごとに発行するライセンス証明書
+type LicenseCertificate struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	LicenseID    string     `gorm:"size:32;uniqueIndex;not null" json:"license_id"` // 検証用の一意なID（LIC-XXXX-XXXX-XXXX-XXXX）
+	OrderID      uint       `gorm:"not null;index" json:"order_id"`
+	OrderItemID  uint       `gorm:"not null;uniqueIndex" json:"order_item_id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	ProductID    uint       `gorm:"not null" json:"product_id"`
+	ProductName  string     `gorm:"size:255" json:"product_name"` // 発行時点の商品名
+	LicenseType  string     `gorm:"size:20;not null" json:"license_type"`
+	Terms        string     `gorm:"type:text" json:"terms"`
+	Quantity     int        `gorm:"not null" json:"quantity"`
+	Price        float64    `gorm:"not null" json:"price"`
+	LicenseeName string     `gorm:"size:255" json:"licensee_name"` // 発行時点の購入者名
+	IssuedAt     time.Time  `json:"issued_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason string     `gorm:"size:500" json:"revoke_reason,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// IsValid 取り消されていない証明書か
+func (c *LicenseCertificate) IsValid() bool {
+	return c.RevokedAt == nil
+}
+
+// LicenseVerification 公開の検証結果（購入者のメールアドレスや注文の詳細は含めず、購入者名は伏せ字にする）
+type LicenseVerification struct {
+	LicenseID    string     `json:"license_id"`
+	Valid        bool       `json:"valid"`
+	ProductID    uint       `json:"product_id"`
+	ProductName  string     `json:"product_name"`
+	LicenseType  string     `json:"license_type"`
+	Quantity     int        `json:"quantity"`
+	LicenseeName string     `json:"licensee_name"` // 各語の先頭1文字のみ（例: "山*** 太***"）
+	IssuedAt     time.Time  `json:"issued_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
\ No newline at end of file
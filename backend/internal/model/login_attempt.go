@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// LoginAttempt ログイン試行履歴
+type LoginAttempt struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    *uint     `gorm:"index" json:"user_id,omitempty"` // 未登録のメールアドレスの場合はnil
+	Email     string    `gorm:"size:255;index;not null" json:"email"`
+	IPAddress string    `gorm:"size:45;index" json:"ip_address"`
+	UserAgent string    `gorm:"size:500" json:"user_agent"`
+	Success   bool      `gorm:"not null" json:"success"`
+	Reason    string    `gorm:"size:50" json:"reason,omitempty"` // invalid_password, unknown_email, locked, throttled
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
\ No newline at end of file
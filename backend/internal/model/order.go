@@ -9,19 +9,25 @@ import (
 )
 
 type Order struct {
-	ID              uint           `gorm:"primarykey" json:"id"`
-	UserID          uint           `gorm:"not null" json:"user_id"`
-	OrderNumber     string         `gorm:"type:varchar(50);uniqueIndex;not null" json:"order_number"`
-	TotalAmount     float64        `gorm:"not null" json:"total_amount"`
-	Status          string         `gorm:"default:'pending'" json:"status"` // pending, confirmed, shipped, delivered, cancelled
-	ShippingAddress string         `gorm:"type:text" json:"shipping_address"`                       // nullable に変更
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uint            `gorm:"primarykey" json:"id"`
+	UserID            uint            `gorm:"not null" json:"user_id"`
+	OrderNumber       string          `gorm:"type:varchar(50);uniqueIndex;not null" json:"order_number"`
+	TotalAmount       float64         `gorm:"not null" json:"total_amount"` // 商品の小計＋送料−値引き
+	ShippingFee       float64         `gorm:"not null;default:0" json:"shipping_fee"`
+	DiscountAmount    float64         `gorm:"not null;default:0" json:"discount_amount"`
+	Status            string          `gorm:"default:'pending'" json:"status"`                           // pending, confirmed, shipped, delivered, cancelled
+	ShippingAddress   string          `gorm:"type:text" json:"shipping_address"`                         // nullable に変更
+	ShippingAddressID *uint           `json:"shipping_address_id,omitempty"`                             // 注文時に選択した住所録のID（参照用）
+	ShippingDetails   AddressSnapshot `gorm:"embedded;embeddedPrefix:shipping_" json:"shipping_details"` // 注文時点の配送先
+	StockShortage     bool            `gorm:"not null;default:false" json:"stock_shortage"`              // 確保期限後に決済され在庫を引き当てられなかった（手動での対応が必要）
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt  `gorm:"index" json:"-"`
 
 	// リレーション
-	User       User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	OrderItems []OrderItem `gorm:"foreignKey:OrderID" json:"order_items,omitempty"`
+	User       User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	OrderItems []OrderItem     `gorm:"foreignKey:OrderID" json:"order_items,omitempty"`
+	Discounts  []OrderDiscount `gorm:"foreignKey:OrderID" json:"discounts,omitempty"`
 }
 
 // BeforeCreate 注文作成前のフック（注文番号の自動生成）
@@ -36,13 +42,19 @@ func (o *Order) BeforeCreate(tx *gorm.DB) error {
 }
 
 type OrderItem struct {
-	ID        uint      `gorm:"primarykey" json:"id"`
-	OrderID   uint      `gorm:"not null" json:"order_id"`
-	ProductID uint      `gorm:"not null" json:"product_id"`
-	Quantity  int       `gorm:"not null" json:"quantity"`
-	Price     float64   `gorm:"not null" json:"price"` // 注文時の価格
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint      `gorm:"primarykey" json:"id"`
+	OrderID      uint      `gorm:"not null" json:"order_id"`
+	ProductID    uint      `gorm:"not null" json:"product_id"`
+	VariantID    *uint     `gorm:"index" json:"variant_id,omitempty"`
+	SKU          string    `gorm:"size:64" json:"sku,omitempty"`           // 注文時のSKU
+	VariantName  string    `gorm:"size:200" json:"variant_name,omitempty"` // 注文時のバリエーション名
+	LicenseID    *uint     `gorm:"index" json:"license_id,omitempty"`
+	LicenseType  string    `gorm:"size:20" json:"license_type,omitempty"`    // 注文時のライセンスの種類
+	LicenseTerms string    `gorm:"type:text" json:"license_terms,omitempty"` // 注文時のライセンスの利用条件
+	Quantity     int       `gorm:"not null" json:"quantity"`
+	Price        float64   `gorm:"not null" json:"price"` // 注文時の価格
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 
 	// リレーション
 	Product Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
@@ -13,7 +13,7 @@ type Payment struct {
 	StripePaymentMethodID string         `gorm:"size:255" json:"stripe_payment_method_id,omitempty"`
 	Amount                int            `gorm:"not null" json:"amount"` // 最小通貨単位（日本円の場合は円単位）
 	Currency              string         `gorm:"default:'jpy'" json:"currency"`
-	Status                string         `gorm:"default:'pending'" json:"status"` // pending, succeeded, failed, canceled
+	Status                string         `gorm:"default:'pending'" json:"status"` // pending, succeeded, failed, canceled, refund_required（キャンセル済みの注文への決済）
 	CreatedAt             time.Time      `json:"created_at"`
 	UpdatedAt             time.Time      `json:"updated_at"`
 	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`
@@ -1,23 +1,104 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Product struct {
-	ID          uint           `gorm:"primarykey" json:"id"`
-	Name        string         `gorm:"not null" json:"name"`
-	Description string         `json:"description"`
-	Price       float64        `gorm:"not null" json:"price"`
-	Stock       int            `gorm:"default:0" json:"stock"`
-	Category    string         `json:"category"`
-	ImageURL    string         `json:"image_url"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uint           `gorm:"primarykey" json:"id"`
+	Name              string         `gorm:"not null" json:"name"`
+	SKU               *string        `gorm:"size:64;uniqueIndex" json:"sku,omitempty"` // 商品コード（一括インポート・エクスポートの照合キー）
+	Description       string         `json:"description"`
+	Price             float64        `gorm:"not null" json:"price"`                         // バリエーション・ライセンスがある場合は最安値
+	Stock             int            `gorm:"default:0" json:"stock"`                        // バリエーションがある場合は在庫の合計
+	Reserved          int            `gorm:"not null;default:0" json:"reserved"`            // 未決済の注文で確保されている数
+	Available         int            `gorm:"-" json:"available"`                            // 購入できる数（在庫 - 確保数）
+	LowStockThreshold int            `gorm:"not null;default:0" json:"low_stock_threshold"` // 発注点（購入できる数がこれ以下で管理者に通知。0は通知しない）
+	CategoryID        *uint          `gorm:"index" json:"category_id,omitempty"`
+	Category          string         `json:"category"`                        // カテゴリ名（互換性のため残す。CategoryIDから設定される）
+	ImageURL          string         `json:"image_url"`                       // 先頭の商品画像（透かし入りの中サイズ）
+	RatingAverage     float64        `gorm:"default:0" json:"rating_average"` // 公開中のレビューの平均評価
+	RatingCount       int            `gorm:"default:0" json:"rating_count"`   // 公開中のレビュー数
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// リレーション
-	OrderItems []OrderItem `gorm:"foreignKey:ProductID" json:"-"`
+	OrderItems []OrderItem      `gorm:"foreignKey:ProductID" json:"-"`
+	Options    []ProductOption  `gorm:"foreignKey:ProductID" json:"options,omitempty"`
+	Variants   []ProductVariant `gorm:"foreignKey:ProductID" json:"variants,omitempty"`
+	Images     []ProductImage   `gorm:"foreignKey:ProductID" json:"images,omitempty"`
+	Licenses   []ProductLicense `gorm:"foreignKey:ProductID" json:"licenses,omitempty"`
+}
+
+// 購入できる数を算出
+func (p *Product) AfterFind(tx *gorm.DB) error {
+	p.Available = availableQuantity(p.Stock, p.Reserved)
+	return nil
+}
+
+func (p *Product) AfterSave(tx *gorm.DB) error {
+	p.Available = availableQuantity(p.Stock, p.Reserved)
+	return nil
+}
+
+// 在庫から確保数を除いた数（負の値にはしない）
+func availableQuantity(stock, reserved int) int {
+	if stock < reserved {
+		return 0
+	}
+	return stock - reserved
+}
+
+// 商品コードが指定されていない商品に割り当てる既定の商品コード
+func DefaultProductSKU(id uint) string {
+	return fmt.Sprintf("PRD-%06d", id)
+}
+
+// バリエーション（SKU）単位で販売する商品か
+func (p *Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+// 商品のバリエーションを取得（存在しない場合はnil）
+func (p *Product) FindVariant(id uint) *ProductVariant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// ライセンスを選んで購入する商品か
+func (p *Product) HasLicenses() bool {
+	for i := range p.Licenses {
+		if p.Licenses[i].Active {
+			return true
+		}
+	}
+	return false
+}
+
+// 販売中のライセンスを取得（存在しない場合はnil）
+func (p *Product) FindLicense(id uint) *ProductLicense {
+	for i := range p.Licenses {
+		if p.Licenses[i].ID == id && p.Licenses[i].Active {
+			return &p.Licenses[i]
+		}
+	}
+	return nil
+}
+
+// 販売中のライセンスを種類で取得（存在しない場合はnil）
+func (p *Product) FindLicenseByType(licenseType string) *ProductLicense {
+	for i := range p.Licenses {
+		if p.Licenses[i].Type == licenseType && p.Licenses[i].Active {
+			return &p.Licenses[i]
+		}
+	}
+	return nil
 }
\ No newline at end of file
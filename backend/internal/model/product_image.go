@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// 商品画像のサイズ
+const (
+	ImageSizeThumbnail = "thumbnail"
+	ImageSizeMedium    = "medium"
+	ImageSizeLarge     = "large"
+)
+
+// ProductImage 商品画像（表示順付き、アップロード時に各サイズを生成）
+type ProductImage struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	ProductID    uint      `gorm:"not null;index" json:"product_id"`
+	Position     int       `gorm:"default:0" json:"position"`
+	AltText      string    `gorm:"size:255" json:"alt_text"`
+	StorageKey   string    `gorm:"size:255;not null" json:"-"` // 保存先のキーの接頭辞（products/{id}/{ランダム文字列}）
+	ContentType  string    `gorm:"size:50" json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Size         int64     `json:"size"` // 元画像のバイト数
+	ThumbnailURL string    `json:"thumbnail_url"`
+	MediumURL    string    `json:"medium_url"`
+	LargeURL     string    `json:"large_url"`
+	Watermarked  bool      `gorm:"not null;default:false" json:"-"` // 公開用の各サイズが透かし入りで元画像が非公開の保存先にあるか
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+)
+
+// 一括インポート・エクスポートのファイル形式
+const (
+	ProductFileFormatCSV   = "csv"
+	ProductFileFormatJSONL = "jsonl" // 1行に1商品のJSON
+)
+
+// 一括インポートの列（エクスポートも同じ順で出力する）
+var ProductImportColumns = []string{"sku", "name", "description", "price", "stock", "category"}
+
+// インポートジョブの状態
+const (
+	ImportJobStatusPending   = "pending"
+	ImportJobStatusRunning   = "running"
+	ImportJobStatusCompleted = "completed"
+	ImportJobStatusFailed    = "failed" // ファイル全体の処理に失敗（行ごとのエラーはErrorsに記録）
+)
+
+// ProductImportJob 商品の一括インポートの実行結果（大きなファイルは非同期で処理する）
+type ProductImportJob struct {
+	ID            uint             `gorm:"primarykey" json:"id"`
+	Format        string           `gorm:"size:10;not null" json:"format"`
+	DryRun        bool             `gorm:"not null;default:false" json:"dry_run"` // 検証のみで保存しない
+	Status        string           `gorm:"size:20;not null;index" json:"status"`
+	TotalRows     int              `gorm:"not null;default:0" json:"total_rows"`
+	ProcessedRows int              `gorm:"not null;default:0" json:"processed_rows"`
+	CreatedCount  int              `gorm:"not null;default:0" json:"created_count"`
+	UpdatedCount  int              `gorm:"not null;default:0" json:"updated_count"`
+	FailedCount   int              `gorm:"not null;default:0" json:"failed_count"`
+	Errors        []ImportRowError `gorm:"serializer:json;type:text" json:"errors"`
+	Message       string           `gorm:"size:500" json:"message,omitempty"` // ジョブが失敗した理由
+	CreatedBy     uint             `gorm:"index" json:"created_by"`
+	StartedAt     *time.Time       `json:"started_at,omitempty"`
+	FinishedAt    *time.Time       `json:"finished_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// ImportRowError インポートできなかった行（行番号はファイルの行番号）
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+// ProductImportRow インポートする1行（指定されなかった列は既存の値を維持する）
+type ProductImportRow struct {
+	Line        int      `json:"-"`
+	SKU         string   `json:"sku"`
+	Name        *string  `json:"name,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Price       *float64 `json:"price,omitempty"`
+	Stock       *int     `json:"stock,omitempty"`
+	Category    *string  `json:"category,omitempty"` // カテゴリのスラッグまたは名前（空の場合はカテゴリなし）
+}
+
+// ProductExportRow エクスポートする1行（ProductImportRowと同じ形式）
+type ProductExportRow struct {
+	SKU         string  `json:"sku"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Stock       int     `json:"stock"`
+	Category    string  `json:"category"`
+}
\ No newline at end of file
@@ -0,0 +1,61 @@
+package model
+
+// 商品検索の並び順
+const (
+	ProductSortRelevance  = "relevance"  // 関連度順（キーワード指定時のデフォルト）
+	ProductSortNewest     = "newest"     // 新着順（キーワード未指定時のデフォルト）
+	ProductSortPriceAsc   = "price_asc"  // 価格の安い順
+	ProductSortPriceDesc  = "price_desc" // 価格の高い順
+	ProductSortPopularity = "popularity" // 販売数の多い順
+)
+
+// ProductSortOptions 指定可能な並び順
+var ProductSortOptions = []string{
+	ProductSortRelevance,
+	ProductSortNewest,
+	ProductSortPriceAsc,
+	ProductSortPriceDesc,
+	ProductSortPopularity,
+}
+
+// ProductSearchParams 商品検索の条件
+type ProductSearchParams struct {
+	Keyword     string
+	CategoryID  *uint
+	CategoryIDs []uint // CategoryIDとその子孫カテゴリ（サービスで展開）
+	MinPrice    *float64
+	MaxPrice    *float64
+	InStock     bool
+	Sort        string
+	Page        int
+	PageSize    int
+}
+
+// ProductSearchResult 商品検索の結果
+type ProductSearchResult struct {
+	Products []Product    `json:"products"`
+	Total    int64        `json:"total"`
+	Sort     string       `json:"sort"` // 適用された並び順
+	Facets   SearchFacets `json:"facets"`
+}
+
+// SearchFacets 検索結果の絞り込み候補と件数
+type SearchFacets struct {
+	Categories  []CategoryFacet   `json:"categories"`
+	PriceRanges []PriceRangeFacet `json:"price_ranges"`
+	InStock     int64             `json:"in_stock"`
+}
+
+// CategoryFacet カテゴリ別の件数
+type CategoryFacet struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// PriceRangeFacet 価格帯別の件数（Maxがnilの場合は上限なし）
+type PriceRangeFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int64    `json:"count"`
+}
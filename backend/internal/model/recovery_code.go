@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+)
+
+// RecoveryCode 2段階認証のリカバリーコード（ハッシュ値のみ保存）
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
\ No newline at end of file
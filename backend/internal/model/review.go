@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// レビューの公開状態
+const (
+	ReviewStatusPending  = "pending"  // 投稿・編集後の確認待ち
+	ReviewStatusApproved = "approved" // 公開中
+	ReviewStatusHidden   = "hidden"   // 管理者が非表示にした
+)
+
+// Review 商品レビュー（配送済みの注文に含まれる商品のみ投稿可能）
+type Review struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	UserID         uint       `gorm:"not null;uniqueIndex:idx_review_user_product" json:"user_id"`
+	ProductID      uint       `gorm:"not null;uniqueIndex:idx_review_user_product;index" json:"product_id"`
+	OrderID        uint       `gorm:"not null" json:"order_id"` // 購入確認に使った注文
+	Rating         int        `gorm:"not null" json:"rating"`   // 1〜5
+	Title          string     `gorm:"size:100" json:"title"`
+	Comment        string     `gorm:"type:text" json:"comment"`
+	Status         string     `gorm:"size:20;default:'pending';index" json:"status"`
+	HelpfulCount   int        `gorm:"default:0" json:"helpful_count"`
+	ModerationNote string     `gorm:"type:text" json:"moderation_note,omitempty"`
+	ModeratedBy    *uint      `json:"moderated_by,omitempty"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// リレーション
+	User         *User  `gorm:"foreignKey:UserID" json:"-"`
+	ReviewerName string `gorm:"-" json:"reviewer_name,omitempty"`
+}
+
+// ReviewVote レビューへの「参考になった」投票（1ユーザー1票）
+type ReviewVote struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ReviewID  uint      `gorm:"not null;uniqueIndex:idx_review_vote" json:"review_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_review_vote;index" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReviewSummary 公開中のレビューの集計
+type ReviewSummary struct {
+	Average      float64       `json:"average"`
+	Count        int64         `json:"count"`
+	Distribution map[int]int64 `json:"distribution"` // 評価（1〜5）ごとの件数
+}
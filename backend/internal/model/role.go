@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+// 権限
+const (
+	PermissionProductsWrite      = "products:write"
+	PermissionOrdersRead         = "orders:read"
+	PermissionOrdersUpdateStatus = "orders:update_status"
+	PermissionUsersRead          = "users:read"
+	PermissionUsersWrite         = "users:write"
+	PermissionUsersDelete        = "users:delete"
+	PermissionRolesManage        = "roles:manage"
+	PermissionReviewsModerate    = "reviews:moderate"
+	PermissionInventoryWrite     = "inventory:write"
+	PermissionCouponsWrite       = "coupons:write"
+)
+
+// 組み込みロール
+const (
+	RoleAdmin = "admin"
+)
+
+// Permission 権限マスタ
+type Permission struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Name        string    `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Role ロール（権限の集合）
+type Role struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Name        string    `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	IsSystem    bool      `gorm:"default:false" json:"is_system"` // 組み込みロール（削除不可）
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// リレーション
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+}
\ No newline at end of file
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"time"
+)
+
+// 在庫の変動の種類
+const (
+	StockMovementOpening      = "opening"      // 変動履歴の記録を始める前の在庫
+	StockMovementOrder        = "order"        // 決済完了した注文の引き当て
+	StockMovementCancellation = "cancellation" // 決済済みの注文のキャンセルによる戻し
+	StockMovementAdjustment   = "adjustment"   // 管理者による調整
+	StockMovementImport       = "import"       // 商品の一括インポート
+	StockMovementReturn       = "return"       // 返品の受け入れ
+	StockMovementTransfer     = "transfer"     // 倉庫間の移動
+)
+
+// 在庫を変動させた理由
+const (
+	StockReasonReceived       = "received"        // 入荷
+	StockReasonCustomerReturn = "customer_return" // 返品
+	StockReasonDamaged        = "damaged"         // 破損
+	StockReasonLost           = "lost"            // 紛失
+	StockReasonRecount        = "recount"         // 棚卸
+	StockReasonCorrection     = "correction"      // 誤りの訂正
+
+	StockReasonInitial         = "initial_stock"    // 商品・バリエーションの作成
+	StockReasonProductEdit     = "product_edit"     // 商品・バリエーションの編集
+	StockReasonVariantDeleted  = "variant_deleted"  // バリエーションの削除
+	StockReasonVariantsEnabled = "variants_enabled" // 在庫をバリエーションごとの管理に切り替え
+	StockReasonVariantsRemoved = "variants_removed" // 在庫を商品単位の管理に戻す
+)
+
+// 管理者が在庫の調整で指定できる理由
+var StockAdjustmentReasons = []string{
+	StockReasonReceived,
+	StockReasonCustomerReturn,
+	StockReasonDamaged,
+	StockReasonLost,
+	StockReasonRecount,
+	StockReasonCorrection,
+}
+
+// 変動の参照先
+const (
+	StockReferenceOrder     = "order"
+	StockReferenceImportJob = "product_import_job"
+	StockReferenceWarehouse = "warehouse" // 倉庫間の移動の相手の倉庫
+)
+
+// StockMovement 在庫の変動履歴（追記のみで更新・削除しない）
+// バリエーションのある商品はバリエーションごと、それ以外は商品単位（VariantIDなし）で倉庫ごとに記録し、変動数の合計が在庫数と一致する
+type StockMovement struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	ProductID     uint      `gorm:"not null;index" json:"product_id"`
+	VariantID     *uint     `gorm:"index" json:"variant_id,omitempty"`
+	WarehouseID   *uint     `gorm:"index" json:"warehouse_id,omitempty"` // 未指定で記録した場合は既定の倉庫
+	Quantity      int       `gorm:"not null" json:"quantity"`            // 増減数（入庫は正、出庫は負）
+	Type          string    `gorm:"size:20;not null;index" json:"type"`
+	Reason        string    `gorm:"size:30" json:"reason,omitempty"`
+	ReferenceType string    `gorm:"size:30;index:idx_stock_movement_reference" json:"reference_type,omitempty"`
+	ReferenceID   *uint     `gorm:"index:idx_stock_movement_reference" json:"reference_id,omitempty"`
+	ActorID       *uint     `gorm:"index" json:"actor_id,omitempty"` // 操作したユーザー（注文の決済・期限切れなど自動の処理ではなし）
+	Note          string    `gorm:"size:500" json:"note,omitempty"`
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+}
+
+// StockMovementSource 在庫を変動させた操作（変動履歴の種類・理由・参照先・操作者と対象の倉庫）
+type StockMovementSource struct {
+	WarehouseID   *uint // 未指定の場合は既定の倉庫
+	Type          string
+	Reason        string
+	ReferenceType string
+	ReferenceID   *uint
+	ActorID       *uint
+	Note          string
+}
+
+// 商品（とバリエーション）の変動履歴を作成
+func (s StockMovementSource) Movement(productID uint, variantID *uint, quantity int) *StockMovement {
+	return &StockMovement{
+		ProductID:     productID,
+		VariantID:     variantID,
+		WarehouseID:   s.WarehouseID,
+		Quantity:      quantity,
+		Type:          s.Type,
+		Reason:        s.Reason,
+		ReferenceType: s.ReferenceType,
+		ReferenceID:   s.ReferenceID,
+		ActorID:       s.ActorID,
+		Note:          s.Note,
+	}
+}
+
+// 未指定の種類・理由を補う
+func (s StockMovementSource) WithDefaults(movementType, reason string) StockMovementSource {
+	if s.Type == "" {
+		s.Type = movementType
+	}
+	if s.Reason == "" {
+		s.Reason = reason
+	}
+	return s
+}
+
+// StockDiscrepancy 変動履歴の合計と在庫数が一致しない商品・バリエーション（WarehouseIDがある場合は倉庫ごとの在庫）
+type StockDiscrepancy struct {
+	ProductID   uint  `json:"product_id"`
+	VariantID   *uint `json:"variant_id,omitempty"`
+	WarehouseID *uint `json:"warehouse_id,omitempty"`
+	Stock       int   `json:"stock"`
+	LedgerStock int   `json:"ledger_stock"` // 変動履歴の合計
+	Difference  int   `json:"difference"`   // Stock - LedgerStock
+}
+
+// WarehouseStockDiscrepancy 在庫数が倉庫ごとの在庫の合計と一致しない商品・バリエーション
+type WarehouseStockDiscrepancy struct {
+	ProductID      uint  `json:"product_id"`
+	VariantID      *uint `json:"variant_id,omitempty"`
+	Stock          int   `json:"stock"`
+	WarehouseStock int   `json:"warehouse_stock"` // 倉庫ごとの在庫の合計
+	Difference     int   `json:"difference"`      // Stock - WarehouseStock
+}
+
+// StockReconciliation 変動履歴・倉庫ごとの在庫と在庫数の照合結果
+type StockReconciliation struct {
+	CheckedAt              time.Time                   `json:"checked_at"`
+	Consistent             bool                        `json:"consistent"`
+	Discrepancies          []StockDiscrepancy          `json:"discrepancies"`
+	WarehouseDiscrepancies []WarehouseStockDiscrepancy `json:"warehouse_discrepancies"`
+}
\ No newline at end of file
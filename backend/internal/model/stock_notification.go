@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// StockAlert 在庫が発注点を下回った商品の管理者向けアラート（解消されるまで商品ごとに1件）
+type StockAlert struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	ProductID   uint       `gorm:"not null;uniqueIndex:idx_stock_alert_open,where:resolved_at IS NULL" json:"product_id"`
+	ProductName string     `gorm:"size:200" json:"product_name"`
+	Threshold   int        `gorm:"not null" json:"threshold"` // アラート時点の発注点
+	Available   int        `gorm:"not null" json:"available"` // アラート時点の購入できる数
+	ResolvedAt  *time.Time `gorm:"index" json:"resolved_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// StockSubscription 在庫切れの商品の入荷通知の登録（通知後に再入荷すれば再度登録できる）
+type StockSubscription struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	ProductID  uint       `gorm:"not null;uniqueIndex:idx_stock_subscription_product_email" json:"product_id"`
+	UserID     *uint      `gorm:"index" json:"user_id,omitempty"`
+	Email      string     `gorm:"size:255;not null;uniqueIndex:idx_stock_subscription_product_email" json:"email"`
+	Token      string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // 配信停止リンクのトークン
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
\ No newline at end of file
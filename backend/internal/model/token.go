@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+// RefreshToken リフレッシュトークン（ハッシュ値のみ保存）
+type RefreshToken struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash    string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	FamilyID     string     `gorm:"size:64;index;not null" json:"family_id"` // ローテーションで引き継ぐセッション単位のID
+	MFAVerified  bool       `gorm:"default:false" json:"mfa_verified"`       // 2段階認証を経たセッションか
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at,omitempty"`    // ローテーション済みの日時
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"` // 失効日時（ログアウト・再利用検知など）
+	ReplacedByID *uint      `json:"replaced_by_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// RevokedToken 失効リスト（Redisが使えない場合のフォールバック）
+type RevokedToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Key       string    `gorm:"size:100;uniqueIndex;not null" json:"key"` // jti:{jti} または user:{user_id}
+	RevokedAt time.Time `gorm:"not null" json:"revoked_at"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TokenPair ログイン・リフレッシュ時に返すトークンの組
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // アクセストークンの有効秒数
+}
+
+// LoginResult ログイン結果（2段階認証が必要な場合はトークンの代わりにMFAトークンを返す）
+type LoginResult struct {
+	Tokens      *TokenPair
+	User        *User
+	MFARequired bool
+	MFAToken    string
+}
\ No newline at end of file
@@ -8,17 +8,23 @@ import (
 )
 
 type User struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string         `gorm:"not null" json:"-"`
-	Name      string         `gorm:"not null" json:"name"`
-	Role      string         `gorm:"default:'customer'" json:"role"` // customer, admin
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint           `gorm:"primarykey" json:"id"`
+	Email           string         `gorm:"uniqueIndex;not null" json:"email"`
+	Password        string         `gorm:"not null" json:"-"`
+	Name            string         `gorm:"not null" json:"name"`
+	Role            string         `gorm:"default:'customer'" json:"role"` // customer, admin
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	LockedUntil     *time.Time     `json:"locked_until,omitempty"` // ログイン失敗によるロック期限
+	TOTPSecret      string         `gorm:"size:64" json:"-"`
+	TOTPEnabled     bool           `gorm:"default:false" json:"totp_enabled"`
+	TOTPLastStep    int64          `json:"-"` // 最後に使用したタイムステップ（コードの再利用防止）
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// リレーション
 	Orders []Order `gorm:"foreignKey:UserID" json:"orders,omitempty"`
+	Roles  []Role  `gorm:"many2many:user_roles" json:"roles,omitempty"`
 }
 
 // パスワードをハッシュ化
@@ -31,6 +37,16 @@ func (u *User) HashPassword(password string) error {
 	return nil
 }
 
+// メールアドレス確認済みか
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// ログインがロックされているか
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
 // パスワードの検証
 func (u *User) CheckPassword(password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProductOption 商品の選択肢（例: サイズ、形式）
+type ProductOption struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ProductID uint      `gorm:"not null;index" json:"product_id"`
+	Name      string    `gorm:"size:50;not null" json:"name"`
+	Values    []string  `gorm:"serializer:json;type:text" json:"values"`
+	Position  int       `gorm:"default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProductVariant 選択肢の組み合わせごとの販売単位（SKU）
+type ProductVariant struct {
+	ID        uint              `gorm:"primarykey" json:"id"`
+	ProductID uint              `gorm:"not null;index" json:"product_id"`
+	SKU       string            `gorm:"size:64;uniqueIndex;not null" json:"sku"`
+	Name      string            `gorm:"size:200" json:"name"`                     // 表示名（例: "L / PNG"）
+	Options   map[string]string `gorm:"serializer:json;type:text" json:"options"` // 選択肢名 → 値
+	Price     float64           `gorm:"not null" json:"price"`
+	Stock     int               `gorm:"default:0" json:"stock"`
+	Reserved  int               `gorm:"not null;default:0" json:"reserved"` // 未決済の注文で確保されている数
+	Available int               `gorm:"-" json:"available"`                 // 購入できる数（在庫 - 確保数）
+	ImageURL  string            `json:"image_url"`
+	Position  int               `gorm:"default:0" json:"position"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt gorm.DeletedAt    `gorm:"index" json:"-"`
+}
+
+// 購入できる数を算出
+func (v *ProductVariant) AfterFind(tx *gorm.DB) error {
+	v.Available = availableQuantity(v.Stock, v.Reserved)
+	return nil
+}
+
+func (v *ProductVariant) AfterSave(tx *gorm.DB) error {
+	v.Available = availableQuantity(v.Stock, v.Reserved)
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Warehouse 商品を出荷する倉庫
+type Warehouse struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	Code       string    `gorm:"size:32;not null;uniqueIndex" json:"code"`
+	Name       string    `gorm:"size:100;not null" json:"name"`
+	Prefecture string    `gorm:"size:10;not null" json:"prefecture"`       // 所在地（配送先に近い倉庫の判定に使う）
+	Priority   int       `gorm:"not null;default:0" json:"priority"`       // 距離が同じ・配送先が無い場合の優先順（小さいほど優先）
+	Active     bool      `gorm:"not null" json:"active"`                   // 無効な倉庫からは出荷しない
+	IsDefault  bool      `gorm:"not null;default:false" json:"is_default"` // 倉庫を指定しない在庫の調整・作成時の在庫の保管先
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WarehouseStock 倉庫ごとの在庫（商品の在庫・確保数は全倉庫の合計）
+// バリエーションのある商品はバリエーションごと、それ以外は商品単位（VariantIDなし）で管理する
+type WarehouseStock struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	WarehouseID uint       `gorm:"not null;uniqueIndex:idx_warehouse_stock_product,where:variant_id IS NULL;uniqueIndex:idx_warehouse_stock_variant,where:variant_id IS NOT NULL" json:"warehouse_id"`
+	ProductID   uint       `gorm:"not null;index;uniqueIndex:idx_warehouse_stock_product,where:variant_id IS NULL" json:"product_id"`
+	VariantID   *uint      `gorm:"uniqueIndex:idx_warehouse_stock_variant,where:variant_id IS NOT NULL" json:"variant_id,omitempty"`
+	Stock       int        `gorm:"not null;default:0" json:"stock"`
+	Reserved    int        `gorm:"not null;default:0" json:"reserved"`
+	Available   int        `gorm:"-" json:"available"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Warehouse   *Warehouse `gorm:"foreignKey:WarehouseID" json:"warehouse,omitempty"`
+	Product     *Product   `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+}
+
+func (s *WarehouseStock) AfterFind(tx *gorm.DB) error {
+	s.Available = availableQuantity(s.Stock, s.Reserved)
+	return nil
+}
+
+// WarehouseAllocation 注文明細を出荷する倉庫と数量（在庫が足りない場合は複数の倉庫に分ける）
+type WarehouseAllocation struct {
+	OrderItemID uint
+	WarehouseID uint
+	Quantity    int
+}
\ No newline at end of file
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type AddressRepository interface {
+	Create(address *model.Address) error
+	GetByID(userID, id uint) (*model.Address, error)
+	GetDefault(userID uint) (*model.Address, error)
+	ListByUserID(userID uint) ([]model.Address, error)
+	CountByUserID(userID uint) (int64, error)
+	Update(address *model.Address) error
+	Delete(userID, id uint) error
+	SetDefault(userID, id uint) error
+}
+
+type addressRepository struct {
+	db *gorm.DB
+}
+
+func NewAddressRepository(db *gorm.DB) AddressRepository {
+	return &addressRepository{db: db}
+}
+
+func (r *addressRepository) Create(address *model.Address) error {
+	return r.db.Create(address).Error
+}
+
+// ユーザー本人の住所のみ取得
+func (r *addressRepository) GetByID(userID, id uint) (*model.Address, error) {
+	var address model.Address
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&address).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("address not found")
+		}
+		return nil, err
+	}
+	return &address, nil
+}
+
+// デフォルトの住所が無い場合はnilを返す
+func (r *addressRepository) GetDefault(userID uint) (*model.Address, error) {
+	var address model.Address
+	err := r.db.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &address, nil
+}
+
+// デフォルトの住所を先頭に、新しい順で取得
+func (r *addressRepository) ListByUserID(userID uint) ([]model.Address, error) {
+	var addresses []model.Address
+	err := r.db.Where("user_id = ?", userID).
+		Order("is_default DESC").
+		Order("created_at DESC").
+		Find(&addresses).Error
+	return addresses, err
+}
+
+func (r *addressRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Address{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *addressRepository) Update(address *model.Address) error {
+	return r.db.Save(address).Error
+}
+
+// 削除（論理削除）。デフォルトの住所を削除した場合は最新の住所をデフォルトにする
+func (r *addressRepository) Delete(userID, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var address model.Address
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&address).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("address not found")
+			}
+			return err
+		}
+
+		if err := tx.Model(&address).Update("is_default", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+
+		if !address.IsDefault {
+			return nil
+		}
+
+		var next model.Address
+		err := tx.Where("user_id = ?", userID).Order("created_at DESC").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+}
+
+// 指定した住所をデフォルトにする（他の住所のデフォルトは解除）
+func (r *addressRepository) SetDefault(userID, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Address{}).
+			Where("user_id = ? AND id <> ?", userID, id).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.Address{}).
+			Where("id = ? AND user_id = ?", id, userID).
+			Update("is_default", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("address not found")
+		}
+		return nil
+	})
+}
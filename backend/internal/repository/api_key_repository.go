@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(key *model.APIKey) error
+	GetByID(userID, id uint) (*model.APIKey, error)
+	GetByHash(keyHash string) (*model.APIKey, error)
+	ListByUserID(userID uint) ([]model.APIKey, error)
+	CountByUserID(userID uint) (int64, error)
+	Update(key *model.APIKey) error
+	Delete(userID, id uint) (bool, error)
+	DeleteByUserID(userID uint) (int64, error)
+	TouchLastUsed(id uint, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// ユーザー本人のキーのみ取得
+func (r *apiKeyRepository) GetByID(userID, id uint) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api key not found")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetByHash(keyHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Preload("User").Where("key_hash = ?", keyHash).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api key not found")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) ListByUserID(userID uint) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.APIKey{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *apiKeyRepository) Update(key *model.APIKey) error {
+	return r.db.Omit("User").Save(key).Error
+}
+
+func (r *apiKeyRepository) Delete(userID, id uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.APIKey{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ユーザーの全てのキーを削除（削除した件数を返す）
+func (r *apiKeyRepository) DeleteByUserID(userID uint) (int64, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&model.APIKey{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id uint, usedAt time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
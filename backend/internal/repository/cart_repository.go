@@ -10,11 +10,12 @@ import (
 type CartRepository interface {
 	GetByUserID(userID uint) ([]model.CartItem, error)
 	GetByID(id uint) (*model.CartItem, error)
-	GetByUserAndProduct(userID, productID uint) (*model.CartItem, error)
+	GetByUserAndProduct(userID, productID uint, variantID, licenseID *uint) (*model.CartItem, error)
 	Create(cartItem *model.CartItem) error
 	Update(cartItem *model.CartItem) error
 	Delete(id uint) error
 	DeleteByUserID(userID uint) error
+	WithTx(tx *gorm.DB) CartRepository
 }
 
 type cartRepository struct {
@@ -25,10 +26,15 @@ func NewCartRepository(db *gorm.DB) CartRepository {
 	return &cartRepository{db: db}
 }
 
+// トランザクション内で操作するリポジトリ
+func (r *cartRepository) WithTx(tx *gorm.DB) CartRepository {
+	return &cartRepository{db: tx}
+}
+
 // ユーザーのカートアイテム全取得
 func (r *cartRepository) GetByUserID(userID uint) ([]model.CartItem, error) {
 	var items []model.CartItem
-	err := r.db.Where("user_id = ?", userID).Preload("Product").Find(&items).Error
+	err := r.db.Where("user_id = ?", userID).Preload("Product").Preload("Variant").Preload("License").Find(&items).Error
 	return items, err
 }
 
@@ -45,10 +51,21 @@ func (r *cartRepository) GetByID(id uint) (*model.CartItem, error) {
 	return &item, nil
 }
 
-// ユーザーと商品でカートアイテム取得
-func (r *cartRepository) GetByUserAndProduct(userID, productID uint) (*model.CartItem, error) {
+// ユーザーと商品（バリエーション・ライセンス）でカートアイテム取得
+func (r *cartRepository) GetByUserAndProduct(userID, productID uint, variantID, licenseID *uint) (*model.CartItem, error) {
 	var item model.CartItem
-	err := r.db.Where("user_id = ? AND product_id = ?", userID, productID).First(&item).Error
+	query := r.db.Where("user_id = ? AND product_id = ?", userID, productID)
+	if variantID != nil {
+		query = query.Where("variant_id = ?", *variantID)
+	} else {
+		query = query.Where("variant_id IS NULL")
+	}
+	if licenseID != nil {
+		query = query.Where("license_id = ?", *licenseID)
+	} else {
+		query = query.Where("license_id IS NULL")
+	}
+	err := query.First(&item).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil // 見つからない場合はnilを返す（エラーではない）
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type CategoryRepository interface {
+	Create(category *model.Category) error
+	GetByID(id uint) (*model.Category, error)
+	GetBySlug(slug string) (*model.Category, error)
+	GetByName(name string) (*model.Category, error)
+	List() ([]model.Category, error)
+	Update(category *model.Category) error
+	Delete(id uint) error
+	DescendantIDs(id uint) ([]uint, error)
+	CountChildren(id uint) (int64, error)
+	CountProducts(id uint) (int64, error)
+	ListLegacyCategoryNames() ([]string, error)
+	AssignLegacyCategory(name string, categoryID uint) error
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+func (r *categoryRepository) Create(category *model.Category) error {
+	return r.db.Create(category).Error
+}
+
+func (r *categoryRepository) GetByID(id uint) (*model.Category, error) {
+	var category model.Category
+	err := r.db.First(&category, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("category not found")
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// 存在しない場合はnilを返す
+func (r *categoryRepository) GetBySlug(slug string) (*model.Category, error) {
+	var category model.Category
+	err := r.db.Where("slug = ?", slug).First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// 存在しない場合はnilを返す
+func (r *categoryRepository) GetByName(name string) (*model.Category, error) {
+	var category model.Category
+	err := r.db.Where("name = ?", name).Order("id").First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) List() ([]model.Category, error) {
+	var categories []model.Category
+	err := r.db.Order("sort_order").Order("name").Find(&categories).Error
+	return categories, err
+}
+
+func (r *categoryRepository) Update(category *model.Category) error {
+	return r.db.Save(category).Error
+}
+
+func (r *categoryRepository) Delete(id uint) error {
+	return r.db.Delete(&model.Category{}, id).Error
+}
+
+// 指定カテゴリと全ての子孫カテゴリのID
+func (r *categoryRepository) DescendantIDs(id uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT id FROM tree`, id).Scan(&ids).Error
+	return ids, err
+}
+
+func (r *categoryRepository) CountChildren(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *categoryRepository) CountProducts(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Product{}).Where("category_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// カテゴリIDが未設定の商品に使われている文字列のカテゴリ名
+func (r *categoryRepository) ListLegacyCategoryNames() ([]string, error) {
+	var names []string
+	err := r.db.Model(&model.Product{}).
+		Where("category_id IS NULL AND category IS NOT NULL AND category <> ''").
+		Distinct().
+		Pluck("category", &names).Error
+	return names, err
+}
+
+// 文字列のカテゴリ名が一致する商品にカテゴリIDを設定
+func (r *categoryRepository) AssignLegacyCategory(name string, categoryID uint) error {
+	return r.db.Unscoped().Model(&model.Product{}).
+		Where("category_id IS NULL AND category = ?", name).
+		Update("category_id", categoryID).Error
+}
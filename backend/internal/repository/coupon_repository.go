@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepository interface {
+	Create(coupon *model.Coupon) error
+	GetByID(id uint) (*model.Coupon, error)
+	GetByCode(code string) (*model.Coupon, error)
+	GetByIDForUpdate(id uint) (*model.Coupon, error)
+	CodeExists(code string, excludeID uint) (bool, error)
+	List(page, pageSize int) ([]model.Coupon, int64, error)
+	Update(coupon *model.Coupon) error
+	Delete(id uint) error
+	HasRedemptions(couponID uint) (bool, error)
+	CountRedemptions(couponID, userID uint) (int64, error)
+	Redeem(redemption *model.CouponRedemption) (bool, error)
+	ReleaseByOrderID(orderID uint) (bool, error)
+	GetCartCoupon(userID uint) (*model.Coupon, error)
+	SetCartCoupon(userID, couponID uint) error
+	DeleteCartCoupon(userID uint) error
+	WithTx(tx *gorm.DB) CouponRepository
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{db: db}
+}
+
+// トランザクション内で操作するリポジトリ
+func (r *couponRepository) WithTx(tx *gorm.DB) CouponRepository {
+	return &couponRepository{db: tx}
+}
+
+// クーポン作成（対象の商品・カテゴリも保存）
+func (r *couponRepository) Create(coupon *model.Coupon) error {
+	coupon.Products = couponProducts(coupon)
+	coupon.Categories = couponCategories(coupon)
+	return r.db.Create(coupon).Error
+}
+
+func (r *couponRepository) GetByID(id uint) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := r.db.Preload("Products").Preload("Categories").First(&coupon, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// コードでクーポン取得（codeは大文字に揃えて渡すこと）
+func (r *couponRepository) GetByCode(code string) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := r.db.Preload("Products").Preload("Categories").Where("code = ?", code).First(&coupon).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// 行ロックを取得してクーポン取得（同じクーポンを使う注文の利用回数の確認を順番に行う）
+func (r *couponRepository) GetByIDForUpdate(id uint) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, err
+	}
+	if err := r.db.Where("coupon_id = ?", id).Find(&coupon.Products).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("coupon_id = ?", id).Find(&coupon.Categories).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, coupon.AfterFind(r.db)
+}
+
+func (r *couponRepository) CodeExists(code string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Coupon{}).Where("code = ? AND id <> ?", code, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// クーポン一覧（新しい順）
+func (r *couponRepository) List(page, pageSize int) ([]model.Coupon, int64, error) {
+	var coupons []model.Coupon
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	if err := r.db.Model(&model.Coupon{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.Preload("Products").Preload("Categories").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&coupons).Error
+
+	return coupons, total, err
+}
+
+// クーポン更新（利用回数は変更せず、対象の商品・カテゴリは置き換える）
+func (r *couponRepository) Update(coupon *model.Coupon) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(coupon).
+			Select("code", "description", "type", "value", "max_discount", "min_subtotal",
+				"starts_at", "ends_at", "usage_limit", "per_user_limit", "active").
+			Updates(coupon).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("coupon_id = ?", coupon.ID).Delete(&model.CouponProduct{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("coupon_id = ?", coupon.ID).Delete(&model.CouponCategory{}).Error; err != nil {
+			return err
+		}
+		if products := couponProducts(coupon); len(products) > 0 {
+			if err := tx.Create(&products).Error; err != nil {
+				return err
+			}
+		}
+		if categories := couponCategories(coupon); len(categories) > 0 {
+			if err := tx.Create(&categories).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// クーポン削除（対象の商品・カテゴリと、適用中のカートからも削除）
+func (r *couponRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, m := range []interface{}{&model.CouponProduct{}, &model.CouponCategory{}, &model.CartCoupon{}} {
+			if err := tx.Where("coupon_id = ?", id).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.Coupon{}, id).Error
+	})
+}
+
+// 注文で利用されたことがあるか（キャンセルされた注文を含む）
+func (r *couponRepository) HasRedemptions(couponID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.CouponRedemption{}).Where("coupon_id = ?", couponID).Count(&count).Error
+	return count > 0, err
+}
+
+// ユーザーが注文で利用した回数（キャンセルされた注文を除く）
+func (r *couponRepository) CountRedemptions(couponID, userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ? AND status = ?", couponID, userID, model.CouponRedemptionRedeemed).
+		Count(&count).Error
+	return count, err
+}
+
+// 全体の利用回数が上限に達していない場合のみ利用回数を増やして利用を記録
+func (r *couponRepository) Redeem(redemption *model.CouponRedemption) (bool, error) {
+	result := r.db.Model(&model.Coupon{}).
+		Where("id = ? AND (usage_limit = 0 OR used_count < usage_limit)", redemption.CouponID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	redemption.Status = model.CouponRedemptionRedeemed
+	if err := r.db.Create(redemption).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 注文でのクーポンの利用を取り消して利用回数を戻す（取り消し済みの場合はfalse）
+func (r *couponRepository) ReleaseByOrderID(orderID uint) (bool, error) {
+	released := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var redemption model.CouponRedemption
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND status = ?", orderID, model.CouponRedemptionRedeemed).
+			First(&redemption).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&redemption).Update("status", model.CouponRedemptionReleased).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&model.Coupon{}).
+			Where("id = ? AND used_count > 0", redemption.CouponID).
+			Update("used_count", gorm.Expr("used_count - 1")).Error
+		if err != nil {
+			return err
+		}
+		released = true
+		return nil
+	})
+	return released, err
+}
+
+// カートに適用中のクーポン（適用していない・削除された場合はnil）
+func (r *couponRepository) GetCartCoupon(userID uint) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := r.db.Preload("Products").Preload("Categories").
+		Where("id = (?)", r.db.Model(&model.CartCoupon{}).Select("coupon_id").Where("user_id = ?", userID)).
+		First(&coupon).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// カートにクーポンを適用（既に適用中のクーポンは置き換える）
+func (r *couponRepository) SetCartCoupon(userID, couponID uint) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"coupon_id", "updated_at"}),
+	}).Create(&model.CartCoupon{UserID: userID, CouponID: couponID}).Error
+}
+
+func (r *couponRepository) DeleteCartCoupon(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.CartCoupon{}).Error
+}
+
+func couponProducts(coupon *model.Coupon) []model.CouponProduct {
+	products := make([]model.CouponProduct, 0, len(coupon.ProductIDs))
+	for _, productID := range coupon.ProductIDs {
+		products = append(products, model.CouponProduct{CouponID: coupon.ID, ProductID: productID})
+	}
+	return products
+}
+
+func couponCategories(coupon *model.Coupon) []model.CouponCategory {
+	categories := make([]model.CouponCategory, 0, len(coupon.CategoryIDs))
+	for _, categoryID := range coupon.CategoryIDs {
+		categories = append(categories, model.CouponCategory{CouponID: coupon.ID, CategoryID: categoryID})
+	}
+	return categories
+}
\ No newline at end of file
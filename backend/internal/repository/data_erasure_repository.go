@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type DataErasureRepository interface {
+	Create(request *model.DataErasureRequest) error
+	GetByID(id uint) (*model.DataErasureRequest, error)
+	GetLatestByUserID(userID uint) (*model.DataErasureRequest, error)
+	List(status string, page, pageSize int) ([]model.DataErasureRequest, int64, error)
+	Update(request *model.DataErasureRequest) error
+	AnonymizeUser(userID uint) error
+}
+
+type dataErasureRepository struct {
+	db *gorm.DB
+}
+
+func NewDataErasureRepository(db *gorm.DB) DataErasureRepository {
+	return &dataErasureRepository{db: db}
+}
+
+func (r *dataErasureRepository) Create(request *model.DataErasureRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *dataErasureRepository) GetByID(id uint) (*model.DataErasureRequest, error) {
+	var request model.DataErasureRequest
+	err := r.db.Preload("User").First(&request, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("erasure request not found")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// 最新の依頼（存在しない場合はnil）
+func (r *dataErasureRepository) GetLatestByUserID(userID uint) (*model.DataErasureRequest, error) {
+	var request model.DataErasureRequest
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// 依頼一覧（statusが空の場合は全件、古い順）
+func (r *dataErasureRepository) List(status string, page, pageSize int) ([]model.DataErasureRequest, int64, error) {
+	var requests []model.DataErasureRequest
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := r.db.Model(&model.DataErasureRequest{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("User").
+		Order("created_at ASC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&requests).Error
+
+	return requests, total, err
+}
+
+func (r *dataErasureRepository) Update(request *model.DataErasureRequest) error {
+	return r.db.Omit("User").Save(request).Error
+}
+
+// ユーザーの個人データを匿名化（注文・決済の金額は会計記録として残す）
+func (r *dataErasureRepository) AnonymizeUser(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 注文の配送先を消去
+		if err := tx.Unscoped().Model(&model.Order{}).
+			Where("user_id = ?", userID).
+			Updates(map[string]interface{}{
+				"shipping_address":        "",
+				"shipping_address_id":     nil,
+				"shipping_recipient_name": "",
+				"shipping_postal_code":    "",
+				"shipping_prefecture":     "",
+				"shipping_city":           "",
+				"shipping_line1":          "",
+				"shipping_line2":          "",
+				"shipping_phone":          "",
+			}).Error; err != nil {
+			return err
+		}
+
+		// 決済手段の参照を消去（PaymentIntentのIDは返金・照合のため残す）
+		if err := tx.Unscoped().Model(&model.Payment{}).
+			Where("order_id IN (?)", tx.Unscoped().Model(&model.Order{}).Select("id").Where("user_id = ?", userID)).
+			Update("stripe_payment_method_id", "").Error; err != nil {
+			return err
+		}
+
+		// 投稿したレビューを削除し、商品の平均評価を再計算（他のユーザーへの投票は件数として残す）
+		var reviewedProductIDs []uint
+		if err := tx.Model(&model.Review{}).Where("user_id = ?", userID).Pluck("product_id", &reviewedProductIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_id IN (?)", tx.Model(&model.Review{}).Select("id").Where("user_id = ?", userID)).
+			Delete(&model.ReviewVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.Review{}).Error; err != nil {
+			return err
+		}
+		if err := refreshProductRatings(tx, reviewedProductIDs); err != nil {
+			return err
+		}
+
+		// ダウンロードのアクセスログから接続元を消去（ダウンロード回数の記録は残す）
+		if err := tx.Model(&model.DownloadLog{}).
+			Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"ip_address": "", "user_agent": ""}).Error; err != nil {
+			return err
+		}
+
+		// ライセンス証明書の購入者名を消去（証明書自体は購入の記録として有効なまま残す）
+		if err := tx.Model(&model.LicenseCertificate{}).
+			Where("user_id = ?", userID).
+			Update("licensee_name", "Deleted user").Error; err != nil {
+			return err
+		}
+
+		// 本人に紐づくデータを削除
+		for _, m := range []interface{}{
+			&model.Address{},
+			&model.CartItem{},
+			&model.Identity{},
+			&model.APIKey{},
+			&model.RefreshToken{},
+			&model.UserToken{},
+			&model.RecoveryCode{},
+			&model.LoginAttempt{},
+			&model.StockSubscription{},
+			&model.CartCoupon{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+
+		// 未登録扱いで記録されたログイン試行もメールアドレスで削除
+		if err := tx.Where("email = (?)", tx.Unscoped().Model(&model.User{}).Select("LOWER(email)").Where("id = ?", userID)).
+			Delete(&model.LoginAttempt{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM user_roles WHERE user_id = ?", userID).Error; err != nil {
+			return err
+		}
+
+		// ユーザー情報を匿名化して論理削除（注文からの参照は残す）
+		if err := tx.Unscoped().Model(&model.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"email":             fmt.Sprintf("erased-user-%d@erased.invalid", userID),
+				"name":              "Deleted user",
+				"password":          "",
+				"email_verified_at": nil,
+				"locked_until":      nil,
+				"totp_secret":       "",
+				"totp_enabled":      false,
+				"totp_last_step":    0,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&model.User{}, userID).Error
+	})
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DownloadRepository interface {
+	CreateGrants(grants []model.DownloadGrant) error
+	GetGrantByID(id uint) (*model.DownloadGrant, error)
+	ListGrantsByOrderID(orderID uint) ([]model.DownloadGrant, error)
+	IncrementDownloadCount(id uint) (bool, error)
+	CreateLog(entry *model.DownloadLog) error
+	ListLogs(orderID uint, page, pageSize int) ([]model.DownloadLog, int64, error)
+	ListLogsByUserID(userID uint) ([]model.DownloadLog, error)
+}
+
+type downloadRepository struct {
+	db *gorm.DB
+}
+
+func NewDownloadRepository(db *gorm.DB) DownloadRepository {
+	return &downloadRepository{db: db}
+}
+
+// 注文明細ごとのダウンロード権を作成（付与済みの明細はそのまま）
+func (r *downloadRepository) CreateGrants(grants []model.DownloadGrant) error {
+	if len(grants) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "order_item_id"}},
+		DoNothing: true,
+	}).Create(&grants).Error
+}
+
+func (r *downloadRepository) GetGrantByID(id uint) (*model.DownloadGrant, error) {
+	var grant model.DownloadGrant
+	err := r.db.First(&grant, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("download not found")
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+func (r *downloadRepository) ListGrantsByOrderID(orderID uint) ([]model.DownloadGrant, error) {
+	var grants []model.DownloadGrant
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&grants).Error
+	return grants, err
+}
+
+// 上限に達していない場合のみダウンロード回数を加算（同時アクセスでも上限を超えない）
+func (r *downloadRepository) IncrementDownloadCount(id uint) (bool, error) {
+	result := r.db.Model(&model.DownloadGrant{}).
+		Where("id = ? AND download_count < max_downloads", id).
+		Update("download_count", gorm.Expr("download_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *downloadRepository) CreateLog(entry *model.DownloadLog) error {
+	return r.db.Create(entry).Error
+}
+
+// アクセスログ一覧（orderIDが0の場合は全注文）
+func (r *downloadRepository) ListLogs(orderID uint, page, pageSize int) ([]model.DownloadLog, int64, error) {
+	var logs []model.DownloadLog
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := r.db.Model(&model.DownloadLog{})
+	if orderID != 0 {
+		query = query.Where("order_id = ?", orderID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").Order("id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
+	return logs, total, err
+}
+
+func (r *downloadRepository) ListLogsByUserID(userID uint) ([]model.DownloadLog, error) {
+	var logs []model.DownloadLog
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&logs).Error
+	return logs, err
+}
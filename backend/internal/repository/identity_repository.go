@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type IdentityRepository interface {
+	Create(identity *model.Identity) error
+	GetByProviderSubject(provider, subject string) (*model.Identity, error)
+	ListByUserID(userID uint) ([]model.Identity, error)
+	CountByUserID(userID uint) (int64, error)
+	Delete(userID, id uint) (bool, error)
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) Create(identity *model.Identity) error {
+	return r.db.Create(identity).Error
+}
+
+// 紐付けが存在しない場合はnilを返す
+func (r *identityRepository) GetByProviderSubject(provider, subject string) (*model.Identity, error) {
+	var identity model.Identity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) ListByUserID(userID uint) ([]model.Identity, error) {
+	var identities []model.Identity
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	return identities, err
+}
+
+func (r *identityRepository) CountByUserID(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Identity{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// ユーザー本人の紐付けのみ削除
+func (r *identityRepository) Delete(userID, id uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Identity{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+type OAuthStateRepository interface {
+	Create(state *model.OAuthState) error
+	Consume(stateHash string) (*model.OAuthState, error)
+	DeleteExpired() error
+}
+
+type oauthStateRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthStateRepository(db *gorm.DB) OAuthStateRepository {
+	return &oauthStateRepository{db: db}
+}
+
+func (r *oauthStateRepository) Create(state *model.OAuthState) error {
+	return r.db.Create(state).Error
+}
+
+// stateを取得して削除（同じstateの二重使用防止）
+func (r *oauthStateRepository) Consume(stateHash string) (*model.OAuthState, error) {
+	var state model.OAuthState
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state_hash = ?", stateHash).First(&state).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&model.OAuthState{}, state.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("oauth state not found")
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *oauthStateRepository) DeleteExpired() error {
+	return r.db.Where("expires_at < ?", time.Now()).Delete(&model.OAuthState{}).Error
+}
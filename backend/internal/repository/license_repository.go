@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LicenseRepository interface {
+	ListByProductID(productID uint) ([]model.ProductLicense, error)
+	SaveProductLicenses(productID uint, licenses []model.ProductLicense) error
+	CreateCertificates(certificates []model.LicenseCertificate) error
+	GetCertificateByLicenseID(licenseID string) (*model.LicenseCertificate, error)
+	ListCertificatesByOrderID(orderID uint) ([]model.LicenseCertificate, error)
+	ListCertificatesByUserID(userID uint) ([]model.LicenseCertificate, error)
+	RevokeCertificate(licenseID, reason string) (*model.LicenseCertificate, error)
+}
+
+type licenseRepository struct {
+	db *gorm.DB
+}
+
+func NewLicenseRepository(db *gorm.DB) LicenseRepository {
+	return &licenseRepository{db: db}
+}
+
+func (r *licenseRepository) ListByProductID(productID uint) ([]model.ProductLicense, error) {
+	var licenses []model.ProductLicense
+	err := r.db.Where("product_id = ?", productID).Order("price").Order("id").Find(&licenses).Error
+	return licenses, err
+}
+
+// 種類ごとにライセンスを登録・更新し、指定されなかった種類は販売停止にする
+// （カート・注文から参照されているため行は削除しない）。商品の価格は販売中のライセンスの最安値に合わせる
+func (r *licenseRepository) SaveProductLicenses(productID uint, licenses []model.ProductLicense) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		types := make([]string, 0, len(licenses))
+		for i := range licenses {
+			licenses[i].ID = 0
+			licenses[i].ProductID = productID
+			licenses[i].Active = true
+			types = append(types, licenses[i].Type)
+		}
+
+		query := tx.Model(&model.ProductLicense{}).Where("product_id = ?", productID)
+		if len(types) > 0 {
+			query = query.Where("type NOT IN ?", types)
+		}
+		if err := query.Update("active", false).Error; err != nil {
+			return err
+		}
+
+		if len(licenses) == 0 {
+			return nil
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "product_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"price", "terms", "active", "updated_at"}),
+		}).Create(&licenses).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.Product{}).Where("id = ?", productID).
+			Update("price", tx.Model(&model.ProductLicense{}).
+				Select("MIN(price)").
+				Where("product_id = ? AND active = ?", productID, true)).Error
+	})
+}
+
+// 注文明細ごとに証明書を作成（発行済みの明細はそのまま）
+func (r *licenseRepository) CreateCertificates(certificates []model.LicenseCertificate) error {
+	if len(certificates) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "order_item_id"}},
+		DoNothing: true,
+	}).Create(&certificates).Error
+}
+
+func (r *licenseRepository) GetCertificateByLicenseID(licenseID string) (*model.LicenseCertificate, error) {
+	var certificate model.LicenseCertificate
+	err := r.db.Where("license_id = ?", licenseID).First(&certificate).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("license not found")
+		}
+		return nil, err
+	}
+	return &certificate, nil
+}
+
+func (r *licenseRepository) ListCertificatesByOrderID(orderID uint) ([]model.LicenseCertificate, error) {
+	var certificates []model.LicenseCertificate
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&certificates).Error
+	return certificates, err
+}
+
+func (r *licenseRepository) ListCertificatesByUserID(userID uint) ([]model.LicenseCertificate, error) {
+	var certificates []model.LicenseCertificate
+	err := r.db.Where("user_id = ?", userID).Order("issued_at").Find(&certificates).Error
+	return certificates, err
+}
+
+// 証明書を取り消す（取り消し済みの場合はそのまま返す）
+func (r *licenseRepository) RevokeCertificate(licenseID, reason string) (*model.LicenseCertificate, error) {
+	certificate, err := r.GetCertificateByLicenseID(licenseID)
+	if err != nil {
+		return nil, err
+	}
+	if certificate.RevokedAt != nil {
+		return certificate, nil
+	}
+
+	now := time.Now()
+	certificate.RevokedAt = &now
+	certificate.RevokeReason = reason
+	if err := r.db.Save(certificate).Error; err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
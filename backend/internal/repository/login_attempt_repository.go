@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type LoginAttemptRepository interface {
+	Create(attempt *model.LoginAttempt) error
+	ListByUserID(userID uint, page, pageSize int) ([]model.LoginAttempt, int64, error)
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Create(attempt *model.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// ユーザーのログイン試行履歴（新しい順）
+func (r *loginAttemptRepository) ListByUserID(userID uint, page, pageSize int) ([]model.LoginAttempt, int64, error) {
+	var attempts []model.LoginAttempt
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := r.db.Model(&model.LoginAttempt{}).Where("user_id = ?", userID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&attempts).Error
+
+	return attempts, total, err
+}
\ No newline at end of file
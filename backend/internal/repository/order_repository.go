@@ -11,7 +11,10 @@ type OrderRepository interface {
 	Create(order *model.Order) error
 	GetByID(id uint) (*model.Order, error)
 	GetByUserID(userID uint, page, pageSize int) ([]model.Order, int64, error)
+	ListAllByUserID(userID uint) ([]model.Order, error)
 	Update(order *model.Order) error
+	UpdateStatusIf(id uint, from, to string) (bool, error)
+	WithTx(tx *gorm.DB) OrderRepository
 	List(page, pageSize int) ([]model.Order, int64, error)
 }
 
@@ -23,6 +26,11 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
+// トランザクション内で操作するリポジトリ
+func (r *orderRepository) WithTx(tx *gorm.DB) OrderRepository {
+	return &orderRepository{db: tx}
+}
+
 // 注文作成
 func (r *orderRepository) Create(order *model.Order) error {
 	return r.db.Create(order).Error
@@ -31,7 +39,7 @@ func (r *orderRepository) Create(order *model.Order) error {
 // IDで注文取得
 func (r *orderRepository) GetByID(id uint) (*model.Order, error) {
 	var order model.Order
-	err := r.db.Preload("OrderItems.Product").Preload("User").First(&order, id).Error
+	err := r.db.Preload("OrderItems.Product").Preload("Discounts").Preload("User").First(&order, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("order not found")
@@ -54,7 +62,7 @@ func (r *orderRepository) GetByUserID(userID uint, page, pageSize int) ([]model.
 		return nil, 0, err
 	}
 
-	err := query.Preload("OrderItems.Product").
+	err := query.Preload("OrderItems.Product").Preload("Discounts").
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(pageSize).
@@ -63,11 +71,32 @@ func (r *orderRepository) GetByUserID(userID uint, page, pageSize int) ([]model.
 	return orders, total, err
 }
 
+// ユーザーの全注文取得（データエクスポート用）
+func (r *orderRepository) ListAllByUserID(userID uint) ([]model.Order, error) {
+	var orders []model.Order
+	err := r.db.Preload("OrderItems.Product").Preload("Discounts").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&orders).Error
+	return orders, err
+}
+
 // 注文更新
 func (r *orderRepository) Update(order *model.Order) error {
 	return r.db.Save(order).Error
 }
 
+// 現在のステータスがfromの場合のみステータスを更新（同時に決済された注文を上書きしない）
+func (r *orderRepository) UpdateStatusIf(id uint, from, to string) (bool, error) {
+	result := r.db.Model(&model.Order{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // 注文一覧取得（管理者用）
 func (r *orderRepository) List(page, pageSize int) ([]model.Order, int64, error) {
 	var orders []model.Order
@@ -79,7 +108,7 @@ func (r *orderRepository) List(page, pageSize int) ([]model.Order, int64, error)
 		return nil, 0, err
 	}
 
-	err := r.db.Preload("OrderItems.Product").
+	err := r.db.Preload("OrderItems.Product").Preload("Discounts").
 		Preload("User").
 		Order("created_at DESC").
 		Offset(offset).
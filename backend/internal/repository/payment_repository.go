@@ -14,6 +14,7 @@ type PaymentRepository interface {
 	GetByPaymentIntentID(paymentIntentID string) (*model.Payment, error)
 	Update(payment *model.Payment) error
 	List(page, pageSize int) ([]model.Payment, int64, error)
+	ListByUserID(userID uint) ([]model.Payment, error)
 }
 
 type paymentRepository struct {
@@ -91,4 +92,14 @@ func (r *paymentRepository) List(page, pageSize int) ([]model.Payment, int64, er
 		Find(&payments).Error
 
 	return payments, total, err
+}
+
+// ユーザーの注文に紐づく決済一覧（データエクスポート用）
+func (r *paymentRepository) ListByUserID(userID uint) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := r.db.Joins("JOIN orders ON orders.id = payments.order_id").
+		Where("orders.user_id = ?", userID).
+		Order("payments.created_at DESC").
+		Find(&payments).Error
+	return payments, err
 }
\ No newline at end of file
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type ProductImageRepository interface {
+	Create(image *model.ProductImage) error
+	GetByID(productID, id uint) (*model.ProductImage, error)
+	ListByProductID(productID uint) ([]model.ProductImage, error)
+	CountByProductID(productID uint) (int64, error)
+	Update(image *model.ProductImage) error
+	Delete(productID, id uint) error
+	Reorder(productID uint, imageIDs []uint) error
+	SyncProductImageURL(productID uint) error
+	ListUnwatermarked(afterID uint, limit int) ([]model.ProductImage, error)
+}
+
+type productImageRepository struct {
+	db *gorm.DB
+}
+
+func NewProductImageRepository(db *gorm.DB) ProductImageRepository {
+	return &productImageRepository{db: db}
+}
+
+func (r *productImageRepository) Create(image *model.ProductImage) error {
+	return r.db.Create(image).Error
+}
+
+// 指定商品の画像のみ取得
+func (r *productImageRepository) GetByID(productID, id uint) (*model.ProductImage, error) {
+	var image model.ProductImage
+	err := r.db.Where("id = ? AND product_id = ?", id, productID).First(&image).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("image not found")
+		}
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r *productImageRepository) ListByProductID(productID uint) ([]model.ProductImage, error) {
+	var images []model.ProductImage
+	err := r.db.Where("product_id = ?", productID).Order("position").Order("id").Find(&images).Error
+	return images, err
+}
+
+func (r *productImageRepository) CountByProductID(productID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.ProductImage{}).Where("product_id = ?", productID).Count(&count).Error
+	return count, err
+}
+
+func (r *productImageRepository) Update(image *model.ProductImage) error {
+	return r.db.Save(image).Error
+}
+
+func (r *productImageRepository) Delete(productID, id uint) error {
+	return r.db.Where("id = ? AND product_id = ?", id, productID).Delete(&model.ProductImage{}).Error
+}
+
+// 指定した順に表示順を振り直す
+func (r *productImageRepository) Reorder(productID uint, imageIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range imageIDs {
+			if err := tx.Model(&model.ProductImage{}).
+				Where("id = ? AND product_id = ?", id, productID).
+				Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 商品のimage_urlを先頭の画像（中サイズ）に合わせる（画像が無い場合は変更しない）
+func (r *productImageRepository) SyncProductImageURL(productID uint) error {
+	var image model.ProductImage
+	err := r.db.Where("product_id = ?", productID).Order("position").Order("id").First(&image).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return r.db.Model(&model.Product{}).Where("id = ?", productID).Update("image_url", image.MediumURL).Error
+}
+
+// 透かし導入前にアップロードされた画像
+func (r *productImageRepository) ListUnwatermarked(afterID uint, limit int) ([]model.ProductImage, error) {
+	var images []model.ProductImage
+	err := r.db.Where("watermarked = ?", false).Order("id").Limit(limit).Find(&images).Error
+	return images, err
+}
\ No newline at end of file
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type ProductImportRepository interface {
+	Create(job *model.ProductImportJob) error
+	GetByID(id uint) (*model.ProductImportJob, error)
+	Update(job *model.ProductImportJob) error
+	List(page, pageSize int) ([]model.ProductImportJob, int64, error)
+	FailUnfinished(message string) (int64, error)
+}
+
+type productImportRepository struct {
+	db *gorm.DB
+}
+
+func NewProductImportRepository(db *gorm.DB) ProductImportRepository {
+	return &productImportRepository{db: db}
+}
+
+func (r *productImportRepository) Create(job *model.ProductImportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *productImportRepository) GetByID(id uint) (*model.ProductImportJob, error) {
+	var job model.ProductImportJob
+	err := r.db.First(&job, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("import job not found")
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *productImportRepository) Update(job *model.ProductImportJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *productImportRepository) List(page, pageSize int) ([]model.ProductImportJob, int64, error) {
+	var jobs []model.ProductImportJob
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	if err := r.db.Model(&model.ProductImportJob{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 一覧では行ごとのエラーを返さない
+	err := r.db.Omit("errors").Order("created_at DESC").Order("id DESC").Offset(offset).Limit(pageSize).Find(&jobs).Error
+	return jobs, total, err
+}
+
+// 完了していないジョブを失敗にする（サーバーの再起動で中断されたジョブ）
+func (r *productImportRepository) FailUnfinished(message string) (int64, error) {
+	result := r.db.Model(&model.ProductImportJob{}).
+		Where("status IN ?", []string{model.ImportJobStatusPending, model.ImportJobStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":      model.ImportJobStatusFailed,
+			"message":     message,
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
\ No newline at end of file
@@ -2,20 +2,29 @@ package repository
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/Naonao3/EC-site/backend/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepository interface {
 	Create(product *model.Product) error
 	GetByID(id uint) (*model.Product, error)
+	GetBySKU(sku string) (*model.Product, error)
+	SKUExists(sku string, excludeID uint) (bool, error)
+	AssignMissingSKUs() error
+	FindInBatches(batchSize int, fn func(products []model.Product) error) error
 	Update(product *model.Product) error
 	Delete(id uint) error
 	List(page, pageSize int) ([]model.Product, int64, error)
 	GetByCategory(category string, page, pageSize int) ([]model.Product, int64, error)
-	Search(keyword string, page, pageSize int) ([]model.Product, int64, error)
-	UpdateStock(id uint, quantity int) error
+	GetByCategoryIDs(categoryIDs []uint, page, pageSize int) ([]model.Product, int64, error)
+	Search(params model.ProductSearchParams) ([]model.Product, int64, error)
+	SearchFacets(params model.ProductSearchParams) (*model.SearchFacets, error)
+	EnsureSearchIndexes() error
+	WithTx(tx *gorm.DB) ProductRepository
 }
 
 type productRepository struct {
@@ -26,13 +35,25 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	return &productRepository{db: db}
 }
 
+// トランザクション内で操作するリポジトリ
+func (r *productRepository) WithTx(tx *gorm.DB) ProductRepository {
+	return &productRepository{db: tx}
+}
+
+// 選択肢・バリエーション・画像・ライセンスは専用のAPIで更新するため関連は保存しない
+// 在庫は変動履歴と合わせて増減するため作成時には保存しない
 func (r *productRepository) Create(product *model.Product) error {
-	return r.db.Create(product).Error
+	return r.db.Omit(clause.Associations, "stock").Create(product).Error
 }
 
 func (r *productRepository) GetByID(id uint) (*model.Product, error) {
 	var product model.Product
-	err := r.db.First(&product, id).Error
+	err := r.db.
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position").Order("id") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("position").Order("id") }).
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position").Order("id") }).
+		Preload("Licenses", func(db *gorm.DB) *gorm.DB { return db.Order("price").Order("id") }).
+		First(&product, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("product not found")
@@ -42,8 +63,47 @@ func (r *productRepository) GetByID(id uint) (*model.Product, error) {
 	return &product, nil
 }
 
+// 商品コードで取得（見つからない場合はnil）
+func (r *productRepository) GetBySKU(sku string) (*model.Product, error) {
+	var product model.Product
+	err := r.db.Where("sku = ?", sku).First(&product).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r.GetByID(product.ID)
+}
+
+// 商品コードが他の商品で使われているか（一意制約に合わせて削除済みの商品も含める）
+func (r *productRepository) SKUExists(sku string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.Product{}).
+		Where("sku = ? AND id <> ?", sku, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// 商品コードの無い商品に既定の商品コードを割り当て（起動時に毎回実行・冪等）
+func (r *productRepository) AssignMissingSKUs() error {
+	return r.db.Unscoped().Model(&model.Product{}).
+		Where("sku IS NULL").
+		Update("sku", gorm.Expr("'PRD-' || LPAD(id::text, 6, '0')")).Error
+}
+
+// 全商品をID順に一定件数ずつ処理（エクスポート用）
+func (r *productRepository) FindInBatches(batchSize int, fn func(products []model.Product) error) error {
+	var products []model.Product
+	result := r.db.FindInBatches(&products, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(products)
+	})
+	return result.Error
+}
+
+// 在庫は変動履歴と合わせて、確保数は在庫予約でのみ増減するため更新しない
 func (r *productRepository) Update(product *model.Product) error {
-	return r.db.Save(product).Error
+	return r.db.Omit(clause.Associations, "stock", "reserved").Save(product).Error
 }
 
 func (r *productRepository) Delete(id uint) error {
@@ -80,13 +140,14 @@ func (r *productRepository) GetByCategory(category string, page, pageSize int) (
 	return products, total, err
 }
 
-func (r *productRepository) Search(keyword string, page, pageSize int) ([]model.Product, int64, error) {
+// 複数カテゴリ（子孫カテゴリを含む）の商品一覧
+func (r *productRepository) GetByCategoryIDs(categoryIDs []uint, page, pageSize int) ([]model.Product, int64, error) {
 	var products []model.Product
 	var total int64
 
 	offset := (page - 1) * pageSize
 
-	query := r.db.Model(&model.Product{}).Where("name ILIKE ? OR description ILIKE ?", "%"+keyword+"%", "%"+keyword+"%")
+	query := r.db.Model(&model.Product{}).Where("category_id IN ?", categoryIDs)
 
 	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -96,6 +157,198 @@ func (r *productRepository) Search(keyword string, page, pageSize int) ([]model.
 	return products, total, err
 }
 
-func (r *productRepository) UpdateStock(id uint, quantity int) error {
-	return r.db.Model(&model.Product{}).Where("id = ?", id).Update("stock", gorm.Expr("stock + ?", quantity)).Error
-}
\ No newline at end of file
+// 全文検索用の列とインデックスを作成（起動時に毎回実行・冪等）
+// search_vectorは単語単位の検索と関連度、pg_trgmのインデックスは日本語の部分一致と表記揺れに使う
+func (r *productRepository) EnsureSearchIndexes() error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+				setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+			) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_description_trgm ON products USING GIN (description gin_trgm_ops)`,
+	}
+	for _, statement := range statements {
+		if err := r.db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// キーワード・カテゴリ・価格帯・在庫で絞り込み、指定の順に並べて検索
+func (r *productRepository) Search(params model.ProductSearchParams) ([]model.Product, int64, error) {
+	var products []model.Product
+	var total int64
+
+	err := r.withSearchSession(params, func(db *gorm.DB) error {
+		offset := (params.Page - 1) * params.PageSize
+
+		if err := r.searchQuery(db, params, "").Count(&total).Error; err != nil {
+			return err
+		}
+
+		query := r.searchQuery(db, params, "")
+		switch params.Sort {
+		case model.ProductSortRelevance:
+			if params.Keyword == "" {
+				query = query.Select("products.*")
+				break
+			}
+			// 単語一致の重み・名前との類似度・名前の部分一致を合計した関連度
+			like := "%" + escapeLike(params.Keyword) + "%"
+			query = query.
+				Select(`products.*, ts_rank(products.search_vector, websearch_to_tsquery('simple', ?)) * 2
+					+ word_similarity(?, products.name)
+					+ CASE WHEN products.name ILIKE ? THEN 1 ELSE 0 END AS relevance`,
+					params.Keyword, params.Keyword, like).
+				Order("relevance DESC")
+		case model.ProductSortPriceAsc:
+			query = query.Select("products.*").Order("products.price ASC")
+		case model.ProductSortPriceDesc:
+			query = query.Select("products.*").Order("products.price DESC")
+		case model.ProductSortPopularity:
+			// キャンセルされていない注文の販売数
+			query = query.
+				Select("products.*").
+				Joins(`LEFT JOIN (
+					SELECT order_items.product_id, SUM(order_items.quantity) AS sold
+					FROM order_items
+					JOIN orders ON orders.id = order_items.order_id
+					WHERE orders.status <> 'cancelled' AND orders.deleted_at IS NULL
+					GROUP BY order_items.product_id
+				) AS sales ON sales.product_id = products.id`).
+				Order("COALESCE(sales.sold, 0) DESC")
+		default:
+			query = query.Select("products.*")
+		}
+		query = query.Order("products.created_at DESC").Order("products.id DESC")
+
+		return query.Offset(offset).Limit(params.PageSize).Find(&products).Error
+	})
+	return products, total, err
+}
+
+// 絞り込み候補ごとの件数（各ファセットは自身の条件を除いて集計し、選択を切り替えた場合の件数を示す）
+func (r *productRepository) SearchFacets(params model.ProductSearchParams) (*model.SearchFacets, error) {
+	facets := &model.SearchFacets{
+		Categories:  []model.CategoryFacet{},
+		PriceRanges: []model.PriceRangeFacet{},
+	}
+
+	err := r.withSearchSession(params, func(db *gorm.DB) error {
+		err := r.searchQuery(db, params, searchFilterCategory).
+			Select("products.category_id AS id, categories.name AS name, COUNT(*) AS count").
+			Joins("JOIN categories ON categories.id = products.category_id").
+			Group("products.category_id, categories.name").
+			Order("count DESC").Order("categories.name").
+			Scan(&facets.Categories).Error
+		if err != nil {
+			return err
+		}
+
+		selects := make([]string, 0, len(priceRangeBounds))
+		vars := make([]interface{}, 0, len(priceRangeBounds)*2)
+		for i, lower := range priceRangeBounds {
+			if i+1 < len(priceRangeBounds) {
+				selects = append(selects, "COUNT(*) FILTER (WHERE products.price >= ? AND products.price < ?)")
+				vars = append(vars, lower, priceRangeBounds[i+1])
+			} else {
+				selects = append(selects, "COUNT(*) FILTER (WHERE products.price >= ?)")
+				vars = append(vars, lower)
+			}
+		}
+		counts := make([]int64, len(priceRangeBounds))
+		dest := make([]interface{}, len(counts))
+		for i := range counts {
+			dest[i] = &counts[i]
+		}
+		row := r.searchQuery(db, params, searchFilterPrice).
+			Select(strings.Join(selects, ", "), vars...).
+			Row()
+		if err := row.Scan(dest...); err != nil {
+			return err
+		}
+		for i, lower := range priceRangeBounds {
+			facet := model.PriceRangeFacet{Min: lower, Count: counts[i]}
+			if i+1 < len(priceRangeBounds) {
+				upper := priceRangeBounds[i+1]
+				facet.Max = &upper
+			}
+			facets.PriceRanges = append(facets.PriceRanges, facet)
+		}
+
+		return r.searchQuery(db, params, searchFilterStock).
+			Where("products.stock > products.reserved").
+			Count(&facets.InStock).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// ファセット集計で除外する絞り込み条件
+const (
+	searchFilterCategory = "category"
+	searchFilterPrice    = "price"
+	searchFilterStock    = "stock"
+)
+
+// 価格帯ファセットの区切り（円）
+var priceRangeBounds = []float64{0, 1000, 3000, 5000, 10000}
+
+// 曖昧一致とみなすword_similarityの下限
+const searchSimilarityThreshold = "0.4"
+
+// キーワード検索はトランザクション内でpg_trgm.word_similarity_thresholdを設定して実行する
+// （<%演算子はこの閾値を使い、名前のトライグラムインデックスで絞り込める）
+func (r *productRepository) withSearchSession(params model.ProductSearchParams, fn func(db *gorm.DB) error) error {
+	if params.Keyword == "" {
+		return fn(r.db)
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)", searchSimilarityThreshold).Error
+		if err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// 検索条件のクエリ（skipで指定した絞り込み条件は適用しない）
+func (r *productRepository) searchQuery(db *gorm.DB, params model.ProductSearchParams, skip string) *gorm.DB {
+	query := db.Model(&model.Product{})
+
+	if params.Keyword != "" {
+		like := "%" + escapeLike(params.Keyword) + "%"
+		query = query.Where(`(products.search_vector @@ websearch_to_tsquery('simple', ?)
+			OR products.name ILIKE ? OR products.description ILIKE ?
+			OR ? <% products.name)`,
+			params.Keyword, like, like, params.Keyword)
+	}
+	if skip != searchFilterCategory && len(params.CategoryIDs) > 0 {
+		query = query.Where("products.category_id IN ?", params.CategoryIDs)
+	}
+	if skip != searchFilterPrice {
+		if params.MinPrice != nil {
+			query = query.Where("products.price >= ?", *params.MinPrice)
+		}
+		if params.MaxPrice != nil {
+			query = query.Where("products.price <= ?", *params.MaxPrice)
+		}
+	}
+	if skip != searchFilterStock && params.InStock {
+		query = query.Where("products.stock > products.reserved")
+	}
+
+	return query
+}
+
+// LIKEのワイルドカード文字をエスケープ
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type ReservationRepository interface {
+	Reserve(reservations []model.InventoryReservation) (bool, error)
+	Commit(orderID uint) (int, int, error)
+	Release(orderID uint, reason string) (int, error)
+	Restock(orderID uint) (int, error)
+	ListByOrderID(orderID uint) ([]model.InventoryReservation, error)
+	ListExpiredOrderIDs(now time.Time, limit int) ([]uint, error)
+	WithTx(tx *gorm.DB) ReservationRepository
+}
+
+type reservationRepository struct {
+	db *gorm.DB
+}
+
+func NewReservationRepository(db *gorm.DB) ReservationRepository {
+	return &reservationRepository{db: db}
+}
+
+// トランザクション内で操作するリポジトリ
+func (r *reservationRepository) WithTx(tx *gorm.DB) ReservationRepository {
+	return &reservationRepository{db: tx}
+}
+
+// 在庫の確保できる分だけ確保数を増やし予約を作成（1件でも不足した場合は何も確保しない）
+func (r *reservationRepository) Reserve(reservations []model.InventoryReservation) (bool, error) {
+	if len(reservations) == 0 {
+		return true, nil
+	}
+
+	errInsufficient := errors.New("insufficient stock")
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, reservation := range reservations {
+			// 出荷元の倉庫の確保できる在庫から確保する
+			if reservation.WarehouseID != nil {
+				result := warehouseStockQuery(tx, reservation.ProductID, reservation.VariantID).
+					Where("warehouse_id = ? AND stock - reserved >= ?", *reservation.WarehouseID, reservation.Quantity).
+					Update("reserved", gorm.Expr("reserved + ?", reservation.Quantity))
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					return errInsufficient
+				}
+			}
+
+			// バリエーションのある商品はバリエーションの在庫で判定し、商品の確保数は合計として増やす
+			if reservation.VariantID != nil {
+				result := tx.Model(&model.ProductVariant{}).
+					Where("id = ? AND stock - reserved >= ?", *reservation.VariantID, reservation.Quantity).
+					Update("reserved", gorm.Expr("reserved + ?", reservation.Quantity))
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					return errInsufficient
+				}
+				if err := tx.Model(&model.Product{}).
+					Where("id = ?", reservation.ProductID).
+					Update("reserved", gorm.Expr("reserved + ?", reservation.Quantity)).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			result := tx.Model(&model.Product{}).
+				Where("id = ? AND stock - reserved >= ?", reservation.ProductID, reservation.Quantity).
+				Update("reserved", gorm.Expr("reserved + ?", reservation.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errInsufficient
+			}
+		}
+		return tx.Create(&reservations).Error
+	})
+	if errors.Is(err, errInsufficient) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// 注文の予約を在庫から引き当てる（確保数と在庫を減らす）
+// 期限切れで解放された予約は決済が間に合わなかった分として、他の注文が確保していない在庫から引き当てる
+// 引き当てた件数と、在庫が足りず引き当てられなかった件数を返す（不足した注文は手動での対応が必要として記録）
+func (r *reservationRepository) Commit(orderID uint) (int, int, error) {
+	late, short := 0, 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var reservations []model.InventoryReservation
+		err := tx.Where("order_id = ? AND (status = ? OR (status = ? AND release_reason = ?))",
+			orderID, model.ReservationStatusActive, model.ReservationStatusReleased, model.ReservationReleaseExpired).
+			Order("id").Find(&reservations).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, reservation := range reservations {
+			if reservation.Status != model.ReservationStatusActive {
+				// 在庫が足りない場合はこの予約の更新だけを取り消す（セーブポイント）
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					claimed, err := r.claimCommit(tx, reservation, now)
+					if err != nil || !claimed {
+						return err
+					}
+					if err := r.takeUnreserved(tx, reservation); err != nil {
+						return err
+					}
+					late++
+					return r.recordMovement(tx, reservation, model.StockMovementOrder, -reservation.Quantity)
+				})
+				if errors.Is(err, errUnreservedShortage) {
+					short++
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			claimed, err := r.claimCommit(tx, reservation, now)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+			err = r.adjust(tx, reservation, map[string]interface{}{
+				"stock":    gorm.Expr("stock - ?", reservation.Quantity),
+				"reserved": gorm.Expr("GREATEST(reserved - ?, 0)", reservation.Quantity),
+			})
+			if err != nil {
+				return err
+			}
+			if err := r.recordMovement(tx, reservation, model.StockMovementOrder, -reservation.Quantity); err != nil {
+				return err
+			}
+		}
+
+		if short > 0 {
+			return tx.Model(&model.Order{}).Where("id = ?", orderID).Update("stock_shortage", true).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return late, short, nil
+}
+
+var errUnreservedShortage = errors.New("insufficient unreserved stock")
+
+// 予約を引き当て済みにする（期限切れの解放と同時に実行された場合に二重に処理しないよう、状態を条件に更新）
+func (r *reservationRepository) claimCommit(tx *gorm.DB, reservation model.InventoryReservation, now time.Time) (bool, error) {
+	result := tx.Model(&model.InventoryReservation{}).
+		Where("id = ? AND status = ?", reservation.ID, reservation.Status).
+		Updates(map[string]interface{}{
+			"status":       model.ReservationStatusCommitted,
+			"committed_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// 確保されていない在庫から引き当てる（倉庫・バリエーション・商品のいずれかで不足する場合はerrUnreservedShortage）
+func (r *reservationRepository) takeUnreserved(tx *gorm.DB, reservation model.InventoryReservation) error {
+	var queries []*gorm.DB
+	if reservation.WarehouseID != nil {
+		queries = append(queries, warehouseStockQuery(tx, reservation.ProductID, reservation.VariantID).
+			Where("warehouse_id = ?", *reservation.WarehouseID))
+	}
+	if reservation.VariantID != nil {
+		queries = append(queries, tx.Unscoped().Model(&model.ProductVariant{}).Where("id = ?", *reservation.VariantID))
+	}
+	queries = append(queries, tx.Unscoped().Model(&model.Product{}).Where("id = ?", reservation.ProductID))
+
+	for _, query := range queries {
+		result := query.Where("stock - reserved >= ?", reservation.Quantity).
+			Update("stock", gorm.Expr("stock - ?", reservation.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUnreservedShortage
+		}
+	}
+	return nil
+}
+
+// 注文の確保中の予約を解放し、解放した件数を返す
+func (r *reservationRepository) Release(orderID uint, reason string) (int, error) {
+	released := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var reservations []model.InventoryReservation
+		err := tx.Where("order_id = ? AND status = ?", orderID, model.ReservationStatusActive).
+			Order("id").Find(&reservations).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, reservation := range reservations {
+			result := tx.Model(&model.InventoryReservation{}).
+				Where("id = ? AND status = ?", reservation.ID, model.ReservationStatusActive).
+				Updates(map[string]interface{}{
+					"status":         model.ReservationStatusReleased,
+					"released_at":    now,
+					"release_reason": reason,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			err := r.adjust(tx, reservation, map[string]interface{}{
+				"reserved": gorm.Expr("GREATEST(reserved - ?, 0)", reservation.Quantity),
+			})
+			if err != nil {
+				return err
+			}
+			released++
+		}
+		return nil
+	})
+	return released, err
+}
+
+// 決済済みの注文のキャンセルで引き当てた在庫を戻し、戻した件数を返す
+func (r *reservationRepository) Restock(orderID uint) (int, error) {
+	restocked := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var reservations []model.InventoryReservation
+		err := tx.Where("order_id = ? AND status = ?", orderID, model.ReservationStatusCommitted).
+			Order("id").Find(&reservations).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, reservation := range reservations {
+			result := tx.Model(&model.InventoryReservation{}).
+				Where("id = ? AND status = ?", reservation.ID, model.ReservationStatusCommitted).
+				Updates(map[string]interface{}{
+					"status":         model.ReservationStatusRestocked,
+					"released_at":    now,
+					"release_reason": model.ReservationReleaseCancelled,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			err := r.adjust(tx, reservation, map[string]interface{}{
+				"stock": gorm.Expr("stock + ?", reservation.Quantity),
+			})
+			if err != nil {
+				return err
+			}
+			if err := r.recordMovement(tx, reservation, model.StockMovementCancellation, reservation.Quantity); err != nil {
+				return err
+			}
+			restocked++
+		}
+		return nil
+	})
+	return restocked, err
+}
+
+func (r *reservationRepository) ListByOrderID(orderID uint) ([]model.InventoryReservation, error) {
+	var reservations []model.InventoryReservation
+	err := r.db.Preload("Warehouse").Where("order_id = ?", orderID).Order("id").Find(&reservations).Error
+	return reservations, err
+}
+
+// 確保期限を過ぎた予約のある注文
+func (r *reservationRepository) ListExpiredOrderIDs(now time.Time, limit int) ([]uint, error) {
+	var orderIDs []uint
+	err := r.db.Model(&model.InventoryReservation{}).
+		Where("status = ? AND expires_at < ?", model.ReservationStatusActive, now).
+		Distinct("order_id").
+		Order("order_id").
+		Limit(limit).
+		Pluck("order_id", &orderIDs).Error
+	return orderIDs, err
+}
+
+// 予約した商品（とバリエーション、出荷元の倉庫）の在庫・確保数を更新
+// 予約後に削除された商品・バリエーションも確保数を戻すため削除済みを含める
+func (r *reservationRepository) adjust(tx *gorm.DB, reservation model.InventoryReservation, updates map[string]interface{}) error {
+	if reservation.WarehouseID != nil {
+		if err := warehouseStockQuery(tx, reservation.ProductID, reservation.VariantID).
+			Where("warehouse_id = ?", *reservation.WarehouseID).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	if reservation.VariantID != nil {
+		if err := tx.Unscoped().Model(&model.ProductVariant{}).
+			Where("id = ?", *reservation.VariantID).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Unscoped().Model(&model.Product{}).
+		Where("id = ?", reservation.ProductID).
+		Updates(updates).Error
+}
+
+// 注文による在庫の変動を出荷元の倉庫の履歴として記録（バリエーションのある商品はバリエーションの履歴として記録）
+func (r *reservationRepository) recordMovement(tx *gorm.DB, reservation model.InventoryReservation, movementType string, quantity int) error {
+	source := model.StockMovementSource{
+		Type:          movementType,
+		ReferenceType: model.StockReferenceOrder,
+		ReferenceID:   &reservation.OrderID,
+		WarehouseID:   reservation.WarehouseID,
+	}
+	return tx.Create(source.Movement(reservation.ProductID, reservation.VariantID, quantity)).Error
+}
\ No newline at end of file
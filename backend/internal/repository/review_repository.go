@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 商品レビュー一覧の並び順
+const (
+	ReviewSortNewest     = "newest"
+	ReviewSortHelpful    = "helpful"
+	ReviewSortRatingDesc = "rating_desc"
+	ReviewSortRatingAsc  = "rating_asc"
+)
+
+type ReviewRepository interface {
+	Create(review *model.Review) error
+	GetByID(id uint) (*model.Review, error)
+	GetByUserAndProduct(userID, productID uint) (*model.Review, error)
+	ListByProductID(productID uint, sort string, page, pageSize int) ([]model.Review, int64, error)
+	ListByUserID(userID uint) ([]model.Review, error)
+	List(status string, page, pageSize int) ([]model.Review, int64, error)
+	Update(review *model.Review) error
+	Delete(id uint) error
+	FindDeliveredOrderID(userID, productID uint) (uint, error)
+	AddVote(reviewID, userID uint) (bool, error)
+	RemoveVote(reviewID, userID uint) (bool, error)
+	Summary(productID uint) (*model.ReviewSummary, error)
+	RefreshProductRating(productID uint) error
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+func (r *reviewRepository) Create(review *model.Review) error {
+	return r.db.Omit("User").Create(review).Error
+}
+
+func (r *reviewRepository) GetByID(id uint) (*model.Review, error) {
+	var review model.Review
+	err := r.db.Preload("User").First(&review, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("review not found")
+		}
+		return nil, err
+	}
+	setReviewerName(&review)
+	return &review, nil
+}
+
+// 存在しない場合はnilを返す
+func (r *reviewRepository) GetByUserAndProduct(userID, productID uint) (*model.Review, error) {
+	var review model.Review
+	err := r.db.Where("user_id = ? AND product_id = ?", userID, productID).First(&review).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &review, nil
+}
+
+// 商品の公開中のレビュー一覧
+func (r *reviewRepository) ListByProductID(productID uint, sort string, page, pageSize int) ([]model.Review, int64, error) {
+	var reviews []model.Review
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := r.db.Model(&model.Review{}).Where("product_id = ? AND status = ?", productID, model.ReviewStatusApproved)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	switch sort {
+	case ReviewSortHelpful:
+		query = query.Order("helpful_count DESC")
+	case ReviewSortRatingDesc:
+		query = query.Order("rating DESC")
+	case ReviewSortRatingAsc:
+		query = query.Order("rating ASC")
+	}
+
+	err := query.Preload("User").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&reviews).Error
+	for i := range reviews {
+		setReviewerName(&reviews[i])
+	}
+
+	return reviews, total, err
+}
+
+// ユーザーが投稿したレビュー（非公開のものを含む）
+func (r *reviewRepository) ListByUserID(userID uint) ([]model.Review, error) {
+	var reviews []model.Review
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&reviews).Error
+	return reviews, err
+}
+
+// レビュー一覧（管理者用、statusが空の場合は全件）
+func (r *reviewRepository) List(status string, page, pageSize int) ([]model.Review, int64, error) {
+	var reviews []model.Review
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := r.db.Model(&model.Review{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("User").
+		Order("created_at ASC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&reviews).Error
+	for i := range reviews {
+		setReviewerName(&reviews[i])
+	}
+
+	return reviews, total, err
+}
+
+func (r *reviewRepository) Update(review *model.Review) error {
+	return r.db.Omit("User").Save(review).Error
+}
+
+// レビューと投票を削除
+func (r *reviewRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("review_id = ?", id).Delete(&model.ReviewVote{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Review{}, id).Error
+	})
+}
+
+// 商品を含む配送済みの注文（購入確認用、存在しない場合は0）
+func (r *reviewRepository) FindDeliveredOrderID(userID, productID uint) (uint, error) {
+	var orderIDs []uint
+	err := r.db.Model(&model.Order{}).
+		Joins("JOIN order_items ON order_items.order_id = orders.id").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?", userID, "delivered", productID).
+		Order("orders.created_at DESC").
+		Limit(1).
+		Pluck("orders.id", &orderIDs).Error
+	if err != nil || len(orderIDs) == 0 {
+		return 0, err
+	}
+	return orderIDs[0], nil
+}
+
+// 「参考になった」の投票（既に投票済みの場合はfalse）
+func (r *reviewRepository) AddVote(reviewID, userID uint) (bool, error) {
+	added := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.ReviewVote{ReviewID: reviewID, UserID: userID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		added = true
+		return tx.Model(&model.Review{}).Where("id = ?", reviewID).
+			Update("helpful_count", gorm.Expr("helpful_count + 1")).Error
+	})
+	return added, err
+}
+
+// 投票の取り消し（投票していない場合はfalse）
+func (r *reviewRepository) RemoveVote(reviewID, userID uint) (bool, error) {
+	removed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("review_id = ? AND user_id = ?", reviewID, userID).Delete(&model.ReviewVote{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		removed = true
+		return tx.Model(&model.Review{}).Where("id = ? AND helpful_count > 0", reviewID).
+			Update("helpful_count", gorm.Expr("helpful_count - 1")).Error
+	})
+	return removed, err
+}
+
+// 商品の公開中のレビューの平均評価と評価ごとの件数
+func (r *reviewRepository) Summary(productID uint) (*model.ReviewSummary, error) {
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	err := r.db.Model(&model.Review{}).
+		Select("rating, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, model.ReviewStatusApproved).
+		Group("rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &model.ReviewSummary{Distribution: map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	sum := int64(0)
+	for _, row := range rows {
+		summary.Distribution[row.Rating] = row.Count
+		summary.Count += row.Count
+		sum += int64(row.Rating) * row.Count
+	}
+	if summary.Count > 0 {
+		summary.Average = roundRating(float64(sum) / float64(summary.Count))
+	}
+
+	return summary, nil
+}
+
+// 商品の平均評価とレビュー数を再計算して保存
+func (r *reviewRepository) RefreshProductRating(productID uint) error {
+	return refreshProductRatings(r.db, []uint{productID})
+}
+
+// 商品一覧で使う平均評価とレビュー数（公開中のレビューのみ）を再計算
+func refreshProductRatings(db *gorm.DB, productIDs []uint) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	return db.Exec(`
+		UPDATE products SET
+			rating_average = COALESCE((
+				SELECT ROUND(AVG(rating)::numeric, 2) FROM reviews
+				WHERE reviews.product_id = products.id AND reviews.status = ?
+			), 0),
+			rating_count = (
+				SELECT COUNT(*) FROM reviews
+				WHERE reviews.product_id = products.id AND reviews.status = ?
+			)
+		WHERE id IN ?`,
+		model.ReviewStatusApproved, model.ReviewStatusApproved, productIDs).Error
+}
+
+// 平均評価を小数点以下2桁に丸める
+func roundRating(value float64) float64 {
+	return float64(int(value*100+0.5)) / 100
+}
+
+// 表示用の投稿者名（メールアドレスなどは返さない）
+func setReviewerName(review *model.Review) {
+	if review.User != nil {
+		review.ReviewerName = review.User.Name
+	}
+}
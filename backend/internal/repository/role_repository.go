@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	EnsurePermission(permission *model.Permission) error
+	ListPermissions() ([]model.Permission, error)
+	GetPermissionsByNames(names []string) ([]model.Permission, error)
+	Create(role *model.Role) error
+	GetByID(id uint) (*model.Role, error)
+	GetByName(name string) (*model.Role, error)
+	Update(role *model.Role) error
+	Delete(id uint) error
+	List() ([]model.Role, error)
+	GetByIDs(ids []uint) ([]model.Role, error)
+	GetUserRoles(userID uint) ([]model.Role, error)
+	SetUserRoles(userID uint, roles []model.Role) error
+	GetPermissionNamesByUserID(userID uint) ([]string, error)
+	AssignRoleByLegacyRole(roleID uint, legacyRole string) error
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// 権限が存在しなければ作成
+func (r *roleRepository) EnsurePermission(permission *model.Permission) error {
+	return r.db.Where(model.Permission{Name: permission.Name}).
+		Attrs(model.Permission{Description: permission.Description}).
+		FirstOrCreate(permission).Error
+}
+
+func (r *roleRepository) ListPermissions() ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) GetPermissionsByNames(names []string) ([]model.Permission, error) {
+	var permissions []model.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	err := r.db.Where("name IN ?", names).Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) Create(role *model.Role) error {
+	return r.db.Create(role).Error
+}
+
+func (r *roleRepository) GetByID(id uint) (*model.Role, error) {
+	var role model.Role
+	err := r.db.Preload("Permissions").First(&role, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("role not found")
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) GetByName(name string) (*model.Role, error) {
+	var role model.Role
+	err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// ロールと権限の紐付けを更新
+func (r *roleRepository) Update(role *model.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Save(role).Error; err != nil {
+			return err
+		}
+		return tx.Model(role).Association("Permissions").Replace(role.Permissions)
+	})
+}
+
+func (r *roleRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		role := &model.Role{ID: id}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM user_roles WHERE role_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+}
+
+func (r *roleRepository) List() ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) GetByIDs(ids []uint) ([]model.Role, error) {
+	var roles []model.Role
+	if len(ids) == 0 {
+		return roles, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) GetUserRoles(userID uint) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) SetUserRoles(userID uint, roles []model.Role) error {
+	user := &model.User{ID: userID}
+	return r.db.Model(user).Association("Roles").Replace(roles)
+}
+
+// ユーザーが持つ権限名の一覧（ロールを跨いで重複排除）
+func (r *roleRepository) GetPermissionNamesByUserID(userID uint) ([]string, error) {
+	var names []string
+	err := r.db.Model(&model.Permission{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("permissions.name").
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+// 旧来のroleカラムの値を持つユーザーにロールを割り当てる（既に割り当て済みなら何もしない）
+func (r *roleRepository) AssignRoleByLegacyRole(roleID uint, legacyRole string) error {
+	var userIDs []uint
+	if err := r.db.Model(&model.User{}).Where("role = ?", legacyRole).Pluck("id", &userIDs).Error; err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	rows := make([]map[string]interface{}, 0, len(userIDs))
+	for _, userID := range userIDs {
+		rows = append(rows, map[string]interface{}{"user_id": userID, "role_id": roleID})
+	}
+	return r.db.Table("user_roles").Clauses(clause.OnConflict{DoNothing: true}).Create(rows).Error
+}
\ No newline at end of file
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockMovementRepository interface {
+	Adjust(movement *model.StockMovement) error
+	Record(movement *model.StockMovement) error
+	WriteOff(productID uint, variantID *uint, source model.StockMovementSource) error
+	Transfer(fromWarehouseID, toWarehouseID, productID uint, variantID *uint, quantity int, source model.StockMovementSource) ([]model.StockMovement, error)
+	ListByProductID(productID uint, page, pageSize int) ([]model.StockMovement, int64, error)
+	Reconcile() ([]model.StockDiscrepancy, error)
+	ReconcileWarehouses() ([]model.WarehouseStockDiscrepancy, error)
+	RecordOpeningBalances() (int64, error)
+}
+
+type stockMovementRepository struct {
+	db *gorm.DB
+}
+
+func NewStockMovementRepository(db *gorm.DB) StockMovementRepository {
+	return &stockMovementRepository{db: db}
+}
+
+// 在庫を増減し、同じトランザクションで変動履歴を記録（確保数を下回る場合は更新せずエラー）
+// 倉庫の在庫と商品の在庫（バリエーションの場合はバリエーションと商品の在庫）を合わせて増減する
+func (r *stockMovementRepository) Adjust(movement *model.StockMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.addWarehouseStock(tx, movement, true); err != nil {
+			return err
+		}
+
+		if movement.VariantID != nil {
+			result := tx.Model(&model.ProductVariant{}).
+				Where("id = ? AND product_id = ? AND stock + ? >= reserved", *movement.VariantID, movement.ProductID, movement.Quantity).
+				Update("stock", gorm.Expr("stock + ?", movement.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("insufficient stock")
+			}
+			if err := tx.Model(&model.Product{}).
+				Where("id = ?", movement.ProductID).
+				Update("stock", gorm.Expr("stock + ?", movement.Quantity)).Error; err != nil {
+				return err
+			}
+		} else {
+			result := tx.Model(&model.Product{}).
+				Where("id = ? AND stock + ? >= reserved", movement.ProductID, movement.Quantity).
+				Update("stock", gorm.Expr("stock + ?", movement.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("insufficient stock")
+			}
+		}
+
+		return tx.Create(movement).Error
+	})
+}
+
+// 商品の在庫を変更せずに倉庫の在庫と変動履歴を記録（在庫の管理単位の切り替えなど）
+func (r *stockMovementRepository) Record(movement *model.StockMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.addWarehouseStock(tx, movement, false); err != nil {
+			return err
+		}
+		return tx.Create(movement).Error
+	})
+}
+
+// 商品（バリエーション）の全倉庫の在庫を0にして変動履歴を記録（商品の在庫は変更しない）
+func (r *stockMovementRepository) WriteOff(productID uint, variantID *uint, source model.StockMovementSource) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var stocks []model.WarehouseStock
+		if err := warehouseStockQuery(tx, productID, variantID).
+			Where("stock <> 0").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Find(&stocks).Error; err != nil {
+			return err
+		}
+
+		for _, stock := range stocks {
+			if err := tx.Model(&model.WarehouseStock{}).
+				Where("id = ?", stock.ID).
+				Update("stock", 0).Error; err != nil {
+				return err
+			}
+			source.WarehouseID = &stock.WarehouseID
+			if err := tx.Create(source.Movement(productID, variantID, -stock.Stock)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 倉庫間で在庫を移動し、移動元・移動先の変動履歴を記録（商品の在庫は変わらない）
+// 移動元で未決済の注文に確保されている分は移動できない
+func (r *stockMovementRepository) Transfer(fromWarehouseID, toWarehouseID, productID uint, variantID *uint, quantity int, source model.StockMovementSource) ([]model.StockMovement, error) {
+	source.Type = model.StockMovementTransfer
+	source.ReferenceType = model.StockReferenceWarehouse
+
+	out := source
+	out.WarehouseID = &fromWarehouseID
+	out.ReferenceID = &toWarehouseID
+	in := source
+	in.WarehouseID = &toWarehouseID
+	in.ReferenceID = &fromWarehouseID
+	movements := []model.StockMovement{
+		*out.Movement(productID, variantID, -quantity),
+		*in.Movement(productID, variantID, quantity),
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range movements {
+			if err := r.addWarehouseStock(tx, &movements[i], true); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&movements).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// 変動履歴の倉庫の在庫を増減（倉庫の指定が無い場合は既定の倉庫に記録）
+// checkReserved の場合は確保数を下回る場合に更新せずエラー
+func (r *stockMovementRepository) addWarehouseStock(tx *gorm.DB, movement *model.StockMovement, checkReserved bool) error {
+	if movement.WarehouseID == nil {
+		warehouseID, err := defaultWarehouseID(tx)
+		if err != nil {
+			return err
+		}
+		movement.WarehouseID = &warehouseID
+	}
+
+	if err := ensureWarehouseStock(tx, *movement.WarehouseID, movement.ProductID, movement.VariantID); err != nil {
+		return err
+	}
+
+	query := warehouseStockQuery(tx, movement.ProductID, movement.VariantID).Where("warehouse_id = ?", *movement.WarehouseID)
+	if checkReserved {
+		query = query.Where("stock + ? >= reserved", movement.Quantity)
+	}
+	result := query.Update("stock", gorm.Expr("stock + ?", movement.Quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("insufficient stock in warehouse")
+	}
+	return nil
+}
+
+// 商品（全バリエーションを含む）の変動履歴（新しい順）
+func (r *stockMovementRepository) ListByProductID(productID uint, page, pageSize int) ([]model.StockMovement, int64, error) {
+	var movements []model.StockMovement
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := r.db.Model(&model.StockMovement{}).Where("product_id = ?", productID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").Order("id DESC").Offset(offset).Limit(pageSize).Find(&movements).Error
+	return movements, total, err
+}
+
+// 変動履歴の合計が在庫数と一致しない商品・バリエーション・倉庫の在庫
+// バリエーションのない商品は商品単位の履歴、バリエーションのある商品は有効なバリエーションの履歴の合計と比較する
+// 倉庫の在庫はその倉庫の変動履歴の合計と比較する
+func (r *stockMovementRepository) Reconcile() ([]model.StockDiscrepancy, error) {
+	var discrepancies []model.StockDiscrepancy
+	err := r.db.Raw(`
+		SELECT p.id AS product_id, NULL::bigint AS variant_id, NULL::bigint AS warehouse_id, p.stock,
+			COALESCE(SUM(m.quantity), 0) AS ledger_stock, p.stock - COALESCE(SUM(m.quantity), 0) AS difference
+		FROM products p
+		LEFT JOIN stock_movements m ON m.product_id = p.id AND m.variant_id IS NULL
+		WHERE p.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.deleted_at IS NULL)
+		GROUP BY p.id, p.stock
+		HAVING p.stock <> COALESCE(SUM(m.quantity), 0)
+		UNION ALL
+		SELECT p.id, NULL::bigint, NULL::bigint, p.stock,
+			COALESCE(SUM(m.quantity), 0), p.stock - COALESCE(SUM(m.quantity), 0)
+		FROM products p
+		JOIN product_variants v ON v.product_id = p.id AND v.deleted_at IS NULL
+		LEFT JOIN stock_movements m ON m.variant_id = v.id
+		WHERE p.deleted_at IS NULL
+		GROUP BY p.id, p.stock
+		HAVING p.stock <> COALESCE(SUM(m.quantity), 0)
+		UNION ALL
+		SELECT v.product_id, v.id, NULL::bigint, v.stock,
+			COALESCE(SUM(m.quantity), 0), v.stock - COALESCE(SUM(m.quantity), 0)
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id AND p.deleted_at IS NULL
+		LEFT JOIN stock_movements m ON m.variant_id = v.id
+		WHERE v.deleted_at IS NULL
+		GROUP BY v.id, v.product_id, v.stock
+		HAVING v.stock <> COALESCE(SUM(m.quantity), 0)
+		UNION ALL
+		SELECT ws.product_id, ws.variant_id, ws.warehouse_id, ws.stock,
+			COALESCE(SUM(m.quantity), 0), ws.stock - COALESCE(SUM(m.quantity), 0)
+		FROM warehouse_stocks ws
+		JOIN products p ON p.id = ws.product_id AND p.deleted_at IS NULL
+		LEFT JOIN stock_movements m ON m.warehouse_id = ws.warehouse_id AND m.product_id = ws.product_id
+			AND m.variant_id IS NOT DISTINCT FROM ws.variant_id
+		GROUP BY ws.id, ws.product_id, ws.variant_id, ws.warehouse_id, ws.stock
+		HAVING ws.stock <> COALESCE(SUM(m.quantity), 0)
+		ORDER BY product_id, variant_id NULLS FIRST, warehouse_id NULLS FIRST
+	`).Scan(&discrepancies).Error
+	return discrepancies, err
+}
+
+// 商品・バリエーションの在庫数と倉庫の在庫の合計が一致しないもの
+func (r *stockMovementRepository) ReconcileWarehouses() ([]model.WarehouseStockDiscrepancy, error) {
+	var discrepancies []model.WarehouseStockDiscrepancy
+	err := r.db.Raw(`
+		SELECT p.id AS product_id, NULL::bigint AS variant_id, p.stock,
+			COALESCE(SUM(ws.stock), 0) AS warehouse_stock, p.stock - COALESCE(SUM(ws.stock), 0) AS difference
+		FROM products p
+		LEFT JOIN warehouse_stocks ws ON ws.product_id = p.id AND ws.variant_id IS NULL
+		WHERE p.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.deleted_at IS NULL)
+		GROUP BY p.id, p.stock
+		HAVING p.stock <> COALESCE(SUM(ws.stock), 0)
+		UNION ALL
+		SELECT v.product_id, v.id, v.stock,
+			COALESCE(SUM(ws.stock), 0), v.stock - COALESCE(SUM(ws.stock), 0)
+		FROM product_variants v
+		JOIN products p ON p.id = v.product_id AND p.deleted_at IS NULL
+		LEFT JOIN warehouse_stocks ws ON ws.variant_id = v.id
+		WHERE v.deleted_at IS NULL
+		GROUP BY v.id, v.product_id, v.stock
+		HAVING v.stock <> COALESCE(SUM(ws.stock), 0)
+		ORDER BY product_id, variant_id NULLS FIRST
+	`).Scan(&discrepancies).Error
+	return discrepancies, err
+}
+
+// 変動履歴の無い商品・バリエーションに現在の在庫を既定の倉庫の初期値として記録し、記録した件数を返す
+func (r *stockMovementRepository) RecordOpeningBalances() (int64, error) {
+	var recorded int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			INSERT INTO stock_movements (product_id, warehouse_id, quantity, type, created_at)
+			SELECT p.id, (SELECT id FROM warehouses WHERE is_default LIMIT 1), p.stock, ?, NOW()
+			FROM products p
+			WHERE p.deleted_at IS NULL AND p.stock <> 0
+				AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.deleted_at IS NULL)
+				AND NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.product_id = p.id AND m.variant_id IS NULL)
+		`, model.StockMovementOpening)
+		if result.Error != nil {
+			return result.Error
+		}
+		recorded += result.RowsAffected
+
+		result = tx.Exec(`
+			INSERT INTO stock_movements (product_id, variant_id, warehouse_id, quantity, type, created_at)
+			SELECT v.product_id, v.id, (SELECT id FROM warehouses WHERE is_default LIMIT 1), v.stock, ?, NOW()
+			FROM product_variants v
+			WHERE v.deleted_at IS NULL AND v.stock <> 0
+				AND NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.variant_id = v.id)
+		`, model.StockMovementOpening)
+		if result.Error != nil {
+			return result.Error
+		}
+		recorded += result.RowsAffected
+		return nil
+	})
+	return recorded, err
+}
\ No newline at end of file
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockNotificationRepository interface {
+	CreateAlert(alert *model.StockAlert) (bool, error)
+	ResolveAlerts(productID uint) error
+	ListAlerts(openOnly bool, page, pageSize int) ([]model.StockAlert, int64, error)
+	Subscribe(subscription *model.StockSubscription) error
+	DeleteSubscription(productID, userID uint) (bool, error)
+	DeleteSubscriptionByToken(token string) (bool, error)
+	ListPendingSubscriptions(productID uint) ([]model.StockSubscription, error)
+	ListSubscriptionsByUserID(userID uint) ([]model.StockSubscription, error)
+	MarkNotified(id uint) (bool, error)
+	ClearNotified(id uint) error
+}
+
+type stockNotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewStockNotificationRepository(db *gorm.DB) StockNotificationRepository {
+	return &stockNotificationRepository{db: db}
+}
+
+// 未解消のアラートが無い場合のみ作成し、作成したかを返す（同時に在庫が減っても通知は1回）
+func (r *stockNotificationRepository) CreateAlert(alert *model.StockAlert) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "product_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "resolved_at IS NULL"}}},
+		DoNothing:   true,
+	}).Create(alert)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// 在庫が発注点を上回った商品のアラートを解消
+func (r *stockNotificationRepository) ResolveAlerts(productID uint) error {
+	return r.db.Model(&model.StockAlert{}).
+		Where("product_id = ? AND resolved_at IS NULL", productID).
+		Update("resolved_at", time.Now()).Error
+}
+
+// アラート一覧（openOnlyの場合は未解消のみ）
+func (r *stockNotificationRepository) ListAlerts(openOnly bool, page, pageSize int) ([]model.StockAlert, int64, error) {
+	var alerts []model.StockAlert
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := r.db.Model(&model.StockAlert{})
+	if openOnly {
+		query = query.Where("resolved_at IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").Order("id DESC").Offset(offset).Limit(pageSize).Find(&alerts).Error
+	return alerts, total, err
+}
+
+// 入荷通知の登録（登録済みの場合は未通知に戻す。配信停止のトークンは最初の登録のものを維持）
+func (r *stockNotificationRepository) Subscribe(subscription *model.StockSubscription) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "notified_at", "updated_at"}),
+	}).Create(subscription).Error
+}
+
+func (r *stockNotificationRepository) DeleteSubscription(productID, userID uint) (bool, error) {
+	result := r.db.Where("product_id = ? AND user_id = ?", productID, userID).Delete(&model.StockSubscription{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *stockNotificationRepository) DeleteSubscriptionByToken(token string) (bool, error) {
+	result := r.db.Where("token = ?", token).Delete(&model.StockSubscription{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// まだ通知していない入荷通知の登録
+func (r *stockNotificationRepository) ListPendingSubscriptions(productID uint) ([]model.StockSubscription, error) {
+	var subscriptions []model.StockSubscription
+	err := r.db.Where("product_id = ? AND notified_at IS NULL", productID).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *stockNotificationRepository) ListSubscriptionsByUserID(userID uint) ([]model.StockSubscription, error) {
+	var subscriptions []model.StockSubscription
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// 未通知の場合のみ通知済みにする（同時に在庫が補充されても通知は1回）
+func (r *stockNotificationRepository) MarkNotified(id uint) (bool, error) {
+	result := r.db.Model(&model.StockSubscription{}).
+		Where("id = ? AND notified_at IS NULL", id).
+		Update("notified_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// 送信に失敗した通知を未通知に戻す（次の在庫の変更で再送する）
+func (r *stockNotificationRepository) ClearNotified(id uint) error {
+	return r.db.Model(&model.StockSubscription{}).
+		Where("id = ?", id).
+		Update("notified_at", nil).Error
+}
\ No newline at end of file
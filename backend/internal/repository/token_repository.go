@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefreshTokenRepository interface {
+	Create(token *model.RefreshToken) error
+	GetByHash(tokenHash string) (*model.RefreshToken, error)
+	MarkUsed(id uint, replacedByID uint) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeByUserID(userID uint) error
+	DeleteExpired() error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *refreshTokenRepository) GetByHash(tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refresh token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// 未使用のトークンのみ使用済みにする（同時リフレッシュ対策の条件付き更新）
+func (r *refreshTokenRepository) MarkUsed(id uint, replacedByID uint) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"used_at": now, "replaced_by_id": replacedByID})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// トークンファミリー全体を失効
+func (r *refreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ユーザーの全リフレッシュトークンを失効
+func (r *refreshTokenRepository) RevokeByUserID(userID uint) error {
+	return r.db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *refreshTokenRepository) DeleteExpired() error {
+	return r.db.Where("expires_at < ?", time.Now()).Delete(&model.RefreshToken{}).Error
+}
+
+type RevokedTokenRepository interface {
+	Upsert(token *model.RevokedToken) error
+	GetByKey(key string) (*model.RevokedToken, error)
+	DeleteExpired() error
+}
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{db: db}
+}
+
+func (r *revokedTokenRepository) Upsert(token *model.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "expires_at", "updated_at"}),
+	}).Create(token).Error
+}
+
+// 有効期限内の失効エントリを取得（見つからない場合はnil）
+func (r *revokedTokenRepository) GetByKey(key string) (*model.RevokedToken, error) {
+	var token model.RevokedToken
+	err := r.db.Where("key = ? AND expires_at > ?", key, time.Now()).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *revokedTokenRepository) DeleteExpired() error {
+	return r.db.Where("expires_at < ?", time.Now()).Delete(&model.RevokedToken{}).Error
+}
\ No newline at end of file
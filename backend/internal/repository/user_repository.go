@@ -12,6 +12,7 @@ type UserRepository interface {
 	GetByID(id uint) (*model.User, error)
 	GetByEmail(email string) (*model.User, error)
 	Update(user *model.User) error
+	UseTOTPStep(id uint, step int64) (bool, error)
 	Delete(id uint) error
 	List(page, pageSize int) ([]model.User, int64, error)
 }
@@ -52,8 +53,20 @@ func (r *userRepository) GetByEmail(email string) (*model.User, error) {
 	return &user, nil
 }
 
+// 最後に使用したTOTPのタイムステップはUseTOTPStepでのみ更新する（古い値で上書きしないように）
 func (r *userRepository) Update(user *model.User) error {
-	return r.db.Save(user).Error
+	return r.db.Omit("TOTPLastStep").Save(user).Error
+}
+
+// TOTPのタイムステップを使用済みにする（既に同じか新しいステップが使用済みの場合はfalse）
+func (r *userRepository) UseTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
 }
 
 func (r *userRepository) Delete(id uint) error {
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository interface {
+	Create(token *model.UserToken) error
+	GetByHash(tokenHash, purpose string) (*model.UserToken, error)
+	MarkUsed(id uint) (bool, error)
+	InvalidateByUser(userID uint, purpose string) error
+}
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+func (r *userTokenRepository) Create(token *model.UserToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *userTokenRepository) GetByHash(tokenHash, purpose string) (*model.UserToken, error) {
+	var token model.UserToken
+	err := r.db.Where("token_hash = ? AND purpose = ?", tokenHash, purpose).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// 未使用の場合のみ使用済みにする（二重使用防止）
+func (r *userTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&model.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ユーザーの未使用トークンを無効化（再発行時に古いトークンを使えなくする）
+func (r *userTokenRepository) InvalidateByUser(userID uint, purpose string) error {
+	return r.db.Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
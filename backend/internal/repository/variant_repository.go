@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+type VariantRepository interface {
+	Create(variant *model.ProductVariant) error
+	GetByID(productID, id uint) (*model.ProductVariant, error)
+	GetBySKU(sku string) (*model.ProductVariant, error)
+	ListByProductID(productID uint) ([]model.ProductVariant, error)
+	CountByProductID(productID uint) (int64, error)
+	Update(variant *model.ProductVariant) error
+	Delete(productID, id uint) error
+	SyncProductSummary(productID uint) error
+	ListOptions(productID uint) ([]model.ProductOption, error)
+	ReplaceOptions(productID uint, options []model.ProductOption) error
+}
+
+type variantRepository struct {
+	db *gorm.DB
+}
+
+func NewVariantRepository(db *gorm.DB) VariantRepository {
+	return &variantRepository{db: db}
+}
+
+// 在庫は変動履歴と合わせて増減するため作成時には保存しない
+func (r *variantRepository) Create(variant *model.ProductVariant) error {
+	return r.db.Omit("stock").Create(variant).Error
+}
+
+// 指定商品のバリエーションのみ取得
+func (r *variantRepository) GetByID(productID, id uint) (*model.ProductVariant, error) {
+	var variant model.ProductVariant
+	err := r.db.Where("id = ? AND product_id = ?", id, productID).First(&variant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("variant not found")
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// 存在しない場合はnilを返す（削除済みのSKUも重複とみなす）
+func (r *variantRepository) GetBySKU(sku string) (*model.ProductVariant, error) {
+	var variant model.ProductVariant
+	err := r.db.Unscoped().Where("sku = ?", sku).First(&variant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (r *variantRepository) ListByProductID(productID uint) ([]model.ProductVariant, error) {
+	var variants []model.ProductVariant
+	err := r.db.Where("product_id = ?", productID).Order("position").Order("id").Find(&variants).Error
+	return variants, err
+}
+
+func (r *variantRepository) CountByProductID(productID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.ProductVariant{}).Where("product_id = ?", productID).Count(&count).Error
+	return count, err
+}
+
+// 在庫は変動履歴と合わせて、確保数は在庫予約でのみ増減するため更新しない
+func (r *variantRepository) Update(variant *model.ProductVariant) error {
+	return r.db.Omit("stock", "reserved").Save(variant).Error
+}
+
+// バリエーションを削除し、カートに入っている同じバリエーションも削除
+func (r *variantRepository) Delete(productID, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND product_id = ?", id, productID).Delete(&model.ProductVariant{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("variant not found")
+		}
+		return tx.Where("variant_id = ?", id).Delete(&model.CartItem{}).Error
+	})
+}
+
+// 商品の価格（最安値）と在庫・確保数（合計）をバリエーションから再計算
+func (r *variantRepository) SyncProductSummary(productID uint) error {
+	var summary struct {
+		Count    int64
+		MinPrice float64
+		Stock    int
+		Reserved int
+	}
+	err := r.db.Model(&model.ProductVariant{}).
+		Select("COUNT(*) AS count, COALESCE(MIN(price), 0) AS min_price, COALESCE(SUM(stock), 0) AS stock, COALESCE(SUM(reserved), 0) AS reserved").
+		Where("product_id = ?", productID).
+		Scan(&summary).Error
+	if err != nil {
+		return err
+	}
+	if summary.Count == 0 {
+		return nil
+	}
+
+	return r.db.Model(&model.Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+		"price":    summary.MinPrice,
+		"stock":    summary.Stock,
+		"reserved": summary.Reserved,
+	}).Error
+}
+
+func (r *variantRepository) ListOptions(productID uint) ([]model.ProductOption, error) {
+	var options []model.ProductOption
+	err := r.db.Where("product_id = ?", productID).Order("position").Order("id").Find(&options).Error
+	return options, err
+}
+
+// 商品の選択肢を置き換える
+func (r *variantRepository) ReplaceOptions(productID uint, options []model.ProductOption) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Delete(&model.ProductOption{}).Error; err != nil {
+			return err
+		}
+		if len(options) == 0 {
+			return nil
+		}
+		for i := range options {
+			options[i].ID = 0
+			options[i].ProductID = productID
+		}
+		return tx.Create(&options).Error
+	})
+}
\ No newline at end of file
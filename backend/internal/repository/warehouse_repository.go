@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WarehouseRepository interface {
+	Create(warehouse *model.Warehouse) error
+	GetByID(id uint) (*model.Warehouse, error)
+	GetDefault() (*model.Warehouse, error)
+	CodeExists(code string, excludeID uint) (bool, error)
+	List() ([]model.Warehouse, error)
+	ListActive() ([]model.Warehouse, error)
+	Update(warehouse *model.Warehouse) error
+	SetDefault(id uint) error
+	ListStocks(productID uint, variantID *uint) ([]model.WarehouseStock, error)
+	ListStocksByWarehouse(warehouseID uint, page, pageSize int) ([]model.WarehouseStock, int64, error)
+	ListStocksByProduct(productID uint) ([]model.WarehouseStock, error)
+	MigrateLegacyStock(warehouseID uint) error
+	WithTx(tx *gorm.DB) WarehouseRepository
+}
+
+type warehouseRepository struct {
+	db *gorm.DB
+}
+
+func NewWarehouseRepository(db *gorm.DB) WarehouseRepository {
+	return &warehouseRepository{db: db}
+}
+
+// トランザクション内で操作するリポジトリ
+func (r *warehouseRepository) WithTx(tx *gorm.DB) WarehouseRepository {
+	return &warehouseRepository{db: tx}
+}
+
+func (r *warehouseRepository) Create(warehouse *model.Warehouse) error {
+	return r.db.Omit("is_default").Create(warehouse).Error
+}
+
+func (r *warehouseRepository) GetByID(id uint) (*model.Warehouse, error) {
+	var warehouse model.Warehouse
+	err := r.db.First(&warehouse, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("warehouse not found")
+		}
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+// 既定の倉庫（存在しない場合はnil）
+func (r *warehouseRepository) GetDefault() (*model.Warehouse, error) {
+	var warehouse model.Warehouse
+	err := r.db.Where("is_default = ?", true).Order("id").First(&warehouse).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+func (r *warehouseRepository) CodeExists(code string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Warehouse{}).Where("code = ? AND id <> ?", code, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *warehouseRepository) List() ([]model.Warehouse, error) {
+	var warehouses []model.Warehouse
+	err := r.db.Order("priority").Order("id").Find(&warehouses).Error
+	return warehouses, err
+}
+
+// 出荷できる倉庫
+func (r *warehouseRepository) ListActive() ([]model.Warehouse, error) {
+	var warehouses []model.Warehouse
+	err := r.db.Where("active = ?", true).Order("priority").Order("id").Find(&warehouses).Error
+	return warehouses, err
+}
+
+// 既定の倉庫の切り替えはSetDefaultで行うため更新しない
+func (r *warehouseRepository) Update(warehouse *model.Warehouse) error {
+	return r.db.Omit("is_default").Save(warehouse).Error
+}
+
+// 既定の倉庫を切り替える（既定の倉庫は常に1つ）
+func (r *warehouseRepository) SetDefault(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Warehouse{}).
+			Where("is_default = ? AND id <> ?", true, id).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&model.Warehouse{}).Where("id = ?", id).Update("is_default", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("warehouse not found")
+		}
+		return nil
+	})
+}
+
+// 商品（バリエーション）の倉庫ごとの在庫（出荷元を決めるまで他の注文の引き当てを待たせる）
+func (r *warehouseRepository) ListStocks(productID uint, variantID *uint) ([]model.WarehouseStock, error) {
+	var stocks []model.WarehouseStock
+	err := warehouseStockQuery(r.db, productID, variantID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Order("warehouse_id").
+		Find(&stocks).Error
+	return stocks, err
+}
+
+// 倉庫の在庫一覧（管理者用）
+func (r *warehouseRepository) ListStocksByWarehouse(warehouseID uint, page, pageSize int) ([]model.WarehouseStock, int64, error) {
+	var stocks []model.WarehouseStock
+	var total int64
+
+	offset := (page - 1) * pageSize
+
+	query := r.db.Model(&model.WarehouseStock{}).Where("warehouse_id = ?", warehouseID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("product_id").Order("variant_id NULLS FIRST").
+		Offset(offset).Limit(pageSize).
+		Find(&stocks).Error
+	return stocks, total, err
+}
+
+// 商品の倉庫ごとの在庫（管理者用）
+func (r *warehouseRepository) ListStocksByProduct(productID uint) ([]model.WarehouseStock, error) {
+	var stocks []model.WarehouseStock
+	err := r.db.Preload("Warehouse").
+		Where("product_id = ?", productID).
+		Order("warehouse_id").Order("variant_id NULLS FIRST").
+		Find(&stocks).Error
+	return stocks, err
+}
+
+// 倉庫の導入前の在庫・在庫予約・変動履歴を指定の倉庫のものとして移行（起動時に実行）
+func (r *warehouseRepository) MigrateLegacyStock(warehouseID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 倉庫ごとの在庫が無い商品・バリエーションの在庫（予約の引き当て・解放のため削除済みも含める）
+		if err := tx.Exec(`
+			INSERT INTO warehouse_stocks (warehouse_id, product_id, stock, reserved, updated_at)
+			SELECT ?, p.id, p.stock, p.reserved, NOW()
+			FROM products p
+			WHERE (p.stock <> 0 OR p.reserved <> 0)
+				AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.deleted_at IS NULL)
+				AND NOT EXISTS (SELECT 1 FROM warehouse_stocks s WHERE s.product_id = p.id AND s.variant_id IS NULL)
+		`, warehouseID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			INSERT INTO warehouse_stocks (warehouse_id, product_id, variant_id, stock, reserved, updated_at)
+			SELECT ?, v.product_id, v.id, v.stock, v.reserved, NOW()
+			FROM product_variants v
+			WHERE (v.stock <> 0 OR v.reserved <> 0)
+				AND NOT EXISTS (SELECT 1 FROM warehouse_stocks s WHERE s.variant_id = v.id)
+		`, warehouseID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.InventoryReservation{}).
+			Where("warehouse_id IS NULL").
+			Update("warehouse_id", warehouseID).Error; err != nil {
+			return err
+		}
+		// 倉庫の導入前の変動履歴は倉庫の項目が無いため、移行先の倉庫を記録する（数量などは変更しない）
+		return tx.Model(&model.StockMovement{}).
+			Where("warehouse_id IS NULL").
+			Update("warehouse_id", warehouseID).Error
+	})
+}
+
+// 既定の倉庫のID
+func defaultWarehouseID(tx *gorm.DB) (uint, error) {
+	var ids []uint
+	err := tx.Model(&model.Warehouse{}).Where("is_default = ?", true).Order("id").Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, errors.New("default warehouse not found")
+	}
+	return ids[0], nil
+}
+
+// 商品（バリエーション）の倉庫ごとの在庫
+func warehouseStockQuery(tx *gorm.DB, productID uint, variantID *uint) *gorm.DB {
+	query := tx.Model(&model.WarehouseStock{}).Where("product_id = ?", productID)
+	if variantID != nil {
+		return query.Where("variant_id = ?", *variantID)
+	}
+	return query.Where("variant_id IS NULL")
+}
+
+// 倉庫の在庫の行が無い場合は作成
+func ensureWarehouseStock(tx *gorm.DB, warehouseID, productID uint, variantID *uint) error {
+	conflict := clause.OnConflict{
+		Columns:     []clause.Column{{Name: "warehouse_id"}, {Name: "product_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "variant_id IS NULL"}}},
+		DoNothing:   true,
+	}
+	if variantID != nil {
+		conflict = clause.OnConflict{
+			Columns:     []clause.Column{{Name: "warehouse_id"}, {Name: "variant_id"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "variant_id IS NOT NULL"}}},
+			DoNothing:   true,
+		}
+	}
+	return tx.Clauses(conflict).Create(&model.WarehouseStock{
+		WarehouseID: warehouseID,
+		ProductID:   productID,
+		VariantID:   variantID,
+	}).Error
+}
\ No newline at end of file
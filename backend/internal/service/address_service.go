@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/jpaddress"
+)
+
+const maxAddressesPerUser = 20
+
+type AddressService interface {
+	ListAddresses(userID uint) ([]model.Address, error)
+	GetAddress(userID, id uint) (*model.Address, error)
+	CreateAddress(userID uint, address *model.Address) (*model.Address, error)
+	UpdateAddress(userID, id uint, input *model.Address) (*model.Address, error)
+	DeleteAddress(userID, id uint) error
+	SetDefaultAddress(userID, id uint) (*model.Address, error)
+}
+
+type addressService struct {
+	addressRepo repository.AddressRepository
+}
+
+func NewAddressService(addressRepo repository.AddressRepository) AddressService {
+	return &addressService{
+		addressRepo: addressRepo,
+	}
+}
+
+func (s *addressService) ListAddresses(userID uint) ([]model.Address, error) {
+	return s.addressRepo.ListByUserID(userID)
+}
+
+func (s *addressService) GetAddress(userID, id uint) (*model.Address, error) {
+	return s.addressRepo.GetByID(userID, id)
+}
+
+// 住所の登録（最初の住所、またはis_default指定時はデフォルトにする）
+func (s *addressService) CreateAddress(userID uint, address *model.Address) (*model.Address, error) {
+	if err := normalizeAddress(address); err != nil {
+		return nil, err
+	}
+
+	count, err := s.addressRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxAddressesPerUser {
+		return nil, fmt.Errorf("address limit reached (%d)", maxAddressesPerUser)
+	}
+
+	makeDefault := address.IsDefault || count == 0
+
+	address.ID = 0
+	address.UserID = userID
+	address.IsDefault = false
+	if err := s.addressRepo.Create(address); err != nil {
+		return nil, err
+	}
+
+	if makeDefault {
+		if err := s.addressRepo.SetDefault(userID, address.ID); err != nil {
+			return nil, err
+		}
+		address.IsDefault = true
+	}
+
+	return address, nil
+}
+
+// 住所の更新（過去の注文にはスナップショットが保存されているため影響しない）
+func (s *addressService) UpdateAddress(userID, id uint, input *model.Address) (*model.Address, error) {
+	address, err := s.addressRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := normalizeAddress(input); err != nil {
+		return nil, err
+	}
+
+	address.RecipientName = input.RecipientName
+	address.PostalCode = input.PostalCode
+	address.Prefecture = input.Prefecture
+	address.City = input.City
+	address.Line1 = input.Line1
+	address.Line2 = input.Line2
+	address.Phone = input.Phone
+
+	if err := s.addressRepo.Update(address); err != nil {
+		return nil, err
+	}
+
+	if input.IsDefault && !address.IsDefault {
+		return s.SetDefaultAddress(userID, id)
+	}
+
+	return address, nil
+}
+
+func (s *addressService) DeleteAddress(userID, id uint) error {
+	return s.addressRepo.Delete(userID, id)
+}
+
+func (s *addressService) SetDefaultAddress(userID, id uint) (*model.Address, error) {
+	if err := s.addressRepo.SetDefault(userID, id); err != nil {
+		return nil, err
+	}
+	return s.addressRepo.GetByID(userID, id)
+}
+
+// 入力値の検証と正規化（郵便番号は123-4567、電話番号はハイフン無し）
+func normalizeAddress(address *model.Address) error {
+	address.RecipientName = strings.TrimSpace(address.RecipientName)
+	address.Prefecture = strings.TrimSpace(address.Prefecture)
+	address.City = strings.TrimSpace(address.City)
+	address.Line1 = strings.TrimSpace(address.Line1)
+	address.Line2 = strings.TrimSpace(address.Line2)
+
+	if address.RecipientName == "" {
+		return errors.New("recipient name is required")
+	}
+	if address.City == "" || address.Line1 == "" {
+		return errors.New("city and line1 are required")
+	}
+
+	postalCode, err := jpaddress.NormalizePostalCode(address.PostalCode)
+	if err != nil {
+		return err
+	}
+	address.PostalCode = postalCode
+
+	if !jpaddress.IsPrefecture(address.Prefecture) {
+		return errors.New("invalid prefecture")
+	}
+
+	phone, err := jpaddress.NormalizePhone(address.Phone)
+	if err != nil {
+		return err
+	}
+	address.Phone = phone
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+const (
+	apiKeyPrefix       = "sk_"
+	apiKeyPrefixLength = 11 // "sk_"+8文字
+	maxAPIKeysPerUser  = 20
+	// 最終使用日時の更新間隔（リクエスト毎の書き込みを避ける）
+	apiKeyTouchInterval = time.Minute
+)
+
+type APIKeyService interface {
+	CreateKey(userID uint, name string, scopes []string, expiresAt *time.Time, mfaVerified bool) (*model.APIKey, string, error)
+	ListKeys(userID uint) ([]model.APIKey, error)
+	UpdateKey(userID, id uint, name string, scopes []string) (*model.APIKey, error)
+	DeleteKey(userID, id uint) error
+	Authenticate(rawKey string) (*model.APIKey, []string, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	roleRepo   repository.RoleRepository
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, roleRepo repository.RoleRepository) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		roleRepo:   roleRepo,
+	}
+}
+
+// APIキーの発行（平文のキーは発行時の一度だけ返す）
+func (s *apiKeyService) CreateKey(userID uint, name string, scopes []string, expiresAt *time.Time, mfaVerified bool) (*model.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", errors.New("api key name is required")
+	}
+	if err := validateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", errors.New("expires_at must be in the future")
+	}
+
+	count, err := s.apiKeyRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, "", fmt.Errorf("api key limit reached (%d)", maxAPIKeysPerUser)
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	rawKey := apiKeyPrefix + secret
+
+	key := &model.APIKey{
+		UserID:      userID,
+		Name:        strings.TrimSpace(name),
+		Prefix:      rawKey[:apiKeyPrefixLength],
+		KeyHash:     hashToken(rawKey),
+		Scopes:      uniqueStrings(scopes),
+		MFAVerified: mfaVerified,
+		ExpiresAt:   expiresAt,
+	}
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+func (s *apiKeyService) ListKeys(userID uint) ([]model.APIKey, error) {
+	return s.apiKeyRepo.ListByUserID(userID)
+}
+
+// 名前とスコープの変更（キー自体と有効期限は変更不可）
+func (s *apiKeyService) UpdateKey(userID, id uint, name string, scopes []string) (*model.APIKey, error) {
+	key, err := s.apiKeyRepo.GetByID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(name) != "" {
+		key.Name = strings.TrimSpace(name)
+	}
+	if scopes != nil {
+		if err := validateScopes(scopes); err != nil {
+			return nil, err
+		}
+		key.Scopes = uniqueStrings(scopes)
+	}
+
+	if err := s.apiKeyRepo.Update(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (s *apiKeyService) DeleteKey(userID, id uint) error {
+	deleted, err := s.apiKeyRepo.Delete(userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+// APIキーの検証（所有ユーザーの現在の権限も返す）
+func (s *apiKeyService) Authenticate(rawKey string) (*model.APIKey, []string, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	key, err := s.apiKeyRepo.GetByHash(hashToken(rawKey))
+	if err != nil {
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	// 削除済みユーザーのキーは使用不可
+	if key.User == nil {
+		return nil, nil, errors.New("invalid api key")
+	}
+	if key.IsExpired() {
+		return nil, nil, errors.New("api key expired")
+	}
+
+	permissions, err := s.roleRepo.GetPermissionNamesByUserID(key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchLastUsed(key.ID, now); err != nil {
+			log.Println("Warning: failed to update api key last used time:", err)
+		}
+		key.LastUsedAt = &now
+	}
+
+	return key, permissions, nil
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		valid := false
+		for _, known := range model.APIKeyScopes {
+			if scope == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
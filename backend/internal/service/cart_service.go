@@ -1,29 +1,60 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/Naonao3/EC-site/backend/internal/model"
 	"github.com/Naonao3/EC-site/backend/internal/repository"
 )
 
+// ゲストカートに入れられる商品（バリエーション・ライセンス）の種類の上限
+const maxGuestCartItems = 100
+
 type CartService interface {
 	GetCart(userID uint) (*model.Cart, error)
-	AddToCart(userID, productID uint, quantity int) (*model.CartItem, error)
+	AddToCart(userID, productID uint, variantID *uint, licenseType string, quantity int) (*model.CartItem, error)
 	UpdateCartItem(userID, cartItemID uint, quantity int) (*model.CartItem, error)
 	RemoveFromCart(userID, cartItemID uint) error
 	ClearCart(userID uint) error
+	GetGuestCart(token string) (*model.Cart, error)
+	AddToGuestCart(token string, productID uint, variantID *uint, licenseType string, quantity int) (string, *model.CartItem, error)
+	UpdateGuestCartItem(token string, itemID uint, quantity int) (*model.CartItem, error)
+	RemoveFromGuestCart(token string, itemID uint) error
+	ClearGuestCart(token string) error
+	MergeGuestCart(userID uint, token string) (*model.CartMergeResult, error)
+	ApplyCoupon(userID uint, code string) (*model.Cart, error)
+	RemoveCoupon(userID uint) error
+	ApplyGuestCoupon(token, code string) (*model.Cart, error)
+	RemoveGuestCoupon(token string) error
 }
 
 type cartService struct {
-	cartRepo    repository.CartRepository
-	productRepo repository.ProductRepository
+	cartRepo        repository.CartRepository
+	productRepo     repository.ProductRepository
+	guestCarts      GuestCartStore
+	guestCartSecret []byte
+	couponService   CouponService
 }
 
-func NewCartService(cartRepo repository.CartRepository, productRepo repository.ProductRepository) CartService {
+func NewCartService(
+	cartRepo repository.CartRepository,
+	productRepo repository.ProductRepository,
+	guestCarts GuestCartStore,
+	guestCartSecret string,
+	couponService CouponService,
+) CartService {
 	return &cartService{
-		cartRepo:    cartRepo,
-		productRepo: productRepo,
+		cartRepo:        cartRepo,
+		productRepo:     productRepo,
+		guestCarts:      guestCarts,
+		guestCartSecret: []byte(guestCartSecret),
+		couponService:   couponService,
 	}
 }
 
@@ -34,41 +65,32 @@ func (s *cartService) GetCart(userID uint) (*model.Cart, error) {
 		return nil, err
 	}
 
-	// カート全体の情報を計算
-	cart := &model.Cart{
-		Items:      items,
-		TotalItems: 0,
-		TotalPrice: 0,
-	}
-
-	for _, item := range items {
-		cart.TotalItems += item.Quantity
-		cart.TotalPrice += item.Product.Price * float64(item.Quantity)
+	coupon, err := s.couponService.CartCoupon(userID)
+	if err != nil {
+		return nil, err
 	}
-
-	return cart, nil
+	return s.priceCart(items, coupon, &userID), nil
 }
 
-// カートに追加
-func (s *cartService) AddToCart(userID, productID uint, quantity int) (*model.CartItem, error) {
+// カートに追加（バリエーションのある商品はvariantID、ライセンスのある商品はlicenseTypeが必須）
+func (s *cartService) AddToCart(userID, productID uint, variantID *uint, licenseType string, quantity int) (*model.CartItem, error) {
 	// バリデーション
 	if quantity <= 0 {
 		return nil, errors.New("quantity must be greater than 0")
 	}
 
-	// 商品の存在確認
-	product, err := s.productRepo.GetByID(productID)
+	stock, licenseID, err := s.resolveNewItem(productID, variantID, licenseType)
 	if err != nil {
 		return nil, err
 	}
 
 	// 在庫確認
-	if product.Stock < quantity {
+	if stock < quantity {
 		return nil, errors.New("insufficient stock")
 	}
 
-	// 既に同じ商品がカートにあるか確認
-	existingItem, err := s.cartRepo.GetByUserAndProduct(userID, productID)
+	// 既に同じ商品（バリエーション・ライセンス）がカートにあるか確認
+	existingItem, err := s.cartRepo.GetByUserAndProduct(userID, productID, variantID, licenseID)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +101,7 @@ func (s *cartService) AddToCart(userID, productID uint, quantity int) (*model.Ca
 		newQuantity := existingItem.Quantity + quantity
 
 		// 在庫確認
-		if product.Stock < newQuantity {
+		if stock < newQuantity {
 			return nil, errors.New("insufficient stock")
 		}
 
@@ -93,6 +115,8 @@ func (s *cartService) AddToCart(userID, productID uint, quantity int) (*model.Ca
 		cartItem := &model.CartItem{
 			UserID:    userID,
 			ProductID: productID,
+			VariantID: variantID,
+			LicenseID: licenseID,
 			Quantity:  quantity,
 		}
 
@@ -142,7 +166,12 @@ func (s *cartService) UpdateCartItem(userID, cartItemID uint, quantity int) (*mo
 		return nil, err
 	}
 
-	if product.Stock < quantity {
+	variant, err := resolveVariant(product, cartItem.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if availableStock(product, variant) < quantity {
 		return nil, errors.New("insufficient stock")
 	}
 
@@ -184,7 +213,606 @@ func (s *cartService) RemoveFromCart(userID, cartItemID uint) error {
 	return s.cartRepo.Delete(cartItemID)
 }
 
-// カートをクリア
+// カートをクリア（適用中のクーポンも外す）
 func (s *cartService) ClearCart(userID uint) error {
-	return s.cartRepo.DeleteByUserID(userID)
+	if err := s.cartRepo.DeleteByUserID(userID); err != nil {
+		return err
+	}
+	return s.couponService.RemoveCartCoupon(userID)
+}
+
+// カートにクーポンを適用（今のカートの内容で使えるクーポンのみ。既に適用中のクーポンは置き換える）
+func (s *cartService) ApplyCoupon(userID uint, code string) (*model.Cart, error) {
+	coupon, err := s.couponService.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.cartRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.couponService.Price(cartDiscountLines(items), coupon, &userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.couponService.SetCartCoupon(userID, coupon.ID); err != nil {
+		return nil, err
+	}
+	return s.priceCart(items, coupon, &userID), nil
+}
+
+// カートからクーポンを外す
+func (s *cartService) RemoveCoupon(userID uint) error {
+	return s.couponService.RemoveCartCoupon(userID)
+}
+
+// ゲストカート取得（トークンが無い場合は空のカート）
+func (s *cartService) GetGuestCart(token string) (*model.Cart, error) {
+	if token == "" {
+		return s.priceCart(nil, nil, nil), nil
+	}
+	cartID, err := s.parseGuestCartToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	guest, err := s.guestCarts.Get(cartID)
+	if err != nil {
+		return nil, err
+	}
+	return s.priceGuestCart(guest), nil
+}
+
+// ゲストカートに追加（トークンが無い場合は新しいゲストカートを作成し、そのトークンを返す）
+func (s *cartService) AddToGuestCart(token string, productID uint, variantID *uint, licenseType string, quantity int) (string, *model.CartItem, error) {
+	if quantity <= 0 {
+		return "", nil, errors.New("quantity must be greater than 0")
+	}
+
+	var cartID string
+	var err error
+	if token == "" {
+		cartID, err = randomToken(24)
+		if err != nil {
+			return "", nil, err
+		}
+		token = s.signGuestCartToken(cartID)
+	} else if cartID, err = s.parseGuestCartToken(token); err != nil {
+		return "", nil, err
+	}
+
+	stock, licenseID, err := s.resolveNewItem(productID, variantID, licenseType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var itemID uint
+	guest, err := s.guestCarts.Update(cartID, func(cart *model.GuestCart) error {
+		// 既にある場合は数量を合算して在庫を確認
+		if item := cart.FindItem(productID, variantID, licenseID); item != nil {
+			if stock < item.Quantity+quantity {
+				return errors.New("insufficient stock")
+			}
+			item.Quantity += quantity
+			itemID = item.ID
+			return nil
+		}
+
+		if stock < quantity {
+			return errors.New("insufficient stock")
+		}
+		if len(cart.Items) >= maxGuestCartItems {
+			return errors.New("guest cart is full")
+		}
+		cart.NextItemID++
+		cart.Items = append(cart.Items, model.GuestCartItem{
+			ID:        cart.NextItemID,
+			ProductID: productID,
+			VariantID: variantID,
+			LicenseID: licenseID,
+			Quantity:  quantity,
+			CreatedAt: time.Now(),
+		})
+		itemID = cart.NextItemID
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	item, err := s.findGuestCartItem(guest, itemID)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, item, nil
+}
+
+// ゲストカートのアイテム更新
+func (s *cartService) UpdateGuestCartItem(token string, itemID uint, quantity int) (*model.CartItem, error) {
+	if quantity <= 0 {
+		return nil, errors.New("quantity must be greater than 0")
+	}
+	cartID, err := s.parseGuestCartToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.guestCarts.Get(cartID)
+	if err != nil {
+		return nil, err
+	}
+	existing := current.FindItemByID(itemID)
+	if existing == nil {
+		return nil, errors.New("cart item not found")
+	}
+
+	// 商品の在庫確認
+	product, err := s.productRepo.GetByID(existing.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := resolveVariant(product, existing.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	if availableStock(product, variant) < quantity {
+		return nil, errors.New("insufficient stock")
+	}
+
+	guest, err := s.guestCarts.Update(cartID, func(cart *model.GuestCart) error {
+		item := cart.FindItemByID(itemID)
+		if item == nil {
+			return errors.New("cart item not found")
+		}
+		item.Quantity = quantity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.findGuestCartItem(guest, itemID)
+}
+
+// ゲストカートから削除
+func (s *cartService) RemoveFromGuestCart(token string, itemID uint) error {
+	cartID, err := s.parseGuestCartToken(token)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.guestCarts.Update(cartID, func(cart *model.GuestCart) error {
+		for i, item := range cart.Items {
+			if item.ID == itemID {
+				cart.Items = append(cart.Items[:i], cart.Items[i+1:]...)
+				return nil
+			}
+		}
+		return errors.New("cart item not found")
+	})
+	return err
+}
+
+// ゲストカートをクリア
+func (s *cartService) ClearGuestCart(token string) error {
+	cartID, err := s.parseGuestCartToken(token)
+	if err != nil {
+		return err
+	}
+	return s.guestCarts.Delete(cartID)
+}
+
+// ゲストカートにクーポンを適用（1ユーザーあたりの利用回数はログイン後の注文時に確認する）
+func (s *cartService) ApplyGuestCoupon(token, code string) (*model.Cart, error) {
+	if token == "" {
+		return nil, errors.New("cart is empty")
+	}
+	cartID, err := s.parseGuestCartToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	coupon, err := s.couponService.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.guestCarts.Get(cartID)
+	if err != nil {
+		return nil, err
+	}
+	items := s.guestCartItems(current)
+	if _, err := s.couponService.Price(cartDiscountLines(items), coupon, nil); err != nil {
+		return nil, err
+	}
+
+	guest, err := s.guestCarts.Update(cartID, func(cart *model.GuestCart) error {
+		if len(cart.Items) == 0 {
+			return errors.New("cart is empty")
+		}
+		cart.CouponCode = coupon.Code
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.priceCart(s.guestCartItems(guest), coupon, nil), nil
+}
+
+// ゲストカートからクーポンを外す
+func (s *cartService) RemoveGuestCoupon(token string) error {
+	cartID, err := s.parseGuestCartToken(token)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.guestCarts.Update(cartID, func(cart *model.GuestCart) error {
+		cart.CouponCode = ""
+		return nil
+	})
+	return err
+}
+
+// ゲストカートをユーザーのカートに統合し、ゲストカートを削除
+// 同じ商品がある場合は数量を合算し、購入できる在庫を超える分・購入できなくなった商品は統合しない
+// ゲストカートに適用中のクーポンは、ユーザーのカートにクーポンが無い場合のみ引き継ぐ
+func (s *cartService) MergeGuestCart(userID uint, token string) (*model.CartMergeResult, error) {
+	cartID, err := s.parseGuestCartToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同じゲストカートを同時に統合しても二重に追加されないよう、取得と同時に削除
+	// 統合に失敗した場合は、まだ統合していないアイテムとクーポンをゲストカートに戻す
+	guest, err := s.guestCarts.Take(cartID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.CartMergeResult{Items: []model.CartMergeItem{}}
+	for i, item := range guest.Items {
+		merged, reason, err := s.mergeGuestCartItem(userID, item)
+		if err != nil {
+			s.restoreGuestCart(cartID, guest, guest.Items[i:])
+			return nil, err
+		}
+		result.Items = append(result.Items, model.CartMergeItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			LicenseID: item.LicenseID,
+			Requested: item.Quantity,
+			Merged:    merged,
+			Reason:    reason,
+		})
+	}
+
+	if guest.CouponCode != "" {
+		current, err := s.couponService.CartCoupon(userID)
+		if err != nil {
+			s.restoreGuestCart(cartID, guest, nil)
+			return nil, err
+		}
+		if current == nil {
+			// 削除されたクーポンは引き継がない
+			if coupon, err := s.couponService.GetByCode(guest.CouponCode); err == nil {
+				if err := s.couponService.SetCartCoupon(userID, coupon.ID); err != nil {
+					s.restoreGuestCart(cartID, guest, nil)
+					return nil, err
+				}
+				result.CouponCode = coupon.Code
+			}
+		}
+	}
+	return result, nil
+}
+
+// 統合できなかったアイテムとクーポンをゲストカートに戻す
+// 取り出した後に同じゲストカートへ追加されたアイテムがあれば、数量を合算する
+func (s *cartService) restoreGuestCart(cartID string, guest *model.GuestCart, items []model.GuestCartItem) {
+	_, err := s.guestCarts.Update(cartID, func(cart *model.GuestCart) error {
+		if cart.NextItemID < guest.NextItemID {
+			cart.NextItemID = guest.NextItemID
+		}
+		for _, item := range items {
+			if existing := cart.FindItem(item.ProductID, item.VariantID, item.LicenseID); existing != nil {
+				existing.Quantity += item.Quantity
+				continue
+			}
+			if cart.FindItemByID(item.ID) != nil {
+				cart.NextItemID++
+				item.ID = cart.NextItemID
+			}
+			cart.Items = append(cart.Items, item)
+		}
+		if cart.CouponCode == "" {
+			cart.CouponCode = guest.CouponCode
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to restore guest cart after a failed merge: %v", err)
+	}
+}
+
+// ゲストカートのアイテムをユーザーのカートに追加し、追加した数量と追加できなかった理由を返す
+func (s *cartService) mergeGuestCartItem(userID uint, item model.GuestCartItem) (int, string, error) {
+	product, err := s.productRepo.GetByID(item.ProductID)
+	if err != nil {
+		return 0, err.Error(), nil
+	}
+	variant, err := resolveVariant(product, item.VariantID)
+	if err != nil {
+		return 0, err.Error(), nil
+	}
+	license, err := resolveLicense(product, item.LicenseID)
+	if err != nil {
+		return 0, err.Error(), nil
+	}
+	if err := model.ValidateSelection(variant, license); err != nil {
+		return 0, err.Error(), nil
+	}
+
+	existing, err := s.cartRepo.GetByUserAndProduct(userID, item.ProductID, item.VariantID, item.LicenseID)
+	if err != nil {
+		return 0, "", err
+	}
+	current := 0
+	if existing != nil {
+		current = existing.Quantity
+	}
+
+	quantity := item.Quantity
+	reason := ""
+	if stock := availableStock(product, variant); current+quantity > stock {
+		quantity = stock - current
+		reason = "insufficient stock"
+	}
+	if quantity <= 0 {
+		return 0, reason, nil
+	}
+
+	if existing != nil {
+		existing.Quantity += quantity
+		if err := s.cartRepo.Update(existing); err != nil {
+			return 0, "", err
+		}
+		return quantity, reason, nil
+	}
+
+	err = s.cartRepo.Create(&model.CartItem{
+		UserID:    userID,
+		ProductID: item.ProductID,
+		VariantID: item.VariantID,
+		LicenseID: item.LicenseID,
+		Quantity:  quantity,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	return quantity, reason, nil
+}
+
+// カートに追加する商品の購入できる在庫数とライセンスを確認
+func (s *cartService) resolveNewItem(productID uint, variantID *uint, licenseType string) (int, *uint, error) {
+	// 商品の存在確認
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	variant, err := resolveVariant(product, variantID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	license, err := selectLicense(product, licenseType)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := model.ValidateSelection(variant, license); err != nil {
+		return 0, nil, err
+	}
+	var licenseID *uint
+	if license != nil {
+		licenseID = &license.ID
+	}
+
+	return availableStock(product, variant), licenseID, nil
+}
+
+// ゲストカートのアイテムに商品情報を付けたもの（購入できなくなった商品は除く）
+func (s *cartService) guestCartItems(guest *model.GuestCart) []model.CartItem {
+	products := make(map[uint]*model.Product)
+	items := make([]model.CartItem, 0, len(guest.Items))
+	for _, item := range guest.Items {
+		product, ok := products[item.ProductID]
+		if !ok {
+			// 削除された商品は表示しない（ユーザーのカートへの統合時にも除かれる）
+			product, _ = s.productRepo.GetByID(item.ProductID)
+			products[item.ProductID] = product
+		}
+		if product == nil {
+			continue
+		}
+
+		variant, err := resolveVariant(product, item.VariantID)
+		if err != nil {
+			continue
+		}
+		license, err := resolveLicense(product, item.LicenseID)
+		if err != nil {
+			continue
+		}
+		if model.ValidateSelection(variant, license) != nil {
+			continue
+		}
+
+		items = append(items, model.CartItem{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			LicenseID: item.LicenseID,
+			Quantity:  item.Quantity,
+			CreatedAt: item.CreatedAt,
+			Product:   *product,
+			Variant:   variant,
+			License:   license,
+		})
+	}
+	return items
+}
+
+func (s *cartService) findGuestCartItem(guest *model.GuestCart, itemID uint) (*model.CartItem, error) {
+	for _, item := range s.guestCartItems(guest) {
+		if item.ID == itemID {
+			return &item, nil
+		}
+	}
+	return nil, errors.New("failed to retrieve cart item")
+}
+
+// ゲストカートのトークン（カートIDとHMAC-SHA256署名）
+func (s *cartService) signGuestCartToken(cartID string) string {
+	mac := hmac.New(sha256.New, s.guestCartSecret)
+	mac.Write([]byte("guest_cart:" + cartID))
+	return cartID + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// トークンの署名を検証してカートIDを返す
+func (s *cartService) parseGuestCartToken(token string) (string, error) {
+	cartID, _, ok := strings.Cut(token, ".")
+	if !ok || cartID == "" || !hmac.Equal([]byte(token), []byte(s.signGuestCartToken(cartID))) {
+		return "", errors.New("invalid cart token")
+	}
+	return cartID, nil
+}
+
+// ゲストカートの金額を計算（適用中のクーポンが削除された場合はその理由を付ける）
+func (s *cartService) priceGuestCart(guest *model.GuestCart) *model.Cart {
+	items := s.guestCartItems(guest)
+	if guest.CouponCode == "" {
+		return s.priceCart(items, nil, nil)
+	}
+
+	coupon, err := s.couponService.GetByCode(guest.CouponCode)
+	if err != nil {
+		cart := s.priceCart(items, nil, nil)
+		cart.CouponCode = guest.CouponCode
+		cart.CouponError = err.Error()
+		return cart
+	}
+	return s.priceCart(items, coupon, nil)
+}
+
+// カートの合計と送料・値引きを計算（クーポンが使えない場合は値引きせず、その理由を付ける）
+func (s *cartService) priceCart(items []model.CartItem, coupon *model.Coupon, userID *uint) *model.Cart {
+	cart := newCart(items)
+	lines := cartDiscountLines(cart.Items)
+
+	if coupon != nil {
+		cart.CouponCode = coupon.Code
+		pricing, err := s.couponService.Price(lines, coupon, userID)
+		if err == nil {
+			cart.CartPricing = *pricing
+			return cart
+		}
+		cart.CouponError = err.Error()
+	}
+
+	// クーポンが無い場合は失敗しない
+	pricing, _ := s.couponService.Price(lines, nil, nil)
+	cart.CartPricing = *pricing
+	return cart
+}
+
+// カートの合計を計算
+func newCart(items []model.CartItem) *model.Cart {
+	if items == nil {
+		items = []model.CartItem{}
+	}
+	cart := &model.Cart{
+		Items:      items,
+		TotalItems: 0,
+		TotalPrice: 0,
+	}
+
+	for _, item := range items {
+		// 価格を決められない明細は合計に含めない（注文時にエラーになる）
+		price, err := item.UnitPrice()
+		if err != nil {
+			continue
+		}
+		cart.TotalItems += item.Quantity
+		cart.TotalPrice += price * float64(item.Quantity)
+	}
+	return cart
+}
+
+// カートアイテムのバリエーションを特定（バリエーションの無い商品の場合はnil）
+func resolveVariant(product *model.Product, variantID *uint) (*model.ProductVariant, error) {
+	if !product.HasVariants() {
+		if variantID != nil {
+			return nil, errors.New("product has no variants")
+		}
+		return nil, nil
+	}
+
+	if variantID == nil {
+		return nil, errors.New("variant_id is required for this product")
+	}
+
+	variant := product.FindVariant(*variantID)
+	if variant == nil {
+		return nil, errors.New("variant not found")
+	}
+	return variant, nil
+}
+
+// カートに追加するライセンスを種類から選択（ライセンスの無い商品の場合はnil）
+func selectLicense(product *model.Product, licenseType string) (*model.ProductLicense, error) {
+	if !product.HasLicenses() {
+		if licenseType != "" {
+			return nil, errors.New("product has no licenses")
+		}
+		return nil, nil
+	}
+
+	if licenseType == "" {
+		return nil, errors.New("license_type is required for this product")
+	}
+
+	license := product.FindLicenseByType(licenseType)
+	if license == nil {
+		return nil, errors.New("license is not available for this product")
+	}
+	return license, nil
+}
+
+// カートアイテムのライセンスを特定（ライセンスの無い商品の場合はnil）
+func resolveLicense(product *model.Product, licenseID *uint) (*model.ProductLicense, error) {
+	if !product.HasLicenses() {
+		if licenseID != nil {
+			return nil, errors.New("license is no longer available for product: " + product.Name)
+		}
+		return nil, nil
+	}
+
+	if licenseID == nil {
+		return nil, errors.New("a license must be selected for product: " + product.Name)
+	}
+
+	license := product.FindLicense(*licenseID)
+	if license == nil {
+		return nil, errors.New("license is no longer available for product: " + product.Name)
+	}
+	return license, nil
+}
+
+// 購入できる在庫数（バリエーションがある場合はバリエーションの在庫。未決済の注文で確保されている分を除く）
+func availableStock(product *model.Product, variant *model.ProductVariant) int {
+	if variant != nil {
+		return variant.Available
+	}
+	return product.Available
 }
\ No newline at end of file
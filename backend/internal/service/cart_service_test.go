@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+type fakeProductRepository struct {
+	repository.ProductRepository
+	products map[uint]*model.Product
+}
+
+func (r *fakeProductRepository) GetByID(id uint) (*model.Product, error) {
+	product, ok := r.products[id]
+	if !ok {
+		return nil, errors.New("product not found")
+	}
+	copied := *product
+	return &copied, nil
+}
+
+type fakeCartRepository struct {
+	repository.CartRepository
+	items []model.CartItem
+	// 追加に失敗させる商品
+	failProductID uint
+}
+
+func (r *fakeCartRepository) GetByUserAndProduct(userID, productID uint, variantID, licenseID *uint) (*model.CartItem, error) {
+	for _, item := range r.items {
+		if item.UserID == userID && item.ProductID == productID {
+			copied := item
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeCartRepository) Create(cartItem *model.CartItem) error {
+	if cartItem.ProductID == r.failProductID {
+		return errors.New("database is unavailable")
+	}
+	cartItem.ID = uint(len(r.items) + 1)
+	r.items = append(r.items, *cartItem)
+	return nil
+}
+
+func (r *fakeCartRepository) quantity(userID, productID uint) int {
+	item, _ := r.GetByUserAndProduct(userID, productID, nil, nil)
+	if item == nil {
+		return 0
+	}
+	return item.Quantity
+}
+
+// 統合に失敗してもゲストカートは失われず、再度の統合で二重に追加されない
+func TestMergeGuestCartRestoresOnFailure(t *testing.T) {
+	const userID = 1
+	products := &fakeProductRepository{products: map[uint]*model.Product{
+		1: {ID: 1, Name: "T-shirt", Price: 1000, Stock: 10, Available: 10},
+		2: {ID: 2, Name: "Mug", Price: 800, Stock: 10, Available: 10},
+	}}
+	carts := &fakeCartRepository{failProductID: 2}
+	guestCarts := NewGuestCartStore(nil, time.Hour)
+	s := NewCartService(carts, products, guestCarts, "secret", nil).(*cartService)
+
+	token, _, err := s.AddToGuestCart("", 1, nil, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.AddToGuestCart(token, 2, nil, "", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.MergeGuestCart(userID, token); err == nil {
+		t.Fatal("MergeGuestCart() error = nil, want error")
+	}
+	cartID, err := s.parseGuestCartToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guest, err := guestCarts.Get(cartID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 統合済みのアイテムは戻さず、統合できなかったアイテムだけが残る
+	if len(guest.Items) != 1 || guest.Items[0].ProductID != 2 || guest.Items[0].Quantity != 3 {
+		t.Fatalf("guest cart after failed merge = %+v, want only product 2 x3", guest.Items)
+	}
+
+	carts.failProductID = 0
+	if _, err := s.MergeGuestCart(userID, token); err != nil {
+		t.Fatalf("MergeGuestCart() error = %v", err)
+	}
+	if got := carts.quantity(userID, 1); got != 2 {
+		t.Errorf("product 1 quantity = %d, want 2", got)
+	}
+	if got := carts.quantity(userID, 2); got != 3 {
+		t.Errorf("product 2 quantity = %d, want 3", got)
+	}
+	if guest, err := guestCarts.Get(cartID); err != nil || len(guest.Items) != 0 {
+		t.Errorf("guest cart after merge = %+v, %v, want empty", guest, err)
+	}
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+type CategoryService interface {
+	MigrateLegacyCategories() error
+	GetTree() ([]*model.Category, error)
+	GetCategory(id uint) (*model.Category, error)
+	DescendantIDs(id uint) ([]uint, error)
+	CreateCategory(name, slug string, parentID *uint, sortOrder int) (*model.Category, error)
+	UpdateCategory(id uint, name, slug string, parentID *uint, sortOrder int) (*model.Category, error)
+	DeleteCategory(id uint) error
+}
+
+type categoryService struct {
+	categoryRepo repository.CategoryRepository
+}
+
+func NewCategoryService(categoryRepo repository.CategoryRepository) CategoryService {
+	return &categoryService{
+		categoryRepo: categoryRepo,
+	}
+}
+
+// 文字列で保存されている商品のカテゴリをカテゴリテーブルの行に移行する（起動時に毎回実行・冪等）
+func (s *categoryService) MigrateLegacyCategories() error {
+	names, err := s.categoryRepo.ListLegacyCategoryNames()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		category, err := s.categoryRepo.GetByName(name)
+		if err != nil {
+			return err
+		}
+		if category == nil {
+			// 旧データではスラッグがカテゴリ名として使われている場合もある
+			category, err = s.categoryRepo.GetBySlug(name)
+			if err != nil {
+				return err
+			}
+		}
+		if category == nil {
+			slug, err := s.uniqueSlug(slugify(name), 0)
+			if err != nil {
+				return err
+			}
+			category = &model.Category{Name: name, Slug: slug}
+			if err := s.categoryRepo.Create(category); err != nil {
+				return err
+			}
+		}
+
+		if err := s.categoryRepo.AssignLegacyCategory(name, category.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *categoryService) GetTree() ([]*model.Category, error) {
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	return model.BuildCategoryTree(categories), nil
+}
+
+// 子カテゴリを含めて取得
+func (s *categoryService) GetCategory(id uint) (*model.Category, error) {
+	if _, err := s.categoryRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	tree, err := s.GetTree()
+	if err != nil {
+		return nil, err
+	}
+	if node := findCategory(tree, id); node != nil {
+		return node, nil
+	}
+	return nil, errors.New("category not found")
+}
+
+func (s *categoryService) DescendantIDs(id uint) ([]uint, error) {
+	return s.categoryRepo.DescendantIDs(id)
+}
+
+func (s *categoryService) CreateCategory(name, slug string, parentID *uint, sortOrder int) (*model.Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("category name is required")
+	}
+
+	if parentID != nil {
+		if _, err := s.categoryRepo.GetByID(*parentID); err != nil {
+			return nil, errors.New("parent category not found")
+		}
+	}
+
+	slug, err := s.validateSlug(slug, name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	category := &model.Category{
+		Name:      name,
+		Slug:      slug,
+		ParentID:  parentID,
+		SortOrder: sortOrder,
+	}
+	if err := s.categoryRepo.Create(category); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (s *categoryService) UpdateCategory(id uint, name, slug string, parentID *uint, sortOrder int) (*model.Category, error) {
+	category, err := s.categoryRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("category name is required")
+	}
+
+	// 自分自身や子孫カテゴリを親にすると循環するため拒否
+	if parentID != nil {
+		if _, err := s.categoryRepo.GetByID(*parentID); err != nil {
+			return nil, errors.New("parent category not found")
+		}
+		descendants, err := s.categoryRepo.DescendantIDs(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, descendantID := range descendants {
+			if descendantID == *parentID {
+				return nil, errors.New("category cannot be moved under itself or its descendants")
+			}
+		}
+	}
+
+	if slug == "" {
+		slug = category.Slug
+	}
+	slug, err = s.validateSlug(slug, name, id)
+	if err != nil {
+		return nil, err
+	}
+
+	category.Name = name
+	category.Slug = slug
+	category.ParentID = parentID
+	category.SortOrder = sortOrder
+	if err := s.categoryRepo.Update(category); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// 子カテゴリや商品が残っているカテゴリは削除できない
+func (s *categoryService) DeleteCategory(id uint) error {
+	if _, err := s.categoryRepo.GetByID(id); err != nil {
+		return err
+	}
+
+	children, err := s.categoryRepo.CountChildren(id)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return errors.New("category has child categories")
+	}
+
+	products, err := s.categoryRepo.CountProducts(id)
+	if err != nil {
+		return err
+	}
+	if products > 0 {
+		return errors.New("category has products")
+	}
+
+	return s.categoryRepo.Delete(id)
+}
+
+// スラッグの検証（未指定の場合はカテゴリ名から生成）
+func (s *categoryService) validateSlug(slug, name string, excludeID uint) (string, error) {
+	if slug == "" {
+		return s.uniqueSlug(slugify(name), excludeID)
+	}
+
+	if slugify(slug) != slug {
+		return "", errors.New("slug may only contain lowercase letters, digits and hyphens")
+	}
+
+	existing, err := s.categoryRepo.GetBySlug(slug)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.ID != excludeID {
+		return "", errors.New("slug already exists")
+	}
+	return slug, nil
+}
+
+// 重複しないスラッグ（重複時は連番を付与）
+func (s *categoryService) uniqueSlug(base string, excludeID uint) (string, error) {
+	slug := base
+	for i := 2; ; i++ {
+		existing, err := s.categoryRepo.GetBySlug(slug)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil || existing.ID == excludeID {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// カテゴリ名からスラッグを生成（日本語などの文字はそのまま残す）
+func slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		if !hyphen && b.Len() > 0 {
+			b.WriteRune('-')
+			hyphen = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "category"
+	}
+	return slug
+}
+
+func findCategory(nodes []*model.Category, id uint) *model.Category {
+	for _, node := range nodes {
+		if node.ID == id {
+			return node
+		}
+		if found := findCategory(node.Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+type CouponService interface {
+	CreateCoupon(coupon *model.Coupon) error
+	UpdateCoupon(coupon *model.Coupon) error
+	DeleteCoupon(id uint) error
+	GetCoupon(id uint) (*model.Coupon, error)
+	ListCoupons(page, pageSize int) ([]model.Coupon, int64, error)
+	GetByCode(code string) (*model.Coupon, error)
+	CartCoupon(userID uint) (*model.Coupon, error)
+	CartCouponForUpdate(userID uint) (*model.Coupon, error)
+	SetCartCoupon(userID, couponID uint) error
+	RemoveCartCoupon(userID uint) error
+	Price(lines []model.DiscountLine, coupon *model.Coupon, userID *uint) (*model.CartPricing, error)
+	Redeem(coupon *model.Coupon, userID, orderID uint, amount float64) error
+	ReleaseOrder(orderID uint) error
+	WithTx(tx *gorm.DB) CouponService
+}
+
+type couponService struct {
+	couponRepo   repository.CouponRepository
+	productRepo  repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+	shippingFee  float64
+}
+
+func NewCouponService(
+	couponRepo repository.CouponRepository,
+	productRepo repository.ProductRepository,
+	categoryRepo repository.CategoryRepository,
+	shippingFee float64,
+) CouponService {
+	return &couponService{
+		couponRepo:   couponRepo,
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		shippingFee:  shippingFee,
+	}
+}
+
+// トランザクション内でクーポンを利用するサービス（注文の作成と同時にコミット・ロールバックされる）
+func (s *couponService) WithTx(tx *gorm.DB) CouponService {
+	return &couponService{
+		couponRepo:   s.couponRepo.WithTx(tx),
+		productRepo:  s.productRepo,
+		categoryRepo: s.categoryRepo,
+		shippingFee:  s.shippingFee,
+	}
+}
+
+// クーポン作成
+func (s *couponService) CreateCoupon(coupon *model.Coupon) error {
+	if err := s.validateCoupon(coupon); err != nil {
+		return err
+	}
+	coupon.UsedCount = 0
+	return s.couponRepo.Create(coupon)
+}
+
+// クーポン更新（利用回数はそのまま）
+func (s *couponService) UpdateCoupon(coupon *model.Coupon) error {
+	existing, err := s.couponRepo.GetByID(coupon.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.validateCoupon(coupon); err != nil {
+		return err
+	}
+	if err := s.couponRepo.Update(coupon); err != nil {
+		return err
+	}
+	coupon.UsedCount = existing.UsedCount
+	coupon.CreatedAt = existing.CreatedAt
+	return nil
+}
+
+// クーポン削除（注文で利用されたクーポンは削除せず、無効にする）
+func (s *couponService) DeleteCoupon(id uint) error {
+	if _, err := s.couponRepo.GetByID(id); err != nil {
+		return err
+	}
+	used, err := s.couponRepo.HasRedemptions(id)
+	if err != nil {
+		return err
+	}
+	if used {
+		return errors.New("coupon has been used in orders; deactivate it instead")
+	}
+	return s.couponRepo.Delete(id)
+}
+
+func (s *couponService) GetCoupon(id uint) (*model.Coupon, error) {
+	return s.couponRepo.GetByID(id)
+}
+
+// クーポン一覧（管理者用）
+func (s *couponService) ListCoupons(page, pageSize int) ([]model.Coupon, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.couponRepo.List(page, pageSize)
+}
+
+// コードでクーポン取得（大文字・小文字を区別しない）
+func (s *couponService) GetByCode(code string) (*model.Coupon, error) {
+	code = normalizeCouponCode(code)
+	if code == "" {
+		return nil, errors.New("coupon code is required")
+	}
+	return s.couponRepo.GetByCode(code)
+}
+
+// ユーザーのカートに適用中のクーポン（適用していない場合はnil）
+func (s *couponService) CartCoupon(userID uint) (*model.Coupon, error) {
+	return s.couponRepo.GetCartCoupon(userID)
+}
+
+// カートに適用中のクーポンを行ロックを取得して取得（注文の作成時に利用回数を確認するため）
+func (s *couponService) CartCouponForUpdate(userID uint) (*model.Coupon, error) {
+	coupon, err := s.couponRepo.GetCartCoupon(userID)
+	if err != nil || coupon == nil {
+		return nil, err
+	}
+	return s.couponRepo.GetByIDForUpdate(coupon.ID)
+}
+
+func (s *couponService) SetCartCoupon(userID, couponID uint) error {
+	return s.couponRepo.SetCartCoupon(userID, couponID)
+}
+
+func (s *couponService) RemoveCartCoupon(userID uint) error {
+	return s.couponRepo.DeleteCartCoupon(userID)
+}
+
+// 送料と値引きを計算（クーポンが使えない場合はその理由をエラーで返す）
+// userIDを指定した場合は1ユーザーあたりの利用回数も確認する
+func (s *couponService) Price(lines []model.DiscountLine, coupon *model.Coupon, userID *uint) (*model.CartPricing, error) {
+	subtotal := 0.0
+	for _, line := range lines {
+		subtotal += line.Amount
+	}
+	pricing := &model.CartPricing{Discounts: []model.OrderDiscount{}}
+	if len(lines) > 0 {
+		pricing.ShippingFee = s.shippingFee
+	}
+
+	if coupon != nil {
+		discount, err := s.discount(coupon, lines, pricing.ShippingFee, userID)
+		if err != nil {
+			return nil, err
+		}
+		pricing.Discount = discount.Amount
+		pricing.Discounts = append(pricing.Discounts, *discount)
+	}
+
+	pricing.Total = math.Max(subtotal+pricing.ShippingFee-pricing.Discount, 0)
+	return pricing, nil
+}
+
+// クーポンの値引き額を計算（対象の商品の小計に対して値引きする）
+func (s *couponService) discount(coupon *model.Coupon, lines []model.DiscountLine, shippingFee float64, userID *uint) (*model.OrderDiscount, error) {
+	if err := s.checkAvailability(coupon, userID); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	eligible, err := s.eligibleSubtotal(coupon, lines)
+	if err != nil {
+		return nil, err
+	}
+	if eligible == 0 {
+		return nil, errors.New("coupon does not apply to any items in the cart")
+	}
+	if eligible < coupon.MinSubtotal {
+		return nil, fmt.Errorf("coupon requires a subtotal of at least %.0f", coupon.MinSubtotal)
+	}
+
+	var amount float64
+	switch coupon.Type {
+	case model.CouponTypePercentage:
+		amount = math.Floor(eligible * coupon.Value / 100)
+		if coupon.MaxDiscount > 0 {
+			amount = math.Min(amount, coupon.MaxDiscount)
+		}
+	case model.CouponTypeFixedAmount:
+		amount = math.Min(coupon.Value, eligible)
+	case model.CouponTypeFreeShipping:
+		amount = shippingFee
+	default:
+		return nil, errors.New("invalid coupon type")
+	}
+
+	couponID := coupon.ID
+	description := coupon.Description
+	if description == "" {
+		description = coupon.Code
+	}
+	return &model.OrderDiscount{
+		CouponID:    &couponID,
+		Code:        coupon.Code,
+		Type:        coupon.Type,
+		Description: description,
+		Amount:      amount,
+	}, nil
+}
+
+// 有効期間・利用回数の確認
+func (s *couponService) checkAvailability(coupon *model.Coupon, userID *uint) error {
+	now := time.Now()
+	if !coupon.Active {
+		return errors.New("coupon is not active")
+	}
+	if coupon.StartsAt != nil && now.Before(*coupon.StartsAt) {
+		return errors.New("coupon is not yet valid")
+	}
+	if coupon.EndsAt != nil && !now.Before(*coupon.EndsAt) {
+		return errors.New("coupon has expired")
+	}
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return errors.New("coupon usage limit reached")
+	}
+
+	if userID != nil && coupon.PerUserLimit > 0 {
+		used, err := s.couponRepo.CountRedemptions(coupon.ID, *userID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(coupon.PerUserLimit) {
+			return errors.New("coupon usage limit per user reached")
+		}
+	}
+	return nil
+}
+
+// クーポンの対象の商品の小計（対象を指定していない場合は全商品）
+func (s *couponService) eligibleSubtotal(coupon *model.Coupon, lines []model.DiscountLine) (float64, error) {
+	if !coupon.HasScope() {
+		total := 0.0
+		for _, line := range lines {
+			total += line.Amount
+		}
+		return total, nil
+	}
+
+	products := make(map[uint]bool, len(coupon.ProductIDs))
+	for _, productID := range coupon.ProductIDs {
+		products[productID] = true
+	}
+	// カテゴリは子孫カテゴリの商品も対象
+	categories := make(map[uint]bool)
+	for _, categoryID := range coupon.CategoryIDs {
+		ids, err := s.categoryRepo.DescendantIDs(categoryID)
+		if err != nil {
+			return 0, err
+		}
+		for _, id := range ids {
+			categories[id] = true
+		}
+	}
+
+	total := 0.0
+	for _, line := range lines {
+		if products[line.ProductID] || (line.CategoryID != nil && categories[*line.CategoryID]) {
+			total += line.Amount
+		}
+	}
+	return total, nil
+}
+
+// 注文でクーポンを利用（全体の利用回数の上限は同時に注文された場合も超えない）
+func (s *couponService) Redeem(coupon *model.Coupon, userID, orderID uint, amount float64) error {
+	ok, err := s.couponRepo.Redeem(&model.CouponRedemption{
+		CouponID:       coupon.ID,
+		UserID:         userID,
+		OrderID:        orderID,
+		DiscountAmount: amount,
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("coupon usage limit reached")
+	}
+	return nil
+}
+
+// キャンセルされた注文のクーポンの利用を取り消す（利用していない・取り消し済みの場合は何もしない）
+func (s *couponService) ReleaseOrder(orderID uint) error {
+	_, err := s.couponRepo.ReleaseByOrderID(orderID)
+	return err
+}
+
+func (s *couponService) validateCoupon(coupon *model.Coupon) error {
+	coupon.Code = normalizeCouponCode(coupon.Code)
+	coupon.Description = strings.TrimSpace(coupon.Description)
+
+	if coupon.Code == "" {
+		return errors.New("coupon code is required")
+	}
+	if len(coupon.Code) > 32 {
+		return errors.New("coupon code must be at most 32 characters")
+	}
+	for _, r := range coupon.Code {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return errors.New("coupon code may only contain letters, digits, hyphens and underscores")
+		}
+	}
+	if len([]rune(coupon.Description)) > 500 {
+		return errors.New("description must be at most 500 characters")
+	}
+
+	switch coupon.Type {
+	case model.CouponTypePercentage:
+		if coupon.Value <= 0 || coupon.Value > 100 {
+			return errors.New("percentage must be greater than 0 and at most 100")
+		}
+	case model.CouponTypeFixedAmount:
+		if coupon.Value <= 0 {
+			return errors.New("value must be greater than 0")
+		}
+		coupon.MaxDiscount = 0
+	case model.CouponTypeFreeShipping:
+		coupon.Value = 0
+		coupon.MaxDiscount = 0
+	default:
+		return errors.New("invalid coupon type")
+	}
+
+	if coupon.MaxDiscount < 0 || coupon.MinSubtotal < 0 {
+		return errors.New("max_discount and min_subtotal must not be negative")
+	}
+	if coupon.UsageLimit < 0 || coupon.PerUserLimit < 0 {
+		return errors.New("usage limits must not be negative")
+	}
+	if coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	coupon.ProductIDs = uniqueIDs(coupon.ProductIDs)
+	for _, productID := range coupon.ProductIDs {
+		if _, err := s.productRepo.GetByID(productID); err != nil {
+			return err
+		}
+	}
+	coupon.CategoryIDs = uniqueIDs(coupon.CategoryIDs)
+	for _, categoryID := range coupon.CategoryIDs {
+		if _, err := s.categoryRepo.GetByID(categoryID); err != nil {
+			return err
+		}
+	}
+
+	exists, err := s.couponRepo.CodeExists(coupon.Code, coupon.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("coupon code already exists")
+	}
+	return nil
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// カートアイテムを値引きの計算に使う明細に変換
+func cartDiscountLines(items []model.CartItem) []model.DiscountLine {
+	lines := make([]model.DiscountLine, 0, len(items))
+	for _, item := range items {
+		// 価格を決められない明細は合計にも含めない（注文時にエラーになる）
+		price, err := item.UnitPrice()
+		if err != nil {
+			continue
+		}
+		lines = append(lines, model.DiscountLine{
+			ProductID:  item.ProductID,
+			CategoryID: item.Product.CategoryID,
+			Amount:     price * float64(item.Quantity),
+		})
+	}
+	return lines
+}
\ No newline at end of file
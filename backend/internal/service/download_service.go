@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/imageproc"
+	"github.com/Naonao3/EC-site/backend/pkg/storage"
+)
+
+// ダウンロードの拒否理由
+var (
+	ErrDownloadInvalidSignature = errors.New("invalid download link")
+	ErrDownloadExpired          = errors.New("download link has expired")
+	ErrDownloadLimitExceeded    = errors.New("download limit has been reached for this purchase")
+	ErrDownloadUnavailable      = errors.New("download is not available")
+)
+
+// 決済が完了しているとみなす注文ステータス
+var paidOrderStatuses = map[string]bool{
+	"confirmed": true,
+	"shipped":   true,
+	"delivered": true,
+}
+
+// DownloadedFile ダウンロードする元画像（呼び出し側でBodyをCloseする）
+type DownloadedFile struct {
+	Body        io.ReadCloser
+	ContentType string
+	FileName    string
+	Size        int64
+}
+
+type DownloadService interface {
+	GrantForOrder(orderID uint) error
+	ListOrderDownloads(userID, orderID uint) ([]model.OrderDownload, error)
+	Download(ctx context.Context, grantID, imageID uint, expires, signature, ipAddress, userAgent string) (*DownloadedFile, error)
+	ListLogs(orderID uint, page, pageSize int) ([]model.DownloadLog, int64, error)
+}
+
+type downloadService struct {
+	downloadRepo   repository.DownloadRepository
+	orderRepo      repository.OrderRepository
+	imageRepo      repository.ProductImageRepository
+	privateStorage storage.Storage
+	signingSecret  []byte
+	baseURL        string
+	urlExpiry      time.Duration
+	maxDownloads   int
+}
+
+func NewDownloadService(
+	downloadRepo repository.DownloadRepository,
+	orderRepo repository.OrderRepository,
+	imageRepo repository.ProductImageRepository,
+	privateStorage storage.Storage,
+	signingSecret string,
+	baseURL string,
+	urlExpiry time.Duration,
+	maxDownloads int,
+) DownloadService {
+	return &downloadService{
+		downloadRepo:   downloadRepo,
+		orderRepo:      orderRepo,
+		imageRepo:      imageRepo,
+		privateStorage: privateStorage,
+		signingSecret:  []byte(signingSecret),
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		urlExpiry:      urlExpiry,
+		maxDownloads:   maxDownloads,
+	}
+}
+
+// 決済済みの注文の各明細にダウンロード権を付与（付与済みの明細はそのまま）
+func (s *downloadService) GrantForOrder(orderID uint) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+	return s.grant(order)
+}
+
+// 注文のダウンロード一覧（元画像ごとに有効期限付きの署名付きURLを発行）
+func (s *downloadService) ListOrderDownloads(userID, orderID uint) ([]model.OrderDownload, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ユーザーの所有確認
+	if order.UserID != userID {
+		return nil, errors.New("unauthorized")
+	}
+
+	if !paidOrderStatuses[order.Status] {
+		return nil, errors.New("downloads are available once payment is confirmed")
+	}
+
+	// 機能追加前に決済された注文にも付与する
+	if err := s.grant(order); err != nil {
+		return nil, err
+	}
+
+	grants, err := s.downloadRepo.ListGrantsByOrderID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	productNames := make(map[uint]string, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		productNames[item.ID] = item.Product.Name
+	}
+
+	expiresAt := time.Now().Add(s.urlExpiry)
+	downloads := make([]model.OrderDownload, 0, len(grants))
+	for _, grant := range grants {
+		images, err := s.imageRepo.ListByProductID(grant.ProductID)
+		if err != nil {
+			return nil, err
+		}
+
+		download := model.OrderDownload{
+			GrantID:            grant.ID,
+			OrderItemID:        grant.OrderItemID,
+			ProductID:          grant.ProductID,
+			ProductName:        productNames[grant.OrderItemID],
+			DownloadCount:      grant.DownloadCount,
+			MaxDownloads:       grant.MaxDownloads,
+			RemainingDownloads: max(0, grant.MaxDownloads-grant.DownloadCount),
+			Files:              make([]model.DownloadFile, 0, len(images)),
+		}
+		for _, image := range images {
+			download.Files = append(download.Files, model.DownloadFile{
+				ImageID:      image.ID,
+				ThumbnailURL: image.ThumbnailURL,
+				ContentType:  image.ContentType,
+				Width:        image.Width,
+				Height:       image.Height,
+				Size:         image.Size,
+				URL:          s.signedURL(grant.ID, image.ID, expiresAt),
+				ExpiresAt:    expiresAt,
+			})
+		}
+		downloads = append(downloads, download)
+	}
+
+	return downloads, nil
+}
+
+// 署名付きURLを検証して元画像を開く（拒否した場合も含めてアクセスログに記録）
+func (s *downloadService) Download(ctx context.Context, grantID, imageID uint, expires, signature, ipAddress, userAgent string) (*DownloadedFile, error) {
+	entry := &model.DownloadLog{
+		GrantID:   grantID,
+		ImageID:   imageID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(grantID, imageID, expires))) {
+		return nil, s.reject(entry, model.DownloadResultInvalidSignature, ErrDownloadInvalidSignature)
+	}
+
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, s.reject(entry, model.DownloadResultInvalidSignature, ErrDownloadInvalidSignature)
+	}
+	if time.Now().Unix() > expiresUnix {
+		return nil, s.reject(entry, model.DownloadResultExpired, ErrDownloadExpired)
+	}
+
+	grant, err := s.downloadRepo.GetGrantByID(grantID)
+	if err != nil {
+		return nil, s.reject(entry, model.DownloadResultUnavailable, ErrDownloadUnavailable)
+	}
+	entry.OrderID = grant.OrderID
+	entry.UserID = grant.UserID
+
+	// キャンセルされた注文はダウンロードできない
+	order, err := s.orderRepo.GetByID(grant.OrderID)
+	if err != nil || !paidOrderStatuses[order.Status] {
+		return nil, s.reject(entry, model.DownloadResultUnavailable, ErrDownloadUnavailable)
+	}
+
+	image, err := s.imageRepo.GetByID(grant.ProductID, imageID)
+	if err != nil {
+		return nil, s.reject(entry, model.DownloadResultUnavailable, ErrDownloadUnavailable)
+	}
+
+	body, err := s.privateStorage.Get(ctx, originalImageKey(image))
+	if err != nil {
+		s.writeLog(entry, model.DownloadResultError)
+		return nil, err
+	}
+
+	// ファイルを開けた場合のみ回数を消費する
+	ok, err := s.downloadRepo.IncrementDownloadCount(grant.ID)
+	if err != nil {
+		body.Close()
+		s.writeLog(entry, model.DownloadResultError)
+		return nil, err
+	}
+	if !ok {
+		body.Close()
+		return nil, s.reject(entry, model.DownloadResultLimitExceeded, ErrDownloadLimitExceeded)
+	}
+
+	s.writeLog(entry, model.DownloadResultSuccess)
+
+	return &DownloadedFile{
+		Body:        body,
+		ContentType: image.ContentType,
+		FileName:    fmt.Sprintf("product-%d-image-%d%s", image.ProductID, image.ID, imageproc.Extension(image.ContentType)),
+		Size:        image.Size,
+	}, nil
+}
+
+func (s *downloadService) ListLogs(orderID uint, page, pageSize int) ([]model.DownloadLog, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	return s.downloadRepo.ListLogs(orderID, page, pageSize)
+}
+
+func (s *downloadService) grant(order *model.Order) error {
+	if !paidOrderStatuses[order.Status] {
+		return nil
+	}
+
+	grants := make([]model.DownloadGrant, 0, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		grants = append(grants, model.DownloadGrant{
+			OrderID:      order.ID,
+			OrderItemID:  item.ID,
+			UserID:       order.UserID,
+			ProductID:    item.ProductID,
+			MaxDownloads: s.maxDownloads,
+		})
+	}
+	return s.downloadRepo.CreateGrants(grants)
+}
+
+// {baseURL}/api/downloads/{grantID}/{imageID}?expires=...&signature=...
+func (s *downloadService) signedURL(grantID, imageID uint, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(grantID, imageID, expires))
+	return fmt.Sprintf("%s/api/downloads/%d/%d?%s", s.baseURL, grantID, imageID, query.Encode())
+}
+
+// ダウンロード権・画像・有効期限に対するHMAC-SHA256署名（16進数）
+func (s *downloadService) sign(grantID, imageID uint, expires string) string {
+	mac := hmac.New(sha256.New, s.signingSecret)
+	fmt.Fprintf(mac, "%d:%d:%s", grantID, imageID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *downloadService) reject(entry *model.DownloadLog, result string, err error) error {
+	s.writeLog(entry, result)
+	return err
+}
+
+// アクセスログの記録に失敗してもダウンロードの可否は変えない
+func (s *downloadService) writeLog(entry *model.DownloadLog, result string) {
+	entry.Result = result
+	if err := s.downloadRepo.CreateLog(entry); err != nil {
+		log.Printf("Failed to write download log: %v", err)
+	}
+}
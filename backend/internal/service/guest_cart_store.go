@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// 同時に更新された場合に読み直す回数
+const guestCartUpdateRetries = 5
+
+// GuestCartStore ゲストカートの保存先（最後の更新から一定期間で期限切れになる）
+type GuestCartStore interface {
+	Get(cartID string) (*model.GuestCart, error)
+	Update(cartID string, fn func(cart *model.GuestCart) error) (*model.GuestCart, error)
+	Take(cartID string) (*model.GuestCart, error)
+	Delete(cartID string) error
+}
+
+// redisClientがnilの場合はメモリに保存（単一プロセス用）
+func NewGuestCartStore(redisClient *redis.Client, ttl time.Duration) GuestCartStore {
+	if redisClient == nil {
+		return &memoryGuestCartStore{ttl: ttl, carts: make(map[string]*memoryGuestCart)}
+	}
+	return &redisGuestCartStore{redis: redisClient, ttl: ttl}
+}
+
+func guestCartKey(cartID string) string {
+	return "guest_cart:" + cartID
+}
+
+// Redisに保存するGuestCartStore
+type redisGuestCartStore struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// 存在しない・期限切れの場合は空のカート
+func (s *redisGuestCartStore) Get(cartID string) (*model.GuestCart, error) {
+	return s.get(context.Background(), s.redis, cartID)
+}
+
+func (s *redisGuestCartStore) get(ctx context.Context, cmd redis.Cmdable, cartID string) (*model.GuestCart, error) {
+	data, err := cmd.Get(ctx, guestCartKey(cartID)).Bytes()
+	if err == redis.Nil {
+		return &model.GuestCart{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cart model.GuestCart
+	if err := json.Unmarshal(data, &cart); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// 読み取りから書き込みまでの間に他のリクエストが更新した場合は読み直してfnを再実行
+func (s *redisGuestCartStore) Update(cartID string, fn func(cart *model.GuestCart) error) (*model.GuestCart, error) {
+	ctx := context.Background()
+	key := guestCartKey(cartID)
+
+	var updated *model.GuestCart
+	for i := 0; i < guestCartUpdateRetries; i++ {
+		err := s.redis.Watch(ctx, func(tx *redis.Tx) error {
+			cart, err := s.get(ctx, tx, cartID)
+			if err != nil {
+				return err
+			}
+			if err := fn(cart); err != nil {
+				return err
+			}
+			data, err := json.Marshal(cart)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				return pipe.Set(ctx, key, data, s.ttl).Err()
+			})
+			if err == nil {
+				updated = cart
+			}
+			return err
+		}, key)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return updated, nil
+	}
+	return nil, errors.New("guest cart was modified concurrently; please retry")
+}
+
+// 取得と同時に削除（同じカートを同時に統合しても一方だけが中身を受け取る）
+func (s *redisGuestCartStore) Take(cartID string) (*model.GuestCart, error) {
+	data, err := s.redis.GetDel(context.Background(), guestCartKey(cartID)).Bytes()
+	if err == redis.Nil {
+		return &model.GuestCart{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cart model.GuestCart
+	if err := json.Unmarshal(data, &cart); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (s *redisGuestCartStore) Delete(cartID string) error {
+	return s.redis.Del(context.Background(), guestCartKey(cartID)).Err()
+}
+
+type memoryGuestCart struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// メモリ上のGuestCartStore（Redisに接続できない場合の代わり。再起動で消える）
+type memoryGuestCartStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	carts map[string]*memoryGuestCart
+}
+
+func (s *memoryGuestCartStore) Get(cartID string) (*model.GuestCart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(cartID)
+}
+
+// ロック取得済みで呼ぶこと
+func (s *memoryGuestCartStore) get(cartID string) (*model.GuestCart, error) {
+	entry, ok := s.carts[cartID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(s.carts, cartID)
+		return &model.GuestCart{}, nil
+	}
+
+	var cart model.GuestCart
+	if err := json.Unmarshal(entry.data, &cart); err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+func (s *memoryGuestCartStore) Update(cartID string, fn func(cart *model.GuestCart) error) (*model.GuestCart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.get(cartID)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(cart); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(cart)
+	if err != nil {
+		return nil, err
+	}
+
+	s.sweep()
+	s.carts[cartID] = &memoryGuestCart{data: data, expiresAt: time.Now().Add(s.ttl)}
+	return cart, nil
+}
+
+func (s *memoryGuestCartStore) Take(cartID string) (*model.GuestCart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cart, err := s.get(cartID)
+	delete(s.carts, cartID)
+	return cart, err
+}
+
+func (s *memoryGuestCartStore) Delete(cartID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.carts, cartID)
+	return nil
+}
+
+// カートが増えすぎた場合に期限切れのものをまとめて削除（ロック取得済みで呼ぶこと）
+func (s *memoryGuestCartStore) sweep() {
+	if len(s.carts) < 10000 {
+		return
+	}
+	now := time.Now()
+	for cartID, entry := range s.carts {
+		if now.After(entry.expiresAt) {
+			delete(s.carts, cartID)
+		}
+	}
+}
\ No newline at end of file
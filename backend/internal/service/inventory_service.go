@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// 1回の解放処理で扱う注文数
+const reservationSweepBatchSize = 100
+
+type InventoryService interface {
+	ReserveOrder(order *model.Order, allocations []model.WarehouseAllocation) error
+	CommitOrder(orderID uint) error
+	ReleaseOrder(orderID uint, reason string) error
+	RestockOrder(orderID uint) error
+	ReleaseExpired() (int, error)
+	RunSweeper(ctx context.Context, interval time.Duration)
+	OrderStockChanged(orderID uint)
+	WithTx(tx *gorm.DB) InventoryService
+}
+
+type inventoryService struct {
+	reservationRepo    repository.ReservationRepository
+	orderRepo          repository.OrderRepository
+	reservationTTL     time.Duration
+	stockNotifications StockNotificationService
+	couponService      CouponService
+	// トランザクション内のサービスでは発注点・入荷通知の確認を呼び出し側がコミット後に行う
+	inTx bool
+}
+
+func NewInventoryService(
+	reservationRepo repository.ReservationRepository,
+	orderRepo repository.OrderRepository,
+	reservationTTL time.Duration,
+	stockNotifications StockNotificationService,
+	couponService CouponService,
+) InventoryService {
+	return &inventoryService{
+		reservationRepo:    reservationRepo,
+		orderRepo:          orderRepo,
+		reservationTTL:     reservationTTL,
+		stockNotifications: stockNotifications,
+		couponService:      couponService,
+	}
+}
+
+// トランザクション内で在庫を操作するサービス（注文の作成と同時にコミット・ロールバックされる）
+func (s *inventoryService) WithTx(tx *gorm.DB) InventoryService {
+	return &inventoryService{
+		reservationRepo:    s.reservationRepo.WithTx(tx),
+		orderRepo:          s.orderRepo.WithTx(tx),
+		reservationTTL:     s.reservationTTL,
+		stockNotifications: s.stockNotifications,
+		couponService:      s.couponService,
+		inTx:               true,
+	}
+}
+
+// 注文明細の出荷元の倉庫ごとに決済期限まで在庫を確保
+func (s *inventoryService) ReserveOrder(order *model.Order, allocations []model.WarehouseAllocation) error {
+	items := make(map[uint]model.OrderItem, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		items[item.ID] = item
+	}
+
+	expiresAt := time.Now().Add(s.reservationTTL)
+	reservations := make([]model.InventoryReservation, 0, len(allocations))
+	for _, allocation := range allocations {
+		item, ok := items[allocation.OrderItemID]
+		if !ok {
+			return errors.New("order item not found")
+		}
+		warehouseID := allocation.WarehouseID
+		reservations = append(reservations, model.InventoryReservation{
+			OrderID:     order.ID,
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			VariantID:   item.VariantID,
+			WarehouseID: &warehouseID,
+			Quantity:    allocation.Quantity,
+			Status:      model.ReservationStatusActive,
+			ExpiresAt:   expiresAt,
+		})
+	}
+
+	ok, err := s.reservationRepo.Reserve(reservations)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("insufficient stock")
+	}
+	return nil
+}
+
+// 決済完了した注文の確保分を在庫から引き当て
+func (s *inventoryService) CommitOrder(orderID uint) error {
+	late, short, err := s.reservationRepo.Commit(orderID)
+	if err != nil {
+		return err
+	}
+	if late > 0 {
+		log.Printf("Order %d was paid after its stock reservation expired; committed %d items from unreserved stock", orderID, late)
+	}
+	if short > 0 {
+		// 確保期限後に決済され、他の注文が確保していない在庫が足りなかった（注文に手動での対応が必要と記録済み）
+		log.Printf("Order %d was paid after its stock reservation expired and %d items are out of stock; manual handling required", orderID, short)
+	}
+	s.stockChanged(orderID)
+	return nil
+}
+
+// 注文の確保分を解放（キャンセル時）
+func (s *inventoryService) ReleaseOrder(orderID uint, reason string) error {
+	released, err := s.reservationRepo.Release(orderID, reason)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		s.stockChanged(orderID)
+	}
+	return nil
+}
+
+// 決済済みの注文の引き当て分を在庫に戻す（キャンセル時）
+func (s *inventoryService) RestockOrder(orderID uint) error {
+	restocked, err := s.reservationRepo.Restock(orderID)
+	if err != nil {
+		return err
+	}
+	if restocked > 0 {
+		s.stockChanged(orderID)
+	}
+	return nil
+}
+
+// 確保期限を過ぎた注文の在庫を解放し、未決済の注文をキャンセル
+func (s *inventoryService) ReleaseExpired() (int, error) {
+	orderIDs, err := s.reservationRepo.ListExpiredOrderIDs(time.Now(), reservationSweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, orderID := range orderIDs {
+		count, err := s.reservationRepo.Release(orderID, model.ReservationReleaseExpired)
+		if err != nil {
+			return released, err
+		}
+		if count == 0 {
+			// 決済完了の処理で先に引き当てられた
+			continue
+		}
+		released++
+		s.OrderStockChanged(orderID)
+
+		// 同時に決済が完了した注文はキャンセルしない
+		cancelled, err := s.orderRepo.UpdateStatusIf(orderID, "pending", "cancelled")
+		if err != nil {
+			return released, err
+		}
+		if cancelled {
+			if err := s.couponService.ReleaseOrder(orderID); err != nil {
+				return released, err
+			}
+		}
+	}
+	return released, nil
+}
+
+// トランザクション外のサービスでのみ発注点・入荷通知を確認
+func (s *inventoryService) stockChanged(orderID uint) {
+	if !s.inTx {
+		s.OrderStockChanged(orderID)
+	}
+}
+
+// 注文の商品の発注点・入荷通知を確認（トランザクション内のサービスではコミット後に呼び出す）
+func (s *inventoryService) OrderStockChanged(orderID uint) {
+	reservations, err := s.reservationRepo.ListByOrderID(orderID)
+	if err != nil {
+		log.Printf("Failed to list stock reservations of order %d: %v", orderID, err)
+		return
+	}
+
+	productIDs := make([]uint, 0, len(reservations))
+	for _, reservation := range reservations {
+		productIDs = append(productIDs, reservation.ProductID)
+	}
+	s.stockNotifications.ProductStockChanged(productIDs...)
+}
+
+// 一定間隔で期限切れの在庫予約を解放（ctxが終了するまで実行）
+func (s *inventoryService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		released, err := s.ReleaseExpired()
+		if err != nil {
+			log.Printf("Failed to release expired stock reservations: %v", err)
+		} else if released > 0 {
+			log.Printf("Released stock reservations of %d expired orders", released)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
\ No newline at end of file
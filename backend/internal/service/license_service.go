@@ -0,0 +1,347 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/pdf"
+)
+
+// ライセンスIDに使う文字（読み間違えやすい0/O・1/Iを除く）
+const licenseIDAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// 証明書の発行者名
+const licenseIssuer = "Shiba Image Store"
+
+// ライセンスの表示名
+var licenseTypeNames = map[string]string{
+	model.LicenseTypePersonal:   "Personal License",
+	model.LicenseTypeCommercial: "Commercial License",
+	model.LicenseTypeExtended:   "Extended License",
+}
+
+type LicenseService interface {
+	ListLicenses(productID uint) ([]model.ProductLicense, error)
+	SetLicenses(productID uint, licenses []model.ProductLicense) ([]model.ProductLicense, error)
+	IssueForOrder(orderID uint) error
+	ListOrderCertificates(userID, orderID uint) ([]model.LicenseCertificate, error)
+	GetCertificate(userID, orderID uint, licenseID string) (*model.LicenseCertificate, error)
+	Verify(licenseID string) (*model.LicenseVerification, error)
+	RevokeCertificate(licenseID, reason string) (*model.LicenseCertificate, error)
+	RenderText(certificate *model.LicenseCertificate) string
+	RenderPDF(certificate *model.LicenseCertificate) []byte
+}
+
+type licenseService struct {
+	licenseRepo repository.LicenseRepository
+	productRepo repository.ProductRepository
+	orderRepo   repository.OrderRepository
+	verifyURL   string
+}
+
+func NewLicenseService(
+	licenseRepo repository.LicenseRepository,
+	productRepo repository.ProductRepository,
+	orderRepo repository.OrderRepository,
+	baseURL string,
+) LicenseService {
+	return &licenseService{
+		licenseRepo: licenseRepo,
+		productRepo: productRepo,
+		orderRepo:   orderRepo,
+		verifyURL:   strings.TrimRight(baseURL, "/") + "/api/licenses/%s/verify",
+	}
+}
+
+// 販売中のライセンス一覧
+func (s *licenseService) ListLicenses(productID uint) ([]model.ProductLicense, error) {
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+
+	licenses, err := s.licenseRepo.ListByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]model.ProductLicense, 0, len(licenses))
+	for _, license := range licenses {
+		if license.Active {
+			active = append(active, license)
+		}
+	}
+	return active, nil
+}
+
+// 商品のライセンスを設定（指定しなかった種類は販売停止）
+func (s *licenseService) SetLicenses(productID uint, licenses []model.ProductLicense) ([]model.ProductLicense, error) {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	// バリエーションで価格を決める商品とは併用できない
+	if len(licenses) > 0 && product.HasVariants() {
+		return nil, errors.New("products with variants cannot be sold with licenses")
+	}
+
+	seen := make(map[string]bool, len(licenses))
+	for i := range licenses {
+		license := &licenses[i]
+		license.Type = strings.ToLower(strings.TrimSpace(license.Type))
+		if !slices.Contains(model.LicenseTypes, license.Type) {
+			return nil, fmt.Errorf("invalid license type: %s (allowed: %s)", license.Type, strings.Join(model.LicenseTypes, ", "))
+		}
+		if seen[license.Type] {
+			return nil, fmt.Errorf("duplicate license type: %s", license.Type)
+		}
+		seen[license.Type] = true
+
+		if license.Price <= 0 {
+			return nil, errors.New("license price must be greater than 0")
+		}
+		license.Terms = strings.TrimSpace(license.Terms)
+	}
+
+	if err := s.licenseRepo.SaveProductLicenses(productID, licenses); err != nil {
+		return nil, err
+	}
+
+	return s.ListLicenses(productID)
+}
+
+// 決済済みの注文のライセンス付き明細に証明書を発行（発行済みの明細はそのまま）
+func (s *licenseService) IssueForOrder(orderID uint) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+	return s.issue(order)
+}
+
+func (s *licenseService) ListOrderCertificates(userID, orderID uint) ([]model.LicenseCertificate, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ユーザーの所有確認
+	if order.UserID != userID {
+		return nil, errors.New("unauthorized")
+	}
+
+	if !paidOrderStatuses[order.Status] {
+		return nil, errors.New("licenses are issued once payment is confirmed")
+	}
+
+	// 機能追加前に決済された注文にも発行する
+	if err := s.issue(order); err != nil {
+		return nil, err
+	}
+
+	return s.licenseRepo.ListCertificatesByOrderID(order.ID)
+}
+
+func (s *licenseService) GetCertificate(userID, orderID uint, licenseID string) (*model.LicenseCertificate, error) {
+	certificate, err := s.licenseRepo.GetCertificateByLicenseID(normalizeLicenseID(licenseID))
+	if err != nil {
+		return nil, err
+	}
+
+	// ユーザーの所有確認
+	if certificate.UserID != userID || certificate.OrderID != orderID {
+		return nil, errors.New("license not found")
+	}
+
+	return certificate, nil
+}
+
+// ライセンスIDの検証（認証不要）
+func (s *licenseService) Verify(licenseID string) (*model.LicenseVerification, error) {
+	certificate, err := s.licenseRepo.GetCertificateByLicenseID(normalizeLicenseID(licenseID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.LicenseVerification{
+		LicenseID:    certificate.LicenseID,
+		Valid:        certificate.IsValid(),
+		ProductID:    certificate.ProductID,
+		ProductName:  certificate.ProductName,
+		LicenseType:  certificate.LicenseType,
+		Quantity:     certificate.Quantity,
+		LicenseeName: maskName(certificate.LicenseeName),
+		IssuedAt:     certificate.IssuedAt,
+		RevokedAt:    certificate.RevokedAt,
+	}, nil
+}
+
+// 証明書の取り消し（返金・規約違反など）
+func (s *licenseService) RevokeCertificate(licenseID, reason string) (*model.LicenseCertificate, error) {
+	return s.licenseRepo.RevokeCertificate(normalizeLicenseID(licenseID), strings.TrimSpace(reason))
+}
+
+// テキスト形式の証明書
+func (s *licenseService) RenderText(certificate *model.LicenseCertificate) string {
+	var b strings.Builder
+	b.WriteString("LICENSE CERTIFICATE\n")
+	b.WriteString("===================\n\n")
+	for _, field := range s.certificateFields(certificate) {
+		fmt.Fprintf(&b, "%-14s %s\n", field[0]+":", field[1])
+	}
+	if certificate.Terms != "" {
+		b.WriteString("\nTerms\n-----\n")
+		b.WriteString(certificate.Terms)
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "\nVerify this license at: %s\n", fmt.Sprintf(s.verifyURL, certificate.LicenseID))
+	return b.String()
+}
+
+// PDF形式の証明書
+func (s *licenseService) RenderPDF(certificate *model.LicenseCertificate) []byte {
+	doc := pdf.New()
+	doc.Rect(36, 36, pdf.PageWidth-72, pdf.PageHeight-72, 1.5)
+	doc.CenteredText(740, 26, "LICENSE CERTIFICATE")
+	doc.CenteredText(712, 12, licenseIssuer)
+	doc.Line(72, 690, pdf.PageWidth-72, 690, 0.5)
+
+	y := 650.0
+	for _, field := range s.certificateFields(certificate) {
+		doc.Text(72, y, 11, field[0])
+		doc.Text(190, y, 11, field[1])
+		y -= 24
+	}
+
+	if certificate.Terms != "" {
+		y -= 12
+		doc.Text(72, y, 12, "Terms")
+		y -= 20
+		for _, line := range wrapText(certificate.Terms, 90) {
+			if y < 120 {
+				break
+			}
+			doc.Text(72, y, 10, line)
+			y -= 15
+		}
+	}
+
+	doc.Line(72, 100, pdf.PageWidth-72, 100, 0.5)
+	doc.Text(72, 80, 9, "Verify this license at:")
+	doc.Text(72, 66, 9, fmt.Sprintf(s.verifyURL, certificate.LicenseID))
+
+	return doc.Bytes()
+}
+
+func (s *licenseService) certificateFields(certificate *model.LicenseCertificate) [][2]string {
+	status := "Valid"
+	if certificate.RevokedAt != nil {
+		status = "Revoked on " + certificate.RevokedAt.Format("2006-01-02")
+	}
+	return [][2]string{
+		{"License ID", certificate.LicenseID},
+		{"License", licenseTypeNames[certificate.LicenseType]},
+		{"Product", certificate.ProductName},
+		{"Licensee", certificate.LicenseeName},
+		{"Quantity", fmt.Sprintf("%d", certificate.Quantity)},
+		{"Price", fmt.Sprintf("JPY %.0f", certificate.Price)},
+		{"Issued", certificate.IssuedAt.Format("2006-01-02")},
+		{"Status", status},
+	}
+}
+
+func (s *licenseService) issue(order *model.Order) error {
+	if !paidOrderStatuses[order.Status] {
+		return nil
+	}
+
+	now := time.Now()
+	var certificates []model.LicenseCertificate
+	for _, item := range order.OrderItems {
+		if item.LicenseType == "" {
+			continue
+		}
+
+		licenseID, err := newLicenseID()
+		if err != nil {
+			return err
+		}
+		certificates = append(certificates, model.LicenseCertificate{
+			LicenseID:    licenseID,
+			OrderID:      order.ID,
+			OrderItemID:  item.ID,
+			UserID:       order.UserID,
+			ProductID:    item.ProductID,
+			ProductName:  item.Product.Name,
+			LicenseType:  item.LicenseType,
+			Terms:        item.LicenseTerms,
+			Quantity:     item.Quantity,
+			Price:        item.Price,
+			LicenseeName: order.User.Name,
+			IssuedAt:     now,
+		})
+	}
+
+	return s.licenseRepo.CreateCertificates(certificates)
+}
+
+// LIC-XXXX-XXXX-XXXX-XXXX形式のライセンスID（80ビット）
+func newLicenseID() (string, error) {
+	groups := make([]string, 4)
+	for i := range groups {
+		group := make([]byte, 4)
+		for j := range group {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(licenseIDAlphabet))))
+			if err != nil {
+				return "", err
+			}
+			group[j] = licenseIDAlphabet[n.Int64()]
+		}
+		groups[i] = string(group)
+	}
+	return "LIC-" + strings.Join(groups, "-"), nil
+}
+
+// 入力されたライセンスIDの表記揺れ（小文字・前後の空白）を吸収
+func normalizeLicenseID(licenseID string) string {
+	return strings.ToUpper(strings.TrimSpace(licenseID))
+}
+
+// 公開する購入者名（各語の先頭1文字だけを残し、文字数が分からないよう残りは固定の伏せ字にする）
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		words[i] = string([]rune(word)[:1]) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+// 指定した幅（半角換算）で折り返す
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		lineWidth := 0
+		for _, r := range paragraph {
+			w := 1
+			if r >= 0x80 {
+				w = 2
+			}
+			if lineWidth+w > width {
+				lines = append(lines, string(line))
+				line, lineWidth = nil, 0
+			}
+			line = append(line, r)
+			lineWidth += w
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
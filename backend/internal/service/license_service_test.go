@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+type fakeLicenseRepository struct {
+	repository.LicenseRepository
+	certificate *model.LicenseCertificate
+}
+
+func (r *fakeLicenseRepository) GetCertificateByLicenseID(licenseID string) (*model.LicenseCertificate, error) {
+	copied := *r.certificate
+	return &copied, nil
+}
+
+// 公開の検証結果では購入者名を伏せる
+func TestVerifyMasksLicenseeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "山田 太郎", want: "山*** 太***"},
+		{name: "Taro Yamada", want: "T*** Y***"},
+		{name: "Taro", want: "T***"},
+		{name: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			licenses := &fakeLicenseRepository{certificate: &model.LicenseCertificate{
+				LicenseID:    "ABCD-EFGH",
+				LicenseeName: tt.name,
+				IssuedAt:     time.Now(),
+			}}
+			s := NewLicenseService(licenses, nil, nil, "http://localhost:8080")
+
+			verification, err := s.Verify("abcd-efgh")
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if verification.LicenseeName != tt.want {
+				t.Errorf("Verify() licensee = %q, want %q", verification.LicenseeName, tt.want)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 連続失敗時の待ち時間の上限
+const maxLoginDelay = 30 * time.Second
+
+// LoginThrottledError ログイン試行が制限されている場合のエラー
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry after %d seconds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// AttemptStore 失敗回数カウンターの保存先
+type AttemptStore interface {
+	// キーのカウンターを加算（最初の加算時にwindowの有効期限を設定）
+	Incr(key string, window time.Duration) (int64, error)
+	// ttlの間だけキーを設定
+	Block(key string, ttl time.Duration) error
+	// キーの残り有効期間（存在しない場合は0）
+	Remaining(key string) (time.Duration, error)
+	Delete(keys ...string) error
+}
+
+// LoginThrottle ログイン失敗回数に応じた遅延・ブロック
+type LoginThrottle interface {
+	// ログインを試行してよいか確認（待つ必要がある場合はLoginThrottledError）
+	Check(email, ip string) error
+	// 失敗を記録し、アカウントをロックすべき場合はロック期限を返す
+	RegisterFailure(email, ip string) (*time.Time, error)
+	// 成功時にアカウント単位のカウンターをリセット
+	Reset(email string) error
+}
+
+type loginThrottle struct {
+	store         AttemptStore
+	maxPerAccount int
+	maxPerIP      int
+	window        time.Duration
+	lockoutPeriod time.Duration
+}
+
+func NewLoginThrottle(store AttemptStore, maxPerAccount, maxPerIP int, window, lockoutPeriod time.Duration) LoginThrottle {
+	return &loginThrottle{
+		store:         store,
+		maxPerAccount: maxPerAccount,
+		maxPerIP:      maxPerIP,
+		window:        window,
+		lockoutPeriod: lockoutPeriod,
+	}
+}
+
+func (t *loginThrottle) Check(email, ip string) error {
+	for _, key := range []string{
+		"login:block:ip:" + ip,
+		"login:delay:email:" + normalizeEmail(email),
+		"login:delay:ip:" + ip,
+	} {
+		remaining, err := t.store.Remaining(key)
+		if err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return &LoginThrottledError{RetryAfter: remaining}
+		}
+	}
+	return nil
+}
+
+func (t *loginThrottle) RegisterFailure(email, ip string) (*time.Time, error) {
+	email = normalizeEmail(email)
+
+	emailFailures, err := t.store.Incr("login:fail:email:"+email, t.window)
+	if err != nil {
+		return nil, err
+	}
+	ipFailures, err := t.store.Incr("login:fail:ip:"+ip, t.window)
+	if err != nil {
+		return nil, err
+	}
+
+	// 連続失敗回数に応じて次の試行までの待ち時間を延ばす（1, 2, 4, 8...秒）
+	if delay := progressiveDelay(emailFailures); delay > 0 {
+		if err := t.store.Block("login:delay:email:"+email, delay); err != nil {
+			return nil, err
+		}
+	}
+	if delay := progressiveDelay(ipFailures); delay > 0 {
+		if err := t.store.Block("login:delay:ip:"+ip, delay); err != nil {
+			return nil, err
+		}
+	}
+
+	// IP単位の失敗回数が上限を超えたら一定期間ブロック
+	if t.maxPerIP > 0 && ipFailures >= int64(t.maxPerIP) {
+		if err := t.store.Block("login:block:ip:"+ip, t.lockoutPeriod); err != nil {
+			return nil, err
+		}
+	}
+
+	// アカウント単位の失敗回数が上限を超えたらアカウントをロック
+	if t.maxPerAccount > 0 && emailFailures >= int64(t.maxPerAccount) {
+		lockedUntil := time.Now().Add(t.lockoutPeriod)
+		if err := t.store.Delete("login:fail:email:" + email); err != nil {
+			return nil, err
+		}
+		return &lockedUntil, nil
+	}
+
+	return nil, nil
+}
+
+func (t *loginThrottle) Reset(email string) error {
+	email = normalizeEmail(email)
+	return t.store.Delete("login:fail:email:"+email, "login:delay:email:"+email)
+}
+
+// 2回目の失敗から待ち時間を発生させる
+func progressiveDelay(failures int64) time.Duration {
+	if failures < 2 {
+		return 0
+	}
+	if failures > 7 {
+		return maxLoginDelay
+	}
+	delay := time.Duration(1<<(failures-2)) * time.Second
+	if delay > maxLoginDelay {
+		return maxLoginDelay
+	}
+	return delay
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Redisを使用し、Redisが使えない場合はメモリにフォールバックするAttemptStore
+type attemptStore struct {
+	redis  *redis.Client
+	memory *memoryAttemptStore
+}
+
+// redisClientはnilでも良い（その場合はメモリのみを使用）
+func NewAttemptStore(redisClient *redis.Client) AttemptStore {
+	return &attemptStore{
+		redis:  redisClient,
+		memory: newMemoryAttemptStore(),
+	}
+}
+
+func (s *attemptStore) Incr(key string, window time.Duration) (int64, error) {
+	if s.redis != nil {
+		ctx := context.Background()
+		pipe := s.redis.TxPipeline()
+		incr := pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, window)
+		_, err := pipe.Exec(ctx)
+		if err == nil {
+			return incr.Val(), nil
+		}
+		log.Println("Warning: Redis unavailable for login throttle, falling back to memory:", err)
+	}
+	return s.memory.Incr(key, window)
+}
+
+func (s *attemptStore) Block(key string, ttl time.Duration) error {
+	if s.redis != nil {
+		err := s.redis.Set(context.Background(), key, 1, ttl).Err()
+		if err == nil {
+			return nil
+		}
+		log.Println("Warning: Redis unavailable for login throttle, falling back to memory:", err)
+	}
+	return s.memory.Block(key, ttl)
+}
+
+func (s *attemptStore) Remaining(key string) (time.Duration, error) {
+	if s.redis != nil {
+		ttl, err := s.redis.PTTL(context.Background(), key).Result()
+		if err == nil {
+			if ttl < 0 {
+				return 0, nil
+			}
+			return ttl, nil
+		}
+		log.Println("Warning: Redis unavailable for login throttle, falling back to memory:", err)
+	}
+	return s.memory.Remaining(key)
+}
+
+func (s *attemptStore) Delete(keys ...string) error {
+	if s.redis != nil {
+		if err := s.redis.Del(context.Background(), keys...).Err(); err != nil {
+			log.Println("Warning: Redis unavailable for login throttle, falling back to memory:", err)
+		}
+	}
+	return s.memory.Delete(keys...)
+}
+
+type memoryEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// メモリ上のAttemptStore（単一プロセス用）
+type memoryAttemptStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+func newMemoryAttemptStore() *memoryAttemptStore {
+	return &memoryAttemptStore{entries: make(map[string]*memoryEntry)}
+}
+
+// 期限切れのエントリを取り除いて返す（ロック取得済みで呼ぶこと）
+func (s *memoryAttemptStore) get(key string) *memoryEntry {
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(s.entries, key)
+		return nil
+	}
+	return entry
+}
+
+// エントリが増えすぎた場合に期限切れのものをまとめて削除（ロック取得済みで呼ぶこと）
+func (s *memoryAttemptStore) sweep() {
+	if len(s.entries) < 10000 {
+		return
+	}
+	now := time.Now()
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func (s *memoryAttemptStore) Incr(key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.get(key)
+	if entry == nil {
+		s.sweep()
+		entry = &memoryEntry{expiresAt: time.Now().Add(window)}
+		s.entries[key] = entry
+	}
+	entry.count++
+	return entry.count, nil
+}
+
+func (s *memoryAttemptStore) Block(key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &memoryEntry{count: 1, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryAttemptStore) Remaining(key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.get(key)
+	if entry == nil {
+		return 0, nil
+	}
+	return time.Until(entry.expiresAt), nil
+}
+
+func (s *memoryAttemptStore) Delete(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
\ No newline at end of file
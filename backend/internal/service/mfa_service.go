@@ -0,0 +1,295 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	// 端末の時刻ずれを前後1ステップ（30秒）まで許容
+	totpSkew = 1
+)
+
+type MFAService interface {
+	SetupTOTP(userID uint) (secret string, uri string, err error)
+	EnableTOTP(userID uint, code string) ([]string, error)
+	DisableTOTP(userID uint, password, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	CompleteLogin(mfaToken, code, ipAddress, userAgent string) (*model.LoginResult, error)
+}
+
+type mfaService struct {
+	userRepo         repository.UserRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	loginAttemptRepo repository.LoginAttemptRepository
+	tokenService     TokenService
+	loginThrottle    LoginThrottle
+	issuer           string
+}
+
+func NewMFAService(
+	userRepo repository.UserRepository,
+	recoveryCodeRepo repository.RecoveryCodeRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	tokenService TokenService,
+	loginThrottle LoginThrottle,
+	issuer string,
+) MFAService {
+	return &mfaService{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		tokenService:     tokenService,
+		loginThrottle:    loginThrottle,
+		issuer:           issuer,
+	}
+}
+
+// TOTP登録開始（シークレット生成。コードを確認するまでは有効化しない）
+func (s *mfaService) SetupTOTP(userID uint) (string, string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if user.TOTPEnabled {
+		return "", "", errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	user.TOTPSecret = secret
+	if err := s.userRepo.Update(user); err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URI(s.issuer, user.Email, secret), nil
+}
+
+// TOTP有効化（認証アプリのコードを確認してからリカバリーコードを発行）
+func (s *mfaService) EnableTOTP(userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor authentication setup has not been started")
+	}
+
+	ok, err := s.verifyTOTP(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid verification code")
+	}
+
+	user.TOTPEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return s.generateRecoveryCodes(user.ID)
+}
+
+// TOTP無効化（パスワードと現在のコードの両方を要求）
+func (s *mfaService) DisableTOTP(userID uint, password, code string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := user.CheckPassword(password); err != nil {
+		return errors.New("invalid password")
+	}
+
+	ok, err := s.verifyCode(user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("invalid verification code")
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.recoveryCodeRepo.DeleteByUserID(user.ID)
+}
+
+// リカバリーコードの再発行（既存のコードは全て無効）
+func (s *mfaService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	ok, err := s.verifyTOTP(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid verification code")
+	}
+
+	return s.generateRecoveryCodes(user.ID)
+}
+
+// ログインの2段階目（MFAトークンとTOTPコードまたはリカバリーコードを検証）
+func (s *mfaService) CompleteLogin(mfaToken, code, ipAddress, userAgent string) (*model.LoginResult, error) {
+	claims, err := s.tokenService.ParseMFAToken(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := &model.LoginAttempt{
+		UserID:    &claims.UserID,
+		Email:     normalizeEmail(claims.Email),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+
+	if err := s.loginThrottle.Check(claims.Email, ipAddress); err != nil {
+		attempt.Reason = "throttled"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	if user.IsLocked() {
+		attempt.Reason = "locked"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+		return nil, errors.New("account is temporarily locked")
+	}
+
+	ok, err := s.verifyCode(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		attempt.Reason = "invalid_mfa_code"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+
+		lockedUntil, err := s.loginThrottle.RegisterFailure(claims.Email, ipAddress)
+		if err != nil {
+			return nil, err
+		}
+		if lockedUntil != nil {
+			user.LockedUntil = lockedUntil
+			if err := s.userRepo.Update(user); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("account is temporarily locked")
+		}
+		return nil, errors.New("invalid verification code")
+	}
+
+	attempt.Success = true
+	recordLoginAttempt(s.loginAttemptRepo, attempt)
+
+	// MFAトークンは一度しか使えないようにする
+	if err := s.tokenService.Logout(claims); err != nil {
+		return nil, err
+	}
+
+	if err := s.loginThrottle.Reset(user.Email); err != nil {
+		return nil, err
+	}
+	if user.LockedUntil != nil {
+		user.LockedUntil = nil
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user, true)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResult{Tokens: tokens, User: user}, nil
+}
+
+// TOTPコードまたはリカバリーコードを検証
+func (s *mfaService) verifyCode(user *model.User, code string) (bool, error) {
+	ok, err := s.verifyTOTP(user, code)
+	if err != nil || ok {
+		return ok, err
+	}
+	return s.recoveryCodeRepo.Consume(user.ID, hashToken(normalizeRecoveryCode(code)))
+}
+
+// TOTPコードを検証（同じタイムステップのコードは再利用不可）
+// 同時に同じコードで検証しても、タイムステップを使用済みにできた1件だけを受け付ける
+func (s *mfaService) verifyTOTP(user *model.User, code string) (bool, error) {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+
+	used, err := s.userRepo.UseTOTPStep(user.ID, step)
+	if err != nil || !used {
+		return false, err
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+// リカバリーコードを生成（平文は発行時の一度だけ返す）
+func (s *mfaService) generateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]model.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(raw[:4] + "-" + raw[4:8] + "-" + raw[8:12])
+		codes = append(codes, code)
+		records = append(records, model.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(userID, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// 区切り文字と大文字小文字の違いを無視する
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
\ No newline at end of file
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/pkg/totp"
+)
+
+// 同じコードで同時に検証しても1件だけを受け付ける
+func TestVerifyTOTPRejectsConcurrentReplay(t *testing.T) {
+	const requests = 20
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &fakeUserRepository{users: map[uint]*model.User{
+		1: {ID: 1, Email: "user@example.com", TOTPSecret: secret, TOTPEnabled: true},
+	}}
+	s := &mfaService{userRepo: users}
+
+	code, err := totp.GenerateCode(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	start := make(chan struct{})
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// リクエストごとに読み込んだユーザー（どれも使用済みのステップを知らない）
+			user, err := users.GetByID(1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			<-start
+			ok, err := s.verifyTOTP(user, code)
+			if err != nil {
+				t.Errorf("verifyTOTP() error = %v", err)
+			}
+			if ok {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if accepted != 1 {
+		t.Errorf("accepted codes = %d, want 1", accepted)
+	}
+
+	// ユーザーの更新で使用済みのステップを古い値に戻さない
+	user, err := users.GetByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.TOTPLastStep = 0
+	if err := users.Update(user); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.verifyTOTP(user, code); ok || err != nil {
+		t.Errorf("verifyTOTP() after Update = %v, %v, want rejected", ok, err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/oidc"
+)
+
+const (
+	oauthStateTTL  = 10 * time.Minute
+	oauthIOTimeout = 15 * time.Second
+)
+
+// ErrAccountLinkRequired 同じメールアドレスのアカウントが存在し、自動では紐付けられない場合のエラー
+var ErrAccountLinkRequired = errors.New("an account with this email already exists; sign in and link the provider from your account settings")
+
+type OAuthService interface {
+	Providers() []string
+	AuthorizationURL(provider string, linkUserID *uint) (string, error)
+	Login(provider, code, state, ipAddress, userAgent string) (*model.LoginResult, error)
+	Link(userID uint, provider, code, state string) (*model.Identity, error)
+	ListIdentities(userID uint) ([]model.Identity, error)
+	Unlink(userID, identityID uint) error
+}
+
+type oauthService struct {
+	providers        *oidc.Registry
+	userRepo         repository.UserRepository
+	identityRepo     repository.IdentityRepository
+	oauthStateRepo   repository.OAuthStateRepository
+	loginAttemptRepo repository.LoginAttemptRepository
+	tokenService     TokenService
+}
+
+func NewOAuthService(
+	providers *oidc.Registry,
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	oauthStateRepo repository.OAuthStateRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	tokenService TokenService,
+) OAuthService {
+	return &oauthService{
+		providers:        providers,
+		userRepo:         userRepo,
+		identityRepo:     identityRepo,
+		oauthStateRepo:   oauthStateRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		tokenService:     tokenService,
+	}
+}
+
+func (s *oauthService) Providers() []string {
+	return s.providers.Names()
+}
+
+// 認可URLの生成（state・nonce・PKCEのベリファイアをサーバー側に保存）
+func (s *oauthService) AuthorizationURL(providerName string, linkUserID *uint) (string, error) {
+	provider, ok := s.providers.Get(providerName)
+	if !ok {
+		return "", errors.New("unknown provider")
+	}
+
+	if err := s.oauthStateRepo.DeleteExpired(); err != nil {
+		log.Println("Warning: failed to delete expired oauth states:", err)
+	}
+
+	state, err := oidc.NewState()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.NewState()
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	record := &model.OAuthState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		UserID:       linkUserID,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if err := s.oauthStateRepo.Create(record); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oauthIOTimeout)
+	defer cancel()
+
+	return provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+}
+
+// ソーシャルログイン（未登録の場合はユーザーを作成）
+func (s *oauthService) Login(providerName, code, state, ipAddress, userAgent string) (*model.LoginResult, error) {
+	claims, err := s.exchange(providerName, code, state, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := &model.LoginAttempt{
+		Email:     normalizeEmail(claims.Email),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+
+	user, err := s.resolveUser(providerName, claims)
+	if err != nil {
+		attempt.Reason = "oidc_" + providerName + "_rejected"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+		return nil, err
+	}
+	attempt.UserID = &user.ID
+	attempt.Email = user.Email
+
+	if user.IsLocked() {
+		attempt.Reason = "locked"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+		return nil, errors.New("account is temporarily locked")
+	}
+
+	// 2段階認証が有効な場合はパスワードログインと同様にMFAトークンを返す
+	if user.TOTPEnabled {
+		attempt.Reason = "mfa_required"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+
+		mfaToken, err := s.tokenService.IssueMFAToken(user)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	attempt.Success = true
+	attempt.Reason = "oidc_" + providerName
+	recordLoginAttempt(s.loginAttemptRepo, attempt)
+
+	tokens, err := s.tokenService.IssueTokens(user, false)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResult{Tokens: tokens, User: user}, nil
+}
+
+// ログイン中のユーザーに外部アカウントを紐付け
+func (s *oauthService) Link(userID uint, providerName, code, state string) (*model.Identity, error) {
+	claims, err := s.exchange(providerName, code, state, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.identityRepo.GetByProviderSubject(providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.UserID == userID {
+			return existing, nil
+		}
+		return nil, errors.New("this account is already linked to another user")
+	}
+
+	identity := &model.Identity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := s.identityRepo.Create(identity); err != nil {
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (s *oauthService) ListIdentities(userID uint) ([]model.Identity, error) {
+	return s.identityRepo.ListByUserID(userID)
+}
+
+// 紐付け解除（ログイン手段が無くなる場合は拒否）
+func (s *oauthService) Unlink(userID, identityID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Password == "" {
+		count, err := s.identityRepo.CountByUserID(userID)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return errors.New("cannot unlink the last sign-in method; set a password first")
+		}
+	}
+
+	deleted, err := s.identityRepo.Delete(userID, identityID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("identity not found")
+	}
+
+	return nil
+}
+
+// stateを検証し、認可コードをIDトークンに交換して検証済みのクレームを返す
+func (s *oauthService) exchange(providerName, code, state string, linkUserID *uint) (*oidc.Claims, error) {
+	provider, ok := s.providers.Get(providerName)
+	if !ok {
+		return nil, errors.New("unknown provider")
+	}
+
+	record, err := s.oauthStateRepo.Consume(hashToken(state))
+	if err != nil {
+		return nil, errors.New("invalid or expired state")
+	}
+	if record.Provider != providerName || time.Now().After(record.ExpiresAt) {
+		return nil, errors.New("invalid or expired state")
+	}
+
+	// ログイン用のstateを連携に、連携用のstateをログインに使い回せないようにする
+	switch {
+	case linkUserID == nil && record.UserID != nil,
+		linkUserID != nil && (record.UserID == nil || *record.UserID != *linkUserID):
+		return nil, errors.New("invalid or expired state")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oauthIOTimeout)
+	defer cancel()
+
+	token, err := provider.Exchange(ctx, code, record.CodeVerifier)
+	if err != nil {
+		log.Println("Warning: oidc token exchange failed:", err)
+		return nil, errors.New("failed to sign in with provider")
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, record.Nonce)
+	if err != nil {
+		log.Println("Warning: oidc id_token verification failed:", err)
+		return nil, errors.New("failed to sign in with provider")
+	}
+
+	return claims, nil
+}
+
+// 外部アカウントに対応するユーザーを取得（必要に応じて紐付け・作成）
+func (s *oauthService) resolveUser(providerName string, claims *oidc.Claims) (*model.User, error) {
+	identity, err := s.identityRepo.GetByProviderSubject(providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		return s.userRepo.GetByID(identity.UserID)
+	}
+
+	email := normalizeEmail(claims.Email)
+	if email == "" {
+		return nil, errors.New("the provider did not return an email address")
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err == nil {
+		// 既存アカウントへの自動紐付けは双方でメールアドレスが確認済みの場合のみ（なりすまし防止）
+		if !bool(claims.EmailVerified) || !user.IsEmailVerified() {
+			return nil, ErrAccountLinkRequired
+		}
+	} else {
+		user = &model.User{
+			Email: email,
+			Name:  displayName(claims.Name, email),
+			Role:  "customer",
+		}
+		if claims.EmailVerified {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		// パスワードは未設定（パスワードリセットで設定できる）
+		if err := s.userRepo.Create(user); err != nil {
+			return nil, err
+		}
+	}
+
+	identity = &model.Identity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    email,
+	}
+	if err := s.identityRepo.Create(identity); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// 表示名が無い場合はメールアドレスのローカル部を使用
+func displayName(name, email string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	if i := strings.Index(email, "@"); i > 0 {
+		return email[:i]
+	}
+	return email
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/oidc"
+	"github.com/Naonao3/EC-site/backend/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type fakeOAuthStateRepository struct {
+	mu     sync.Mutex
+	states map[string]*model.OAuthState
+}
+
+func (r *fakeOAuthStateRepository) Create(state *model.OAuthState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *state
+	r.states[state.StateHash] = &copied
+	return nil
+}
+
+func (r *fakeOAuthStateRepository) Consume(stateHash string) (*model.OAuthState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.states[stateHash]
+	if !ok {
+		return nil, errors.New("oauth state not found")
+	}
+	delete(r.states, stateHash)
+	return state, nil
+}
+
+func (r *fakeOAuthStateRepository) DeleteExpired() error {
+	return nil
+}
+
+// 保存済みのstateの有効期限を過去にする
+func (r *fakeOAuthStateRepository) expireAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, state := range r.states {
+		state.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
+
+type fakeIdentityRepository struct {
+	repository.IdentityRepository
+	mu         sync.Mutex
+	identities []model.Identity
+}
+
+func (r *fakeIdentityRepository) Create(identity *model.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identity.ID = uint(len(r.identities) + 1)
+	r.identities = append(r.identities, *identity)
+	return nil
+}
+
+func (r *fakeIdentityRepository) GetByProviderSubject(provider, subject string) (*model.Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			copied := identity
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+type fakeLoginAttemptRepository struct {
+	repository.LoginAttemptRepository
+}
+
+func (r *fakeLoginAttemptRepository) Create(attempt *model.LoginAttempt) error {
+	return nil
+}
+
+type oauthServiceFixture struct {
+	service *oauthService
+	issuer  *oidctest.Issuer
+	states  *fakeOAuthStateRepository
+	userID  uint
+}
+
+func newOAuthServiceFixture(t *testing.T) *oauthServiceFixture {
+	t.Helper()
+	issuer, err := oidctest.NewIssuer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(issuer.Close)
+
+	// 同じ発行者を使う2つのプロバイダー（プロバイダーの取り違えの確認用）
+	providers := oidc.NewRegistry()
+	for _, name := range []string{"test", "other"} {
+		providers.Register(oidc.NewProvider(oidc.Config{
+			Name:        name,
+			Issuer:      issuer.URL,
+			ClientID:    "client-id",
+			RedirectURL: "http://localhost:3000/callback",
+		}))
+	}
+
+	user := &model.User{ID: 1, Email: "user@example.com", Name: "User"}
+	f := &oauthServiceFixture{
+		issuer: issuer,
+		states: &fakeOAuthStateRepository{states: make(map[string]*model.OAuthState)},
+		userID: user.ID,
+	}
+	f.service = NewOAuthService(
+		providers,
+		&fakeUserRepository{users: map[uint]*model.User{user.ID: user}},
+		&fakeIdentityRepository{},
+		f.states,
+		&fakeLoginAttemptRepository{},
+		&fakeTokenService{},
+	).(*oauthService)
+	return f
+}
+
+// 認可URLを生成し、URLに含まれるstateとnonceを返す
+func (f *oauthServiceFixture) authorize(t *testing.T, provider string, linkUserID *uint) (string, string) {
+	t.Helper()
+	rawURL, err := f.service.AuthorizationURL(provider, linkUserID)
+	if err != nil {
+		t.Fatalf("AuthorizationURL() error = %v", err)
+	}
+	authURL, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	return query.Get("state"), query.Get("nonce")
+}
+
+func TestOAuthCallbackState(t *testing.T) {
+	userID := uint(1)
+	otherUserID := uint(2)
+
+	tests := []struct {
+		name string
+		// 認可URLを生成したプロバイダーと連携するユーザー
+		authorizeProvider string
+		authorizeUserID   *uint
+		// コールバックを受けたユーザー（nilの場合はログイン）
+		callbackUserID *uint
+		// コールバックの前の操作（使用するstateを返す）
+		prepare func(t *testing.T, f *oauthServiceFixture, state string) string
+		// IDトークンのnonce
+		nonce   func(nonce string) string
+		wantErr string
+	}{
+		{
+			name:              "login",
+			authorizeProvider: "test",
+		},
+		{
+			name:              "link",
+			authorizeProvider: "test",
+			authorizeUserID:   &userID,
+			callbackUserID:    &userID,
+		},
+		{
+			name:              "unknown state",
+			authorizeProvider: "test",
+			prepare:           func(t *testing.T, f *oauthServiceFixture, state string) string { return "unknown" },
+			wantErr:           "invalid or expired state",
+		},
+		{
+			name:              "reused state",
+			authorizeProvider: "test",
+			prepare: func(t *testing.T, f *oauthServiceFixture, state string) string {
+				if _, err := f.states.Consume(hashToken(state)); err != nil {
+					t.Fatal(err)
+				}
+				return state
+			},
+			wantErr: "invalid or expired state",
+		},
+		{
+			name:              "expired state",
+			authorizeProvider: "test",
+			prepare: func(t *testing.T, f *oauthServiceFixture, state string) string {
+				f.states.expireAll()
+				return state
+			},
+			wantErr: "invalid or expired state",
+		},
+		{
+			name:              "state issued for another provider",
+			authorizeProvider: "other",
+			wantErr:           "invalid or expired state",
+		},
+		{
+			name:              "login state used for link",
+			authorizeProvider: "test",
+			callbackUserID:    &userID,
+			wantErr:           "invalid or expired state",
+		},
+		{
+			name:              "link state used for login",
+			authorizeProvider: "test",
+			authorizeUserID:   &userID,
+			wantErr:           "invalid or expired state",
+		},
+		{
+			name:              "link state of another user",
+			authorizeProvider: "test",
+			authorizeUserID:   &otherUserID,
+			callbackUserID:    &userID,
+			wantErr:           "invalid or expired state",
+		},
+		{
+			name:              "nonce of another request",
+			authorizeProvider: "test",
+			nonce:             func(nonce string) string { return "other-nonce" },
+			wantErr:           "failed to sign in with provider",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOAuthServiceFixture(t)
+			state, nonce := f.authorize(t, tt.authorizeProvider, tt.authorizeUserID)
+			if tt.prepare != nil {
+				state = tt.prepare(t, f, state)
+			}
+			if tt.nonce != nil {
+				nonce = tt.nonce(nonce)
+			}
+
+			now := time.Now()
+			f.issuer.IssueCode("code", jwt.MapClaims{
+				"iss":            f.issuer.URL,
+				"sub":            "subject",
+				"aud":            "client-id",
+				"iat":            now.Unix(),
+				"exp":            now.Add(time.Hour).Unix(),
+				"nonce":          nonce,
+				"email":          "new@example.com",
+				"email_verified": true,
+			})
+
+			var err error
+			if tt.callbackUserID == nil {
+				var result *model.LoginResult
+				result, err = f.service.Login("test", "code", state, "127.0.0.1", "test")
+				if err == nil && result.Tokens == nil {
+					t.Error("Login() returned no tokens")
+				}
+			} else {
+				var identity *model.Identity
+				identity, err = f.service.Link(*tt.callbackUserID, "test", "code", state)
+				if err == nil && identity.UserID != *tt.callbackUserID {
+					t.Errorf("Link() identity user = %d, want %d", identity.UserID, *tt.callbackUserID)
+				}
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("callback error = %v", err)
+				}
+				if f.issuer.CodeVerifier("code") == "" {
+					t.Error("code_verifier was not sent to the token endpoint")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("callback error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -2,6 +2,8 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 
 	"github.com/Naonao3/EC-site/backend/internal/model"
 	"github.com/Naonao3/EC-site/backend/internal/repository"
@@ -9,123 +11,221 @@ import (
 )
 
 type OrderService interface {
-	CreateOrder(userID uint, db *gorm.DB) (*model.Order, error)
+	CreateOrder(userID uint, addressID *uint, db *gorm.DB) (*model.Order, error)
 	GetOrderByID(userID, orderID uint) (*model.Order, error)
 	GetUserOrders(userID uint, page, pageSize int) ([]model.Order, int64, error)
 	GetAllOrders(page, pageSize int) ([]model.Order, int64, error)
-	UpdateOrderStatus(orderID uint, status string) error
+	UpdateOrderStatus(orderID uint, status string, db *gorm.DB) error
 }
 
 type orderService struct {
-	orderRepo   repository.OrderRepository
-	cartRepo    repository.CartRepository
-	productRepo repository.ProductRepository
+	orderRepo                repository.OrderRepository
+	cartRepo                 repository.CartRepository
+	productRepo              repository.ProductRepository
+	userRepo                 repository.UserRepository
+	addressRepo              repository.AddressRepository
+	inventoryService         InventoryService
+	warehouseService         WarehouseService
+	couponService            CouponService
+	requireEmailVerification bool
 }
 
 func NewOrderService(
 	orderRepo repository.OrderRepository,
 	cartRepo repository.CartRepository,
 	productRepo repository.ProductRepository,
+	userRepo repository.UserRepository,
+	addressRepo repository.AddressRepository,
+	inventoryService InventoryService,
+	warehouseService WarehouseService,
+	couponService CouponService,
+	requireEmailVerification bool,
 ) OrderService {
 	return &orderService{
-		orderRepo:   orderRepo,
-		cartRepo:    cartRepo,
-		productRepo: productRepo,
+		orderRepo:                orderRepo,
+		cartRepo:                 cartRepo,
+		productRepo:              productRepo,
+		userRepo:                 userRepo,
+		addressRepo:              addressRepo,
+		inventoryService:         inventoryService,
+		warehouseService:         warehouseService,
+		couponService:            couponService,
+		requireEmailVerification: requireEmailVerification,
 	}
 }
 
 // 注文作成（トランザクション処理）
-func (s *orderService) CreateOrder(userID uint, db *gorm.DB) (*model.Order, error) {
-	// トランザクション開始
-	tx := db.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
-	}
-
-	// エラー時のロールバック
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+// addressIDを省略した場合はデフォルトの住所を配送先にする
+func (s *orderService) CreateOrder(userID uint, addressID *uint, db *gorm.DB) (*model.Order, error) {
+	// メールアドレス未確認ユーザーの注文をブロック（設定で有効な場合のみ）
+	if s.requireEmailVerification {
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !user.IsEmailVerified() {
+			return nil, errors.New("email verification required")
 		}
-	}()
+	}
 
-	// カートアイテム取得
-	cartItems, err := s.cartRepo.GetByUserID(userID)
+	// 配送先住所の取得
+	var address *model.Address
+	var err error
+	if addressID != nil {
+		address, err = s.addressRepo.GetByID(userID, *addressID)
+	} else {
+		address, err = s.addressRepo.GetDefault(userID)
+	}
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
-	if len(cartItems) == 0 {
-		tx.Rollback()
-		return nil, errors.New("cart is empty")
-	}
+	// カートの読み取りから在庫の確保・カートのクリアまでを1つのトランザクションで実行
+	// （途中で失敗した場合は確保した在庫も含めてロールバックされる）
+	var orderID uint
+	err = db.Transaction(func(tx *gorm.DB) error {
+		cartRepo := s.cartRepo.WithTx(tx)
+		productRepo := s.productRepo.WithTx(tx)
 
-	// 注文作成
-	order := &model.Order{
-		UserID:      userID,
-		TotalAmount: 0,
-		Status:      "pending",
-	}
+		// カートアイテム取得
+		cartItems, err := cartRepo.GetByUserID(userID)
+		if err != nil {
+			return err
+		}
+
+		if len(cartItems) == 0 {
+			return errors.New("cart is empty")
+		}
 
-	// 注文明細を作成し、合計金額を計算
-	var orderItems []model.OrderItem
-	totalAmount := 0.0
+		// 同じ商品を含む注文同士がデッドロックしないよう、商品IDの順に在庫の行ロックを取得（出荷元の決定と在庫の確保）
+		sort.SliceStable(cartItems, func(i, j int) bool { return cartItems[i].ProductID < cartItems[j].ProductID })
 
-	for _, cartItem := range cartItems {
-		// 商品取得
-		product, err := s.productRepo.GetByID(cartItem.ProductID)
-		if err != nil {
-			tx.Rollback()
-			return nil, err
+		// 注文作成
+		order := &model.Order{
+			UserID:      userID,
+			TotalAmount: 0,
+			Status:      "pending",
 		}
 
-		// 在庫確認
-		if product.Stock < cartItem.Quantity {
-			tx.Rollback()
-			return nil, errors.New("insufficient stock for product: " + product.Name)
+		// 住所録の変更が過去の注文に影響しないよう、注文時点の住所をコピーして保存
+		if address != nil {
+			order.ShippingAddressID = &address.ID
+			order.ShippingDetails = address.Snapshot()
+			order.ShippingAddress = order.ShippingDetails.String()
 		}
 
-		// 在庫減少
-		if err := s.productRepo.UpdateStock(product.ID, -cartItem.Quantity); err != nil {
-			tx.Rollback()
-			return nil, err
+		// 注文明細を作成し、値引きの計算に使う明細を集計
+		var orderItems []model.OrderItem
+		var lines []model.DiscountLine
+
+		for _, cartItem := range cartItems {
+			// 商品取得（在庫は読み取った値では確認せず、出荷元の決定と確保の条件付き更新で確認する）
+			product, err := productRepo.GetByID(cartItem.ProductID)
+			if err != nil {
+				return err
+			}
+
+			variant, err := resolveVariant(product, cartItem.VariantID)
+			if err != nil {
+				return err
+			}
+
+			license, err := resolveLicense(product, cartItem.LicenseID)
+			if err != nil {
+				return err
+			}
+
+			// 注文明細作成（単価はカートと同じ規則で決める）
+			price, err := model.ItemPrice(product, variant, license)
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, product.Name)
+			}
+			orderItem := model.OrderItem{
+				ProductID: cartItem.ProductID,
+				Quantity:  cartItem.Quantity,
+			}
+			if variant != nil {
+				orderItem.VariantID = &variant.ID
+				orderItem.SKU = variant.SKU
+				orderItem.VariantName = variant.Name
+			}
+			// ライセンスの種類・利用条件は証明書の発行に使うため注文時点の内容を保存
+			if license != nil {
+				orderItem.LicenseID = &license.ID
+				orderItem.LicenseType = license.Type
+				orderItem.LicenseTerms = license.Terms
+			}
+			orderItem.Price = price
+			orderItems = append(orderItems, orderItem)
+
+			lines = append(lines, model.DiscountLine{
+				ProductID:  product.ID,
+				CategoryID: product.CategoryID,
+				Amount:     price * float64(cartItem.Quantity),
+			})
 		}
 
-		// 注文明細作成
-		orderItem := model.OrderItem{
-			ProductID: cartItem.ProductID,
-			Quantity:  cartItem.Quantity,
-			Price:     product.Price,
+		// カートに適用中のクーポン（同じクーポンを使う注文の利用回数の確認はコミットまで待たせる）
+		coupons := s.couponService.WithTx(tx)
+		coupon, err := coupons.CartCouponForUpdate(userID)
+		if err != nil {
+			return err
 		}
-		orderItems = append(orderItems, orderItem)
 
-		// 合計金額計算
-		totalAmount += product.Price * float64(cartItem.Quantity)
-	}
+		// 合計金額計算（値引きの内訳は注文時点の内容をコピーして保存）
+		pricing, err := coupons.Price(lines, coupon, &userID)
+		if err != nil {
+			return fmt.Errorf("coupon %s cannot be used: %w", coupon.Code, err)
+		}
+		order.ShippingFee = pricing.ShippingFee
+		order.DiscountAmount = pricing.Discount
+		order.TotalAmount = pricing.Total
+		order.Discounts = pricing.Discounts
+		order.OrderItems = orderItems
+
+		// 注文を保存
+		if err := s.orderRepo.WithTx(tx).Create(order); err != nil {
+			return err
+		}
 
-	order.TotalAmount = totalAmount
-	order.OrderItems = orderItems
+		// 配送先に近い倉庫から出荷元を決める
+		allocations, err := s.warehouseService.WithTx(tx).Allocate(order.OrderItems, order.ShippingDetails.Prefecture)
+		if err != nil {
+			return err
+		}
 
-	// 注文を保存
-	if err := tx.Create(order).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
+		// 決済期限まで出荷元の倉庫の在庫を確保（在庫は決済完了時に減らす。確保数の更新は在庫が足りる場合のみ行う）
+		if err := s.inventoryService.WithTx(tx).ReserveOrder(order, allocations); err != nil {
+			return err
+		}
 
-	// カートをクリア
-	if err := s.cartRepo.DeleteByUserID(userID); err != nil {
-		tx.Rollback()
-		return nil, err
-	}
+		// クーポンの利用を記録（全体の利用回数の上限を超える場合は注文を作成しない）
+		if coupon != nil {
+			if err := coupons.Redeem(coupon, userID, order.ID, pricing.Discount); err != nil {
+				return err
+			}
+		}
+
+		// カートをクリア
+		if err := cartRepo.DeleteByUserID(userID); err != nil {
+			return err
+		}
+		if err := coupons.RemoveCartCoupon(userID); err != nil {
+			return err
+		}
 
-	// トランザクションコミット
-	if err := tx.Commit().Error; err != nil {
+		orderID = order.ID
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	// 確保で購入できる数が減った商品の発注点を確認
+	s.inventoryService.OrderStockChanged(orderID)
+
 	// 注文の完全な情報を取得して返す
-	return s.orderRepo.GetByID(order.ID)
+	return s.orderRepo.GetByID(orderID)
 }
 
 // 注文詳細取得
@@ -167,8 +267,16 @@ func (s *orderService) GetAllOrders(page, pageSize int) ([]model.Order, int64, e
 	return s.orderRepo.List(page, pageSize)
 }
 
+// 管理者が変更できる注文ステータスの遷移
+// キャンセル済み・配達済みの注文は変更できず、出荷後はキャンセルできない（在庫を二重に戻したり、出荷した在庫を戻したりしないため）
+var orderStatusTransitions = map[string]map[string]bool{
+	"pending":   {"confirmed": true, "shipped": true, "delivered": true, "cancelled": true},
+	"confirmed": {"shipped": true, "delivered": true, "cancelled": true},
+	"shipped":   {"delivered": true},
+}
+
 // 注文ステータス更新
-func (s *orderService) UpdateOrderStatus(orderID uint, status string) error {
+func (s *orderService) UpdateOrderStatus(orderID uint, status string, db *gorm.DB) error {
 	order, err := s.orderRepo.GetByID(orderID)
 	if err != nil {
 		return err
@@ -186,7 +294,46 @@ func (s *orderService) UpdateOrderStatus(orderID uint, status string) error {
 	if !validStatuses[status] {
 		return errors.New("invalid status")
 	}
+	if order.Status == status {
+		return nil
+	}
+	if !orderStatusTransitions[order.Status][status] {
+		return fmt.Errorf("cannot change order status from %s to %s", order.Status, status)
+	}
+
+	// ステータスの更新と在庫・クーポンの処理を1つのトランザクションで実行（途中で失敗した場合はステータスも戻す）
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 期限切れによるキャンセル・決済完了などで同時に変更された場合は更新しない
+		updated, err := s.orderRepo.WithTx(tx).UpdateStatusIf(orderID, order.Status, status)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return errors.New("order status was changed by another process; please retry")
+		}
+
+		// キャンセルされた注文の確保分を解放し（引き当て済みの分は在庫に戻す）、決済済みとして扱う注文は確保分を在庫から引き当て
+		inventory := s.inventoryService.WithTx(tx)
+		if status == "cancelled" {
+			if err := inventory.ReleaseOrder(orderID, model.ReservationReleaseCancelled); err != nil {
+				return err
+			}
+			if err := inventory.RestockOrder(orderID); err != nil {
+				return err
+			}
+			// キャンセルされた注文で使ったクーポンは利用回数に数えない
+			return s.couponService.WithTx(tx).ReleaseOrder(orderID)
+		}
+		if paidOrderStatuses[status] {
+			return inventory.CommitOrder(orderID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-	order.Status = status
-	return s.orderRepo.Update(order)
+	// 在庫が変わった商品の発注点・入荷通知を確認
+	s.inventoryService.OrderStockChanged(orderID)
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 実際のPostgreSQLを使うテスト（TEST_DATABASE_URLが未設定の場合はスキップ）
+// テストごとにスキーマを作成し、終了時に削除する
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(
+		&model.User{},
+		&model.Product{},
+		&model.Order{},
+		&model.OrderItem{},
+		&model.CartItem{},
+		&model.Permission{},
+		&model.Role{},
+		&model.Address{},
+		&model.Category{},
+		&model.ProductOption{},
+		&model.ProductVariant{},
+		&model.ProductImage{},
+		&model.ProductLicense{},
+		&model.InventoryReservation{},
+		&model.StockMovement{},
+		&model.Warehouse{},
+		&model.WarehouseStock{},
+		&model.Coupon{},
+		&model.CouponProduct{},
+		&model.CouponCategory{},
+		&model.CouponRedemption{},
+		&model.CartCoupon{},
+		&model.OrderDiscount{},
+		&model.Payment{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type noopStockNotifications struct {
+	StockNotificationService
+}
+
+func (noopStockNotifications) ProductStockChanged(productIDs ...uint) {}
+
+func newTestCouponService(db *gorm.DB) CouponService {
+	return NewCouponService(repository.NewCouponRepository(db), repository.NewProductRepository(db), repository.NewCategoryRepository(db), 0)
+}
+
+func newTestInventoryService(db *gorm.DB) InventoryService {
+	return NewInventoryService(repository.NewReservationRepository(db), repository.NewOrderRepository(db), time.Hour, noopStockNotifications{}, newTestCouponService(db))
+}
+
+func newTestOrderService(db *gorm.DB) OrderService {
+	orderRepo := repository.NewOrderRepository(db)
+	productRepo := repository.NewProductRepository(db)
+	reservationRepo := repository.NewReservationRepository(db)
+	couponService := newTestCouponService(db)
+	inventoryService := newTestInventoryService(db)
+	warehouseService := NewWarehouseService(repository.NewWarehouseRepository(db), repository.NewStockMovementRepository(db), productRepo, orderRepo, reservationRepo)
+	return NewOrderService(orderRepo, repository.NewCartRepository(db), productRepo, repository.NewUserRepository(db),
+		repository.NewAddressRepository(db), inventoryService, warehouseService, couponService, false)
+}
+
+// 同じ商品を同時に注文しても在庫を超えて確保しない
+func TestCreateOrderConcurrentCheckout(t *testing.T) {
+	const (
+		stock     = 5
+		customers = 20
+	)
+
+	tests := []struct {
+		name        string
+		withVariant bool
+	}{
+		{name: "product stock"},
+		{name: "variant stock", withVariant: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+
+			warehouse := &model.Warehouse{Code: "TOKYO", Name: "Tokyo", Prefecture: "東京都", Active: true, IsDefault: true}
+			mustCreate(t, db, warehouse)
+			product := &model.Product{Name: "Limited", Price: 1000, Stock: stock}
+			mustCreate(t, db, product)
+
+			var variantID *uint
+			if tt.withVariant {
+				variant := &model.ProductVariant{ProductID: product.ID, SKU: "LIMITED-L", Name: "L", Price: 1200, Stock: stock}
+				mustCreate(t, db, variant)
+				variantID = &variant.ID
+			}
+			mustCreate(t, db, &model.WarehouseStock{WarehouseID: warehouse.ID, ProductID: product.ID, VariantID: variantID, Stock: stock})
+
+			userIDs := make([]uint, customers)
+			for i := range userIDs {
+				user := &model.User{Email: fmt.Sprintf("customer%d@example.com", i), Password: "x", Name: "Customer"}
+				mustCreate(t, db, user)
+				mustCreate(t, db, &model.CartItem{UserID: user.ID, ProductID: product.ID, VariantID: variantID, Quantity: 1})
+				userIDs[i] = user.ID
+			}
+
+			orders := newTestOrderService(db)
+			var (
+				wg        sync.WaitGroup
+				mu        sync.Mutex
+				succeeded int
+			)
+			start := make(chan struct{})
+			for _, userID := range userIDs {
+				wg.Add(1)
+				go func(userID uint) {
+					defer wg.Done()
+					<-start
+					_, err := orders.CreateOrder(userID, nil, db)
+					mu.Lock()
+					defer mu.Unlock()
+					if err == nil {
+						succeeded++
+					} else if !strings.Contains(err.Error(), "insufficient stock") {
+						t.Errorf("CreateOrder() unexpected error = %v", err)
+					}
+				}(userID)
+			}
+			close(start)
+			wg.Wait()
+
+			if succeeded != stock {
+				t.Errorf("succeeded orders = %d, want %d", succeeded, stock)
+			}
+
+			var reloaded model.Product
+			if err := db.First(&reloaded, product.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if reloaded.Reserved != stock || reloaded.Stock-reloaded.Reserved < 0 {
+				t.Errorf("product stock = %d, reserved = %d, want reserved %d", reloaded.Stock, reloaded.Reserved, stock)
+			}
+
+			var warehouseStock model.WarehouseStock
+			if err := db.Where("warehouse_id = ? AND product_id = ?", warehouse.ID, product.ID).First(&warehouseStock).Error; err != nil {
+				t.Fatal(err)
+			}
+			if warehouseStock.Reserved != stock || warehouseStock.Stock-warehouseStock.Reserved < 0 {
+				t.Errorf("warehouse stock = %d, reserved = %d, want reserved %d", warehouseStock.Stock, warehouseStock.Reserved, stock)
+			}
+
+			if variantID != nil {
+				var variant model.ProductVariant
+				if err := db.First(&variant, *variantID).Error; err != nil {
+					t.Fatal(err)
+				}
+				if variant.Reserved != stock || variant.Stock-variant.Reserved < 0 {
+					t.Errorf("variant stock = %d, reserved = %d, want reserved %d", variant.Stock, variant.Reserved, stock)
+				}
+			}
+		})
+	}
+}
+
+// 利用回数に上限のあるクーポンを同時に使っても上限を超えて利用しない
+func TestCreateOrderConcurrentCouponRedemption(t *testing.T) {
+	const (
+		usageLimit = 3
+		customers  = 20
+	)
+
+	db := openTestDB(t)
+
+	warehouse := &model.Warehouse{Code: "TOKYO", Name: "Tokyo", Prefecture: "東京都", Active: true, IsDefault: true}
+	mustCreate(t, db, warehouse)
+	product := &model.Product{Name: "Print", Price: 1000, Stock: customers}
+	mustCreate(t, db, product)
+	mustCreate(t, db, &model.WarehouseStock{WarehouseID: warehouse.ID, ProductID: product.ID, Stock: customers})
+	coupon := &model.Coupon{Code: "LIMITED", Type: model.CouponTypeFixedAmount, Value: 100, UsageLimit: usageLimit, Active: true}
+	mustCreate(t, db, coupon)
+
+	userIDs := make([]uint, customers)
+	for i := range userIDs {
+		user := &model.User{Email: fmt.Sprintf("customer%d@example.com", i), Password: "x", Name: "Customer"}
+		mustCreate(t, db, user)
+		mustCreate(t, db, &model.CartItem{UserID: user.ID, ProductID: product.ID, Quantity: 1})
+		mustCreate(t, db, &model.CartCoupon{UserID: user.ID, CouponID: coupon.ID})
+		userIDs[i] = user.ID
+	}
+
+	orders := newTestOrderService(db)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	start := make(chan struct{})
+	for _, userID := range userIDs {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			<-start
+			_, err := orders.CreateOrder(userID, nil, db)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else if !strings.Contains(err.Error(), "coupon usage limit reached") {
+				t.Errorf("CreateOrder() unexpected error = %v", err)
+			}
+		}(userID)
+	}
+	close(start)
+	wg.Wait()
+
+	if succeeded != usageLimit {
+		t.Errorf("succeeded orders = %d, want %d", succeeded, usageLimit)
+	}
+
+	var reloaded model.Coupon
+	if err := db.First(&reloaded, coupon.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.UsedCount != usageLimit {
+		t.Errorf("coupon used count = %d, want %d", reloaded.UsedCount, usageLimit)
+	}
+
+	var redemptions int64
+	if err := db.Model(&model.CouponRedemption{}).Where("coupon_id = ? AND status = ?", coupon.ID, model.CouponRedemptionRedeemed).Count(&redemptions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if redemptions != usageLimit {
+		t.Errorf("coupon redemptions = %d, want %d", redemptions, usageLimit)
+	}
+}
+
+func mustCreate(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatal(err)
+	}
+}
\ No newline at end of file
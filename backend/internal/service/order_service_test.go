@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+type fakeOrderRepository struct {
+	repository.OrderRepository
+	order *model.Order
+}
+
+func (r *fakeOrderRepository) GetByID(id uint) (*model.Order, error) {
+	copied := *r.order
+	return &copied, nil
+}
+
+// 拒否する遷移はトランザクションを開始する前に返す（dbはnilで確認）
+func TestUpdateOrderStatusRejectsTransitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr bool
+	}{
+		{name: "cancelled to confirmed", from: "cancelled", to: "confirmed", wantErr: true},
+		{name: "cancelled to pending", from: "cancelled", to: "pending", wantErr: true},
+		{name: "delivered to cancelled", from: "delivered", to: "cancelled", wantErr: true},
+		{name: "shipped to cancelled", from: "shipped", to: "cancelled", wantErr: true},
+		{name: "shipped to confirmed", from: "shipped", to: "confirmed", wantErr: true},
+		{name: "confirmed to pending", from: "confirmed", to: "pending", wantErr: true},
+		{name: "unknown status", from: "pending", to: "refunded", wantErr: true},
+		{name: "unchanged status", from: "cancelled", to: "cancelled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders := &fakeOrderRepository{order: &model.Order{ID: 1, Status: tt.from}}
+			s := &orderService{orderRepo: orders}
+
+			err := s.UpdateOrderStatus(1, tt.to, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateOrderStatus(%s -> %s) error = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+			}
+		})
+	}
+}
\ No newline at end of file
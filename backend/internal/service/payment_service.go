@@ -3,37 +3,48 @@ package service
 import (
 	"errors"
 	"fmt"
+	"log"
 
 	"github.com/Naonao3/EC-site/backend/internal/model"
 	"github.com/Naonao3/EC-site/backend/internal/repository"
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/paymentintent"
+	"gorm.io/gorm"
 )
 
 type PaymentService interface {
 	CreatePaymentIntent(orderID uint, userID uint) (string, error)
-	HandlePaymentSuccess(paymentIntentID string) error
+	HandlePaymentSuccess(paymentIntentID string, db *gorm.DB) error
 	GetPaymentByOrderID(orderID uint) (*model.Payment, error)
 }
 
 type paymentService struct {
-	paymentRepo repository.PaymentRepository
-	orderRepo   repository.OrderRepository
-	stripeKey   string
+	paymentRepo      repository.PaymentRepository
+	orderRepo        repository.OrderRepository
+	inventoryService InventoryService
+	downloadService  DownloadService
+	licenseService   LicenseService
+	stripeKey        string
 }
 
 func NewPaymentService(
 	paymentRepo repository.PaymentRepository,
 	orderRepo repository.OrderRepository,
+	inventoryService InventoryService,
+	downloadService DownloadService,
+	licenseService LicenseService,
 	stripeKey string,
 ) PaymentService {
 	// Stripeの秘密鍵を設定
 	stripe.Key = stripeKey
 
 	return &paymentService{
-		paymentRepo: paymentRepo,
-		orderRepo:   orderRepo,
-		stripeKey:   stripeKey,
+		paymentRepo:      paymentRepo,
+		orderRepo:        orderRepo,
+		inventoryService: inventoryService,
+		downloadService:  downloadService,
+		licenseService:   licenseService,
+		stripeKey:        stripeKey,
 	}
 }
 
@@ -50,6 +61,11 @@ func (s *paymentService) CreatePaymentIntent(orderID uint, userID uint) (string,
 		return "", errors.New("unauthorized")
 	}
 
+	// 決済期限切れでキャンセルされた注文・決済済みの注文は決済できない
+	if order.Status != "pending" {
+		return "", errors.New("order is not awaiting payment")
+	}
+
 	// 既に決済が存在するか確認
 	existingPayment, err := s.paymentRepo.GetByOrderID(orderID)
 	if err != nil {
@@ -103,7 +119,9 @@ func (s *paymentService) CreatePaymentIntent(orderID uint, userID uint) (string,
 }
 
 // 決済成功時の処理
-func (s *paymentService) HandlePaymentSuccess(paymentIntentID string) error {
+// 注文の確定・在庫の引き当て・ダウンロード権の付与・証明書の発行がすべて完了してから決済を成功として記録する
+// （途中で失敗した場合はWebhookの再送で再実行される。各処理は再実行しても二重に行われない）
+func (s *paymentService) HandlePaymentSuccess(paymentIntentID string, db *gorm.DB) error {
 	// Payment取得
 	payment, err := s.paymentRepo.GetByPaymentIntentID(paymentIntentID)
 	if err != nil {
@@ -115,28 +133,58 @@ func (s *paymentService) HandlePaymentSuccess(paymentIntentID string) error {
 	}
 
 	// 既に処理済みの場合はスキップ
-	if payment.Status == "succeeded" {
+	if payment.Status == "succeeded" || payment.Status == "refund_required" {
 		return nil
 	}
 
-	// Payment更新
-	payment.Status = "succeeded"
-	if err := s.paymentRepo.Update(payment); err != nil {
+	// 注文の確定と在庫の引き当てを1つのトランザクションで実行
+	cancelled := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		orders := s.orderRepo.WithTx(tx)
+		confirmed, err := orders.UpdateStatusIf(payment.OrderID, "pending", "confirmed")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			order, err := orders.GetByID(payment.OrderID)
+			if err != nil {
+				return err
+			}
+			// 決済期限切れ・管理者の操作でキャンセルされた注文は確定しない（確保分・クーポンは解放済み）
+			if order.Status == "cancelled" {
+				cancelled = true
+				return nil
+			}
+			// 前回の処理で確定済み、または管理者が先にステータスを進めた注文は引き当てだけを確認する
+		}
+		return s.inventoryService.WithTx(tx).CommitOrder(payment.OrderID)
+	})
+	if err != nil {
 		return err
 	}
 
-	// 注文ステータス更新
-	order, err := s.orderRepo.GetByID(payment.OrderID)
-	if err != nil {
+	if cancelled {
+		log.Printf("Payment %s succeeded for cancelled order %d; refund required", paymentIntentID, payment.OrderID)
+		payment.Status = "refund_required"
+		return s.paymentRepo.Update(payment)
+	}
+
+	// 在庫が変わった商品の発注点・入荷通知を確認
+	s.inventoryService.OrderStockChanged(payment.OrderID)
+
+	// 購入した画像の元データのダウンロード権を付与
+	if err := s.downloadService.GrantForOrder(payment.OrderID); err != nil {
 		return err
 	}
 
-	order.Status = "confirmed"
-	if err := s.orderRepo.Update(order); err != nil {
+	// ライセンス付きの明細に証明書を発行
+	if err := s.licenseService.IssueForOrder(payment.OrderID); err != nil {
 		return err
 	}
 
-	return nil
+	// Payment更新
+	payment.Status = "succeeded"
+	return s.paymentRepo.Update(payment)
 }
 
 // 注文IDで決済取得
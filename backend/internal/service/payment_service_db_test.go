@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+type fakeDownloadService struct {
+	DownloadService
+	// 失敗させる回数
+	failures int
+	granted  []uint
+}
+
+func (s *fakeDownloadService) GrantForOrder(orderID uint) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("storage is unavailable")
+	}
+	s.granted = append(s.granted, orderID)
+	return nil
+}
+
+type fakeLicenseService struct {
+	LicenseService
+	// 失敗させる回数
+	failures int
+	issued   []uint
+}
+
+func (s *fakeLicenseService) IssueForOrder(orderID uint) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("certificate numbering is unavailable")
+	}
+	s.issued = append(s.issued, orderID)
+	return nil
+}
+
+type paymentFixture struct {
+	db        *gorm.DB
+	service   *paymentService
+	downloads *fakeDownloadService
+	licenses  *fakeLicenseService
+	orderID   uint
+	productID uint
+}
+
+const testPaymentIntentID = "pi_test"
+
+// 在庫5の商品を2つ注文し、決済待ちのPaymentを作成
+func newPaymentFixture(t *testing.T) *paymentFixture {
+	t.Helper()
+	db := openTestDB(t)
+
+	warehouse := &model.Warehouse{Code: "TOKYO", Name: "Tokyo", Prefecture: "東京都", Active: true, IsDefault: true}
+	mustCreate(t, db, warehouse)
+	product := &model.Product{Name: "Print", Price: 1000, Stock: 5}
+	mustCreate(t, db, product)
+	mustCreate(t, db, &model.WarehouseStock{WarehouseID: warehouse.ID, ProductID: product.ID, Stock: 5})
+	user := &model.User{Email: "customer@example.com", Password: "x", Name: "Customer"}
+	mustCreate(t, db, user)
+	mustCreate(t, db, &model.CartItem{UserID: user.ID, ProductID: product.ID, Quantity: 2})
+
+	order, err := newTestOrderService(db).CreateOrder(user.ID, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, db, &model.Payment{OrderID: order.ID, StripePaymentIntentID: testPaymentIntentID, Amount: int(order.TotalAmount), Status: "pending"})
+
+	f := &paymentFixture{
+		db:        db,
+		downloads: &fakeDownloadService{},
+		licenses:  &fakeLicenseService{},
+		orderID:   order.ID,
+		productID: product.ID,
+	}
+	f.service = &paymentService{
+		paymentRepo:      repository.NewPaymentRepository(db),
+		orderRepo:        repository.NewOrderRepository(db),
+		inventoryService: newTestInventoryService(db),
+		downloadService:  f.downloads,
+		licenseService:   f.licenses,
+	}
+	return f
+}
+
+func (f *paymentFixture) paymentStatus(t *testing.T) string {
+	t.Helper()
+	var payment model.Payment
+	if err := f.db.Where("order_id = ?", f.orderID).First(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return payment.Status
+}
+
+func (f *paymentFixture) orderStatus(t *testing.T) string {
+	t.Helper()
+	var order model.Order
+	if err := f.db.First(&order, f.orderID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return order.Status
+}
+
+func (f *paymentFixture) product(t *testing.T) model.Product {
+	t.Helper()
+	var product model.Product
+	if err := f.db.First(&product, f.productID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return product
+}
+
+// 決済完了で注文を確定し、確保分を在庫から引き当てる
+func TestHandlePaymentSuccessCommitsOrder(t *testing.T) {
+	f := newPaymentFixture(t)
+
+	if err := f.service.HandlePaymentSuccess(testPaymentIntentID, f.db); err != nil {
+		t.Fatalf("HandlePaymentSuccess() error = %v", err)
+	}
+	// 再送されたWebhookは何もしない
+	if err := f.service.HandlePaymentSuccess(testPaymentIntentID, f.db); err != nil {
+		t.Fatalf("HandlePaymentSuccess() retry error = %v", err)
+	}
+
+	if got := f.orderStatus(t); got != "confirmed" {
+		t.Errorf("order status = %q, want confirmed", got)
+	}
+	if got := f.paymentStatus(t); got != "succeeded" {
+		t.Errorf("payment status = %q, want succeeded", got)
+	}
+	if product := f.product(t); product.Stock != 3 || product.Reserved != 0 {
+		t.Errorf("product stock = %d, reserved = %d, want 3 and 0", product.Stock, product.Reserved)
+	}
+	if len(f.downloads.granted) != 1 || len(f.licenses.issued) != 1 {
+		t.Errorf("grants = %v, certificates = %v, want one each", f.downloads.granted, f.licenses.issued)
+	}
+}
+
+// 決済期限切れでキャンセルされた注文は確定せず、返金が必要な決済として記録する
+func TestHandlePaymentSuccessKeepsCancelledOrder(t *testing.T) {
+	f := newPaymentFixture(t)
+	if err := f.service.inventoryService.ReleaseOrder(f.orderID, model.ReservationReleaseExpired); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.db.Model(&model.Order{}).Where("id = ?", f.orderID).Update("status", "cancelled").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.service.HandlePaymentSuccess(testPaymentIntentID, f.db); err != nil {
+		t.Fatalf("HandlePaymentSuccess() error = %v", err)
+	}
+
+	if got := f.orderStatus(t); got != "cancelled" {
+		t.Errorf("order status = %q, want cancelled", got)
+	}
+	if got := f.paymentStatus(t); got != "refund_required" {
+		t.Errorf("payment status = %q, want refund_required", got)
+	}
+	if product := f.product(t); product.Stock != 5 || product.Reserved != 0 {
+		t.Errorf("product stock = %d, reserved = %d, want 5 and 0", product.Stock, product.Reserved)
+	}
+	if len(f.downloads.granted) != 0 || len(f.licenses.issued) != 0 {
+		t.Errorf("grants = %v, certificates = %v, want none", f.downloads.granted, f.licenses.issued)
+	}
+}
+
+// ダウンロード権の付与に失敗した場合は決済を成功として記録せず、Webhookの再送で付与する
+func TestHandlePaymentSuccessRetriesGrant(t *testing.T) {
+	f := newPaymentFixture(t)
+	f.downloads.failures = 1
+
+	if err := f.service.HandlePaymentSuccess(testPaymentIntentID, f.db); err == nil {
+		t.Fatal("HandlePaymentSuccess() error = nil, want error")
+	}
+	if got := f.paymentStatus(t); got != "pending" {
+		t.Fatalf("payment status after failed grant = %q, want pending", got)
+	}
+
+	if err := f.service.HandlePaymentSuccess(testPaymentIntentID, f.db); err != nil {
+		t.Fatalf("HandlePaymentSuccess() retry error = %v", err)
+	}
+	if got := f.paymentStatus(t); got != "succeeded" {
+		t.Errorf("payment status = %q, want succeeded", got)
+	}
+	if len(f.downloads.granted) != 1 {
+		t.Errorf("grants = %v, want one", f.downloads.granted)
+	}
+	// 再実行しても在庫は二重に引き当てない
+	if product := f.product(t); product.Stock != 3 || product.Reserved != 0 {
+		t.Errorf("product stock = %d, reserved = %d, want 3 and 0", product.Stock, product.Reserved)
+	}
+}
+
+// 証明書の発行に失敗した場合は決済を成功として記録せず、Webhookの再送で発行する
+func TestHandlePaymentSuccessRetriesCertificateIssue(t *testing.T) {
+	f := newPaymentFixture(t)
+	f.licenses.failures = 1
+
+	if err := f.service.HandlePaymentSuccess(testPaymentIntentID, f.db); err == nil {
+		t.Fatal("HandlePaymentSuccess() error = nil, want error")
+	}
+	if got := f.paymentStatus(t); got != "pending" {
+		t.Fatalf("payment status after failed issue = %q, want pending", got)
+	}
+
+	if err := f.service.HandlePaymentSuccess(testPaymentIntentID, f.db); err != nil {
+		t.Fatalf("HandlePaymentSuccess() retry error = %v", err)
+	}
+	if got := f.paymentStatus(t); got != "succeeded" {
+		t.Errorf("payment status = %q, want succeeded", got)
+	}
+	if len(f.licenses.issued) != 1 {
+		t.Errorf("certificates = %v, want one", f.licenses.issued)
+	}
+}
\ No newline at end of file
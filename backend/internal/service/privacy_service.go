@@ -0,0 +1,328 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/mailer"
+)
+
+// エクスポートに含めるログイン履歴の件数
+const exportLoginAttemptLimit = 1000
+
+type PrivacyService interface {
+	ExportData(userID uint) (*model.UserDataExport, error)
+	ExportArchive(userID uint) ([]byte, error)
+	RequestErasure(userID uint, reason string) (*model.DataErasureRequest, error)
+	GetErasureRequest(userID uint) (*model.DataErasureRequest, error)
+	CancelErasureRequest(userID uint) error
+	ListErasureRequests(status string, page, pageSize int) ([]model.DataErasureRequest, int64, error)
+	ApproveErasureRequest(id, reviewerID uint, note string) (*model.DataErasureRequest, error)
+	RejectErasureRequest(id, reviewerID uint, note string) (*model.DataErasureRequest, error)
+}
+
+type privacyService struct {
+	userRepo              repository.UserRepository
+	addressRepo           repository.AddressRepository
+	orderRepo             repository.OrderRepository
+	paymentRepo           repository.PaymentRepository
+	cartRepo              repository.CartRepository
+	reviewRepo            repository.ReviewRepository
+	downloadRepo          repository.DownloadRepository
+	licenseRepo           repository.LicenseRepository
+	identityRepo          repository.IdentityRepository
+	apiKeyRepo            repository.APIKeyRepository
+	loginAttemptRepo      repository.LoginAttemptRepository
+	dataErasureRepo       repository.DataErasureRepository
+	stockNotificationRepo repository.StockNotificationRepository
+	tokenService          TokenService
+	mailer                mailer.Mailer
+}
+
+func NewPrivacyService(
+	userRepo repository.UserRepository,
+	addressRepo repository.AddressRepository,
+	orderRepo repository.OrderRepository,
+	paymentRepo repository.PaymentRepository,
+	cartRepo repository.CartRepository,
+	reviewRepo repository.ReviewRepository,
+	downloadRepo repository.DownloadRepository,
+	licenseRepo repository.LicenseRepository,
+	identityRepo repository.IdentityRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	dataErasureRepo repository.DataErasureRepository,
+	stockNotificationRepo repository.StockNotificationRepository,
+	tokenService TokenService,
+	mailer mailer.Mailer,
+) PrivacyService {
+	return &privacyService{
+		userRepo:              userRepo,
+		addressRepo:           addressRepo,
+		orderRepo:             orderRepo,
+		paymentRepo:           paymentRepo,
+		cartRepo:              cartRepo,
+		reviewRepo:            reviewRepo,
+		downloadRepo:          downloadRepo,
+		licenseRepo:           licenseRepo,
+		identityRepo:          identityRepo,
+		apiKeyRepo:            apiKeyRepo,
+		loginAttemptRepo:      loginAttemptRepo,
+		dataErasureRepo:       dataErasureRepo,
+		stockNotificationRepo: stockNotificationRepo,
+		tokenService:          tokenService,
+		mailer:                mailer,
+	}
+}
+
+// 本人のデータを収集
+func (s *privacyService) ExportData(userID uint) (*model.UserDataExport, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &model.UserDataExport{
+		ExportedAt: time.Now(),
+		Profile:    user,
+	}
+
+	if export.Addresses, err = s.addressRepo.ListByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.Orders, err = s.orderRepo.ListAllByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.Payments, err = s.paymentRepo.ListByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.Cart, err = s.cartRepo.GetByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.Reviews, err = s.reviewRepo.ListByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.DownloadLogs, err = s.downloadRepo.ListLogsByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.Licenses, err = s.licenseRepo.ListCertificatesByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.Identities, err = s.identityRepo.ListByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.APIKeys, err = s.apiKeyRepo.ListByUserID(userID); err != nil {
+		return nil, err
+	}
+	if export.LoginAttempts, _, err = s.loginAttemptRepo.ListByUserID(userID, 1, exportLoginAttemptLimit); err != nil {
+		return nil, err
+	}
+	if export.StockSubscriptions, err = s.stockNotificationRepo.ListSubscriptionsByUserID(userID); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// 本人のデータをZIPアーカイブにまとめる（項目ごとにJSONファイル）
+func (s *privacyService) ExportArchive(userID uint) ([]byte, error) {
+	export, err := s.ExportData(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"addresses.json", export.Addresses},
+		{"orders.json", export.Orders},
+		{"payments.json", export.Payments},
+		{"cart.json", export.Cart},
+		{"reviews.json", export.Reviews},
+		{"download_logs.json", export.DownloadLogs},
+		{"licenses.json", export.Licenses},
+		{"identities.json", export.Identities},
+		{"api_keys.json", export.APIKeys},
+		{"login_attempts.json", export.LoginAttempts},
+		{"stock_subscriptions.json", export.StockSubscriptions},
+	}
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 個人データ削除の依頼（管理者の承認後に匿名化する）
+func (s *privacyService) RequestErasure(userID uint, reason string) (*model.DataErasureRequest, error) {
+	latest, err := s.dataErasureRepo.GetLatestByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Status == model.ErasureStatusPending {
+		return nil, errors.New("an erasure request is already pending")
+	}
+
+	request := &model.DataErasureRequest{
+		UserID: userID,
+		Status: model.ErasureStatusPending,
+		Reason: reason,
+	}
+	if err := s.dataErasureRepo.Create(request); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (s *privacyService) GetErasureRequest(userID uint) (*model.DataErasureRequest, error) {
+	request, err := s.dataErasureRepo.GetLatestByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, errors.New("erasure request not found")
+	}
+	return request, nil
+}
+
+// 確認待ちの依頼の取り下げ
+func (s *privacyService) CancelErasureRequest(userID uint) error {
+	request, err := s.GetErasureRequest(userID)
+	if err != nil {
+		return err
+	}
+	if request.Status != model.ErasureStatusPending {
+		return errors.New("erasure request is not pending")
+	}
+
+	request.Status = model.ErasureStatusCancelled
+	return s.dataErasureRepo.Update(request)
+}
+
+// 依頼一覧（管理者用）
+func (s *privacyService) ListErasureRequests(status string, page, pageSize int) ([]model.DataErasureRequest, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.dataErasureRepo.List(status, page, pageSize)
+}
+
+// 依頼の承認（セッションを失効させてから個人データを匿名化）
+func (s *privacyService) ApproveErasureRequest(id, reviewerID uint, note string) (*model.DataErasureRequest, error) {
+	request, err := s.reviewableRequest(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 匿名化後は連絡先が無くなるため、先に宛先を控えておく
+	var email, name string
+	if request.User != nil {
+		email, name = request.User.Email, request.User.Name
+	}
+
+	if err := s.tokenService.RevokeUserSessions(request.UserID); err != nil {
+		return nil, err
+	}
+	if err := s.dataErasureRepo.AnonymizeUser(request.UserID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	request.Status = model.ErasureStatusCompleted
+	request.ReviewedBy = &reviewerID
+	request.ReviewNote = note
+	request.ReviewedAt = &now
+	request.CompletedAt = &now
+	if err := s.dataErasureRepo.Update(request); err != nil {
+		return nil, err
+	}
+
+	if email != "" {
+		s.notify(email, "個人データ削除完了のお知らせ", fmt.Sprintf(
+			"%s 様\n\nご依頼いただいた個人データの削除が完了しました。\nアカウントは削除され、注文履歴からもお名前・ご住所などの個人情報を削除しました。\n会計上必要な注文金額の記録のみ保持しています。\n",
+			name,
+		))
+	}
+
+	request.User = nil
+	return request, nil
+}
+
+// 依頼の却下（理由を本人に通知）
+func (s *privacyService) RejectErasureRequest(id, reviewerID uint, note string) (*model.DataErasureRequest, error) {
+	request, err := s.reviewableRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if note == "" {
+		return nil, errors.New("a note is required when rejecting a request")
+	}
+
+	now := time.Now()
+	request.Status = model.ErasureStatusRejected
+	request.ReviewedBy = &reviewerID
+	request.ReviewNote = note
+	request.ReviewedAt = &now
+	if err := s.dataErasureRepo.Update(request); err != nil {
+		return nil, err
+	}
+
+	if request.User != nil {
+		s.notify(request.User.Email, "個人データ削除のご依頼について", fmt.Sprintf(
+			"%s 様\n\nご依頼いただいた個人データの削除は、以下の理由により実施できませんでした。\n\n%s\n",
+			request.User.Name, note,
+		))
+	}
+
+	return request, nil
+}
+
+func (s *privacyService) reviewableRequest(id uint) (*model.DataErasureRequest, error) {
+	request, err := s.dataErasureRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != model.ErasureStatusPending {
+		return nil, errors.New("erasure request is not pending")
+	}
+	return request, nil
+}
+
+// 通知メールの送信（失敗しても処理は継続）
+func (s *privacyService) notify(to, subject, body string) {
+	if err := s.mailer.Send(&mailer.Message{To: to, Subject: subject, Body: body}); err != nil {
+		log.Println("Warning: failed to send privacy notification:", err)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/imageproc"
+	"github.com/Naonao3/EC-site/backend/pkg/storage"
+)
+
+// 1商品あたりの画像の上限
+const maxImagesPerProduct = 20
+
+// 生成する公開用の画像サイズ（縦横ともこの範囲に収まるよう縮小し、透かしを入れる）
+var productImageSizes = []struct {
+	name      string
+	maxWidth  int
+	maxHeight int
+}{
+	{model.ImageSizeThumbnail, 200, 200},
+	{model.ImageSizeMedium, 600, 600},
+	{model.ImageSizeLarge, 1200, 1200},
+}
+
+// JPEGで保存する際の品質
+const productImageJPEGQuality = 85
+
+type ProductImageService interface {
+	UploadImage(ctx context.Context, productID uint, data []byte, altText string) (*model.ProductImage, error)
+	ListImages(productID uint) ([]model.ProductImage, error)
+	UpdateImage(productID, id uint, altText string) (*model.ProductImage, error)
+	ReorderImages(productID uint, imageIDs []uint) ([]model.ProductImage, error)
+	DeleteImage(ctx context.Context, productID, id uint) error
+	MigrateWatermarks(ctx context.Context) error
+}
+
+type productImageService struct {
+	imageRepo      repository.ProductImageRepository
+	productRepo    repository.ProductRepository
+	storage        storage.Storage // 透かし入りのプレビュー（公開）
+	privateStorage storage.Storage // 元画像（購入者のみ署名付きURLでダウンロード可能）
+	watermarkText  string
+	maxUploadSize  int64
+}
+
+func NewProductImageService(
+	imageRepo repository.ProductImageRepository,
+	productRepo repository.ProductRepository,
+	storage storage.Storage,
+	privateStorage storage.Storage,
+	watermarkText string,
+	maxUploadSize int64,
+) ProductImageService {
+	return &productImageService{
+		imageRepo:      imageRepo,
+		productRepo:    productRepo,
+		storage:        storage,
+		privateStorage: privateStorage,
+		watermarkText:  watermarkText,
+		maxUploadSize:  maxUploadSize,
+	}
+}
+
+// 画像を検証し、元画像を非公開の保存先に、透かし入りの各サイズを公開の保存先に保存
+func (s *productImageService) UploadImage(ctx context.Context, productID uint, data []byte, altText string) (*model.ProductImage, error) {
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+
+	count, err := s.imageRepo.CountByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxImagesPerProduct {
+		return nil, fmt.Errorf("a product can have at most %d images", maxImagesPerProduct)
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("image is empty")
+	}
+	if int64(len(data)) > s.maxUploadSize {
+		return nil, fmt.Errorf("image must be %d MB or smaller", s.maxUploadSize>>20)
+	}
+
+	img, contentType, err := imageproc.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix, err := randomToken(12)
+	if err != nil {
+		return nil, err
+	}
+	image := &model.ProductImage{
+		ProductID:   productID,
+		Position:    int(count),
+		AltText:     strings.TrimSpace(altText),
+		StorageKey:  fmt.Sprintf("products/%d/%s", productID, suffix),
+		ContentType: contentType,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Size:        int64(len(data)),
+		Watermarked: true,
+	}
+
+	originalKey := originalImageKey(image)
+	if err := s.privateStorage.Put(ctx, originalKey, data, contentType); err != nil {
+		return nil, err
+	}
+
+	// 途中で失敗した場合は保存済みのファイルを削除
+	cleanup := func() {
+		s.deleteObjects(ctx, image)
+	}
+
+	if err := s.writePreviews(ctx, image, img); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	if err := s.imageRepo.Create(image); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	if err := s.imageRepo.SyncProductImageURL(productID); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+func (s *productImageService) ListImages(productID uint) ([]model.ProductImage, error) {
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+	return s.imageRepo.ListByProductID(productID)
+}
+
+func (s *productImageService) UpdateImage(productID, id uint, altText string) (*model.ProductImage, error) {
+	image, err := s.imageRepo.GetByID(productID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	image.AltText = strings.TrimSpace(altText)
+	if err := s.imageRepo.Update(image); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// 表示順の変更（商品の全ての画像IDを新しい順に指定する）
+func (s *productImageService) ReorderImages(productID uint, imageIDs []uint) ([]model.ProductImage, error) {
+	images, err := s.ListImages(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := uniqueIDs(imageIDs)
+	if len(ids) != len(imageIDs) || len(ids) != len(images) {
+		return nil, errors.New("image_ids must list every image of the product exactly once")
+	}
+	existing := make(map[uint]bool, len(images))
+	for _, image := range images {
+		existing[image.ID] = true
+	}
+	for _, id := range ids {
+		if !existing[id] {
+			return nil, errors.New("image not found")
+		}
+	}
+
+	if err := s.imageRepo.Reorder(productID, ids); err != nil {
+		return nil, err
+	}
+	if err := s.imageRepo.SyncProductImageURL(productID); err != nil {
+		return nil, err
+	}
+
+	return s.imageRepo.ListByProductID(productID)
+}
+
+func (s *productImageService) DeleteImage(ctx context.Context, productID, id uint) error {
+	image, err := s.imageRepo.GetByID(productID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.imageRepo.Delete(productID, id); err != nil {
+		return err
+	}
+
+	// 削除した画像を指していた場合は先頭の画像に付け替え（画像が無くなった場合は空にする）
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return err
+	}
+	if product.ImageURL == image.MediumURL {
+		product.ImageURL = ""
+		if err := s.productRepo.Update(product); err != nil {
+			return err
+		}
+	}
+	if err := s.imageRepo.SyncProductImageURL(productID); err != nil {
+		return err
+	}
+
+	s.deleteObjects(ctx, image)
+
+	return nil
+}
+
+// 透かし導入前の画像の移行（起動時に毎回実行・冪等）
+// 公開の保存先にある元画像を非公開の保存先に移し、各サイズを透かし入りで作り直す
+func (s *productImageService) MigrateWatermarks(ctx context.Context) error {
+	var afterID uint
+	for {
+		images, err := s.imageRepo.ListUnwatermarked(afterID, 100)
+		if err != nil {
+			return err
+		}
+		if len(images) == 0 {
+			return nil
+		}
+
+		for i := range images {
+			image := &images[i]
+			afterID = image.ID
+			// 1枚の失敗で起動を止めないよう、失敗した画像はログに残して次回に再試行する
+			if err := s.migrateWatermark(ctx, image); err != nil {
+				log.Printf("Failed to migrate image %d to watermarked previews: %v", image.ID, err)
+			}
+		}
+	}
+}
+
+func (s *productImageService) migrateWatermark(ctx context.Context, image *model.ProductImage) error {
+	originalKey := originalImageKey(image)
+
+	// 前回の移行が元画像のコピー後に中断した場合は非公開の保存先から読む
+	data, err := readObject(ctx, s.privateStorage, originalKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		data, err = readObject(ctx, s.storage, originalKey)
+		if err != nil {
+			return err
+		}
+		if err := s.privateStorage.Put(ctx, originalKey, data, image.ContentType); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	img, _, err := imageproc.Decode(data)
+	if err != nil {
+		return err
+	}
+	if err := s.writePreviews(ctx, image, img); err != nil {
+		return err
+	}
+
+	image.Watermarked = true
+	if err := s.imageRepo.Update(image); err != nil {
+		return err
+	}
+	if err := s.imageRepo.SyncProductImageURL(image.ProductID); err != nil {
+		return err
+	}
+
+	return s.storage.Delete(ctx, originalKey)
+}
+
+// 透かし入りの各サイズを生成して公開の保存先に保存し、URLを設定
+func (s *productImageService) writePreviews(ctx context.Context, image *model.ProductImage, img image.Image) error {
+	for _, size := range productImageSizes {
+		preview := imageproc.Watermark(imageproc.Fit(img, size.maxWidth, size.maxHeight), s.watermarkText)
+		encoded, encodedType, err := imageproc.Encode(preview, image.ContentType, productImageJPEGQuality)
+		if err != nil {
+			return err
+		}
+
+		key := sizedImageKey(image, size.name)
+		if err := s.storage.Put(ctx, key, encoded, encodedType); err != nil {
+			return err
+		}
+
+		switch size.name {
+		case model.ImageSizeThumbnail:
+			image.ThumbnailURL = s.storage.URL(key)
+		case model.ImageSizeMedium:
+			image.MediumURL = s.storage.URL(key)
+		case model.ImageSizeLarge:
+			image.LargeURL = s.storage.URL(key)
+		}
+	}
+	return nil
+}
+
+// 元画像と各サイズを削除（削除に失敗しても画像の削除自体は完了しているためログのみ）
+func (s *productImageService) deleteObjects(ctx context.Context, image *model.ProductImage) {
+	if err := s.privateStorage.Delete(ctx, originalImageKey(image)); err != nil {
+		log.Printf("Failed to delete image %s: %v", originalImageKey(image), err)
+	}
+	for _, size := range productImageSizes {
+		key := sizedImageKey(image, size.name)
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete image %s: %v", key, err)
+		}
+	}
+}
+
+func readObject(ctx context.Context, store storage.Storage, key string) ([]byte, error) {
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func originalImageKey(image *model.ProductImage) string {
+	return image.StorageKey + "/original" + imageproc.Extension(image.ContentType)
+}
+
+// 縮小画像はJPEGの元画像はJPEG、それ以外はPNGで保存される
+func sizedImageKey(image *model.ProductImage, size string) string {
+	ext := ".png"
+	if image.ContentType == imageproc.ContentTypeJPEG {
+		ext = ".jpg"
+	}
+	return image.StorageKey + "/" + size + ext
+}
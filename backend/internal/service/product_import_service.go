@@ -0,0 +1,531 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+// この行数を超えるファイルは非同期のジョブで処理する
+const asyncImportThreshold = 500
+
+// 非同期ジョブの進捗を保存する間隔（行数）
+const importProgressInterval = 100
+
+// ジョブに保存する行ごとのエラーの上限（件数はFailedCountで数える）
+const maxImportRowErrors = 1000
+
+// JSON Linesの1行の上限
+const maxImportLineSize = 1 << 20
+
+// エクスポート時に一度に読み込む商品数
+const exportBatchSize = 500
+
+type ProductImportService interface {
+	Import(r io.Reader, format string, dryRun, async bool, userID uint) (*model.ProductImportJob, error)
+	GetJob(id uint) (*model.ProductImportJob, error)
+	ListJobs(page, pageSize int) ([]model.ProductImportJob, int64, error)
+	Export(w io.Writer, format string) error
+	FailInterruptedJobs() error
+}
+
+type productImportService struct {
+	importRepo     repository.ProductImportRepository
+	productRepo    repository.ProductRepository
+	categoryRepo   repository.CategoryRepository
+	productService ProductService
+}
+
+func NewProductImportService(
+	importRepo repository.ProductImportRepository,
+	productRepo repository.ProductRepository,
+	categoryRepo repository.CategoryRepository,
+	productService ProductService,
+) ProductImportService {
+	return &productImportService{
+		importRepo:     importRepo,
+		productRepo:    productRepo,
+		categoryRepo:   categoryRepo,
+		productService: productService,
+	}
+}
+
+// ファイル形式の名前を正規化（対応していない形式はエラー）
+func ParseProductFileFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case model.ProductFileFormatCSV:
+		return model.ProductFileFormatCSV, nil
+	case model.ProductFileFormatJSONL, "ndjson":
+		return model.ProductFileFormatJSONL, nil
+	default:
+		return "", errors.New("unsupported format; use csv or jsonl")
+	}
+}
+
+// 商品の一括インポート（商品コードが一致する商品は更新、無ければ作成）
+// 行ごとに保存するため、エラーのある行だけがスキップされる
+func (s *productImportService) Import(r io.Reader, format string, dryRun, async bool, userID uint) (*model.ProductImportJob, error) {
+	format, err := ParseProductFileFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []model.ProductImportRow
+	var rowErrors []model.ImportRowError
+	if format == model.ProductFileFormatCSV {
+		rows, rowErrors, err = parseProductCSV(r)
+	} else {
+		rows, rowErrors, err = parseProductJSONL(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows)+len(rowErrors) == 0 {
+		return nil, errors.New("import file has no rows")
+	}
+
+	job := &model.ProductImportJob{
+		Format:        format,
+		DryRun:        dryRun,
+		Status:        model.ImportJobStatusPending,
+		TotalRows:     len(rows) + len(rowErrors),
+		ProcessedRows: len(rowErrors),
+		FailedCount:   len(rowErrors),
+		Errors:        rowErrors,
+		CreatedBy:     userID,
+	}
+	if len(job.Errors) > maxImportRowErrors {
+		job.Errors = job.Errors[:maxImportRowErrors]
+	}
+	if err := s.importRepo.Create(job); err != nil {
+		return nil, err
+	}
+
+	if async || len(rows) > asyncImportThreshold {
+		// 処理中のジョブとは別のコピーを返す
+		snapshot := *job
+		snapshot.Errors = append([]model.ImportRowError(nil), job.Errors...)
+		go s.run(job, rows)
+		return &snapshot, nil
+	}
+
+	s.run(job, rows)
+	return job, nil
+}
+
+func (s *productImportService) GetJob(id uint) (*model.ProductImportJob, error) {
+	return s.importRepo.GetByID(id)
+}
+
+func (s *productImportService) ListJobs(page, pageSize int) ([]model.ProductImportJob, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.importRepo.List(page, pageSize)
+}
+
+// 全商品をインポートと同じ形式で書き出す（エクスポートしたファイルはそのままインポートできる）
+func (s *productImportService) Export(w io.Writer, format string) error {
+	format, err := ParseProductFileFormat(format)
+	if err != nil {
+		return err
+	}
+
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		return err
+	}
+	slugs := make(map[uint]string, len(categories))
+	for _, category := range categories {
+		slugs[category.ID] = category.Slug
+	}
+
+	if format == model.ProductFileFormatJSONL {
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return s.productRepo.FindInBatches(exportBatchSize, func(products []model.Product) error {
+			for i := range products {
+				if err := encoder.Encode(exportRow(&products[i], slugs)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(model.ProductImportColumns); err != nil {
+		return err
+	}
+	err = s.productRepo.FindInBatches(exportBatchSize, func(products []model.Product) error {
+		for i := range products {
+			row := exportRow(&products[i], slugs)
+			record := []string{
+				row.SKU,
+				row.Name,
+				row.Description,
+				strconv.FormatFloat(row.Price, 'f', -1, 64),
+				strconv.Itoa(row.Stock),
+				row.Category,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		// バッチごとに書き出してメモリに溜めない
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// サーバーの再起動で中断されたジョブを失敗にする（起動時に実行）
+func (s *productImportService) FailInterruptedJobs() error {
+	count, err := s.importRepo.FailUnfinished("interrupted by server restart")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("Marked %d interrupted product import jobs as failed", count)
+	}
+	return nil
+}
+
+// ジョブの実行（行ごとの結果を集計し、一定行数ごとに進捗を保存）
+func (s *productImportService) run(job *model.ProductImportJob, rows []model.ProductImportRow) {
+	now := time.Now()
+	job.Status = model.ImportJobStatusRunning
+	job.StartedAt = &now
+	s.saveJob(job)
+
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		s.failJob(job, err)
+		return
+	}
+
+	// 在庫の変動はインポートジョブによるものとして記録
+	source := model.StockMovementSource{
+		Type:          model.StockMovementImport,
+		ReferenceType: model.StockReferenceImportJob,
+		ReferenceID:   &job.ID,
+		ActorID:       &job.CreatedBy,
+	}
+
+	seen := make(map[string]int, len(rows))
+	for _, row := range rows {
+		created, err := s.importRow(row, seen, categories, job.DryRun, source)
+		switch {
+		case err != nil:
+			job.FailedCount++
+			if len(job.Errors) < maxImportRowErrors {
+				job.Errors = append(job.Errors, model.ImportRowError{
+					Row:   row.Line,
+					SKU:   strings.TrimSpace(row.SKU),
+					Error: err.Error(),
+				})
+			}
+		case created:
+			job.CreatedCount++
+		default:
+			job.UpdatedCount++
+		}
+
+		job.ProcessedRows++
+		if job.ProcessedRows%importProgressInterval == 0 {
+			s.saveJob(job)
+		}
+	}
+
+	sort.SliceStable(job.Errors, func(i, j int) bool { return job.Errors[i].Row < job.Errors[j].Row })
+	finishedAt := time.Now()
+	job.Status = model.ImportJobStatusCompleted
+	job.FinishedAt = &finishedAt
+	s.saveJob(job)
+}
+
+// 1行分の作成・更新（dryRunの場合は検証のみ）
+func (s *productImportService) importRow(row model.ProductImportRow, seen map[string]int, categories []model.Category, dryRun bool, source model.StockMovementSource) (bool, error) {
+	sku := strings.TrimSpace(row.SKU)
+	if sku == "" {
+		return false, errors.New("sku is required")
+	}
+	if len(sku) > maxProductSKULength {
+		return false, errors.New("sku must be at most 64 characters")
+	}
+	if first, ok := seen[sku]; ok {
+		return false, fmt.Errorf("duplicate sku in file (first seen on row %d)", first)
+	}
+	seen[sku] = row.Line
+
+	product, err := s.productRepo.GetBySKU(sku)
+	if err != nil {
+		return false, err
+	}
+	created := product == nil
+	if created {
+		product = &model.Product{SKU: &sku}
+	}
+
+	if row.Name != nil {
+		product.Name = strings.TrimSpace(*row.Name)
+	}
+	if row.Description != nil {
+		product.Description = *row.Description
+	}
+	// バリエーション・ライセンスから算出される価格・在庫は商品の更新と同様に維持
+	if row.Price != nil && !product.HasVariants() && !product.HasLicenses() {
+		product.Price = *row.Price
+	}
+	if row.Stock != nil && !product.HasVariants() {
+		product.Stock = *row.Stock
+	}
+	if row.Category != nil {
+		if err := applyImportCategory(product, strings.TrimSpace(*row.Category), categories); err != nil {
+			return false, err
+		}
+	}
+
+	if dryRun {
+		if err := validateProduct(product); err != nil {
+			return false, err
+		}
+		if created {
+			// 削除済みの商品の商品コードは再利用できない
+			exists, err := s.productRepo.SKUExists(sku, 0)
+			if err != nil {
+				return false, err
+			}
+			if exists {
+				return false, errors.New("sku already exists")
+			}
+		}
+		return created, nil
+	}
+
+	if created {
+		return true, s.productService.CreateProduct(product, source)
+	}
+	return false, s.productService.UpdateProduct(product, source)
+}
+
+// カテゴリのスラッグ・名前の順にカテゴリを特定して設定（空の場合はカテゴリなし）
+func applyImportCategory(product *model.Product, key string, categories []model.Category) error {
+	if key == "" {
+		product.CategoryID = nil
+		product.Category = ""
+		return nil
+	}
+
+	for _, match := range []func(category *model.Category) bool{
+		func(category *model.Category) bool { return category.Slug == key },
+		func(category *model.Category) bool { return category.Name == key },
+	} {
+		for i := range categories {
+			if match(&categories[i]) {
+				product.CategoryID = &categories[i].ID
+				product.Category = categories[i].Name
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("category not found: %s", key)
+}
+
+func (s *productImportService) saveJob(job *model.ProductImportJob) {
+	if err := s.importRepo.Update(job); err != nil {
+		log.Printf("Failed to save product import job %d: %v", job.ID, err)
+	}
+}
+
+func (s *productImportService) failJob(job *model.ProductImportJob, cause error) {
+	finishedAt := time.Now()
+	job.Status = model.ImportJobStatusFailed
+	job.Message = cause.Error()
+	job.FinishedAt = &finishedAt
+	s.saveJob(job)
+}
+
+func exportRow(product *model.Product, categorySlugs map[uint]string) model.ProductExportRow {
+	sku := model.DefaultProductSKU(product.ID)
+	if product.SKU != nil {
+		sku = *product.SKU
+	}
+
+	// カテゴリテーブルに移行されていないカテゴリは名前で出力
+	category := product.Category
+	if product.CategoryID != nil {
+		if slug, ok := categorySlugs[*product.CategoryID]; ok {
+			category = slug
+		}
+	}
+
+	return model.ProductExportRow{
+		SKU:         sku,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Stock:       product.Stock,
+		Category:    category,
+	}
+}
+
+// CSVの読み込み（1行目は列名。列の順序は問わず、無い列は既存の値を維持）
+func parseProductCSV(r io.Reader) ([]model.ProductImportRow, []model.ImportRowError, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("import file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid csv: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		known := false
+		for _, column := range model.ProductImportColumns {
+			if name == column {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, nil, fmt.Errorf("unknown column: %s", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, nil, fmt.Errorf("duplicate column: %s", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["sku"]; !ok {
+		return nil, nil, errors.New("sku column is required")
+	}
+
+	var rows []model.ProductImportRow
+	var rowErrors []model.ImportRowError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				rowErrors = append(rowErrors, model.ImportRowError{
+					Row:   parseErr.StartLine,
+					Error: "wrong number of fields",
+				})
+				continue
+			}
+			return nil, nil, fmt.Errorf("invalid csv: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row, err := csvImportRow(record, columns)
+		row.Line = line
+		if err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: line, SKU: strings.TrimSpace(row.SKU), Error: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+func csvImportRow(record []string, columns map[string]int) (model.ProductImportRow, error) {
+	row := model.ProductImportRow{SKU: record[columns["sku"]]}
+
+	if i, ok := columns["name"]; ok {
+		row.Name = &record[i]
+	}
+	if i, ok := columns["description"]; ok {
+		row.Description = &record[i]
+	}
+	if i, ok := columns["price"]; ok {
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+		if err != nil {
+			return row, errors.New("invalid price")
+		}
+		row.Price = &price
+	}
+	if i, ok := columns["stock"]; ok {
+		stock, err := strconv.Atoi(strings.TrimSpace(record[i]))
+		if err != nil {
+			return row, errors.New("invalid stock")
+		}
+		row.Stock = &stock
+	}
+	if i, ok := columns["category"]; ok {
+		row.Category = &record[i]
+	}
+
+	return row, nil
+}
+
+// JSON Linesの読み込み（1行に1商品。無いフィールドは既存の値を維持、空行は無視）
+func parseProductJSONL(r io.Reader) ([]model.ProductImportRow, []model.ImportRowError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+
+	var rows []model.ProductImportRow
+	var rowErrors []model.ImportRowError
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" {
+			continue
+		}
+
+		var row model.ProductImportRow
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: line, Error: "invalid json: " + err.Error()})
+			continue
+		}
+		if decoder.More() {
+			rowErrors = append(rowErrors, model.ImportRowError{Row: line, Error: "invalid json: multiple values on one line"})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, nil, fmt.Errorf("line %d exceeds the maximum length", line+1)
+		}
+		return nil, nil, err
+	}
+
+	return rows, rowErrors, nil
+}
\ No newline at end of file
@@ -2,70 +2,153 @@ package service
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 
 	"github.com/Naonao3/EC-site/backend/internal/model"
 	"github.com/Naonao3/EC-site/backend/internal/repository"
 )
 
 type ProductService interface {
-	CreateProduct(product *model.Product) error
+	CreateProduct(product *model.Product, source model.StockMovementSource) error
 	GetProductByID(id uint) (*model.Product, error)
-	UpdateProduct(product *model.Product) error
+	UpdateProduct(product *model.Product, source model.StockMovementSource) error
 	DeleteProduct(id uint) error
 	ListProducts(page, pageSize int) ([]model.Product, int64, error)
 	GetProductsByCategory(category string, page, pageSize int) ([]model.Product, int64, error)
-	SearchProducts(keyword string, page, pageSize int) ([]model.Product, int64, error)
-	UpdateStock(id uint, quantity int) error
+	GetProductsByCategoryID(categoryID uint, page, pageSize int) ([]model.Product, int64, error)
+	SearchProducts(params model.ProductSearchParams) (*model.ProductSearchResult, error)
 }
 
 type productService struct {
-	productRepo repository.ProductRepository
+	productRepo        repository.ProductRepository
+	categoryRepo       repository.CategoryRepository
+	movementRepo       repository.StockMovementRepository
+	stockNotifications StockNotificationService
 }
 
-func NewProductService(productRepo repository.ProductRepository) ProductService {
+func NewProductService(
+	productRepo repository.ProductRepository,
+	categoryRepo repository.CategoryRepository,
+	movementRepo repository.StockMovementRepository,
+	stockNotifications StockNotificationService,
+) ProductService {
 	return &productService{
-		productRepo: productRepo,
+		productRepo:        productRepo,
+		categoryRepo:       categoryRepo,
+		movementRepo:       movementRepo,
+		stockNotifications: stockNotifications,
 	}
 }
 
-func (s *productService) CreateProduct(product *model.Product) error {
+// 商品作成（初期在庫はsourceの操作として変動履歴に記録）
+func (s *productService) CreateProduct(product *model.Product, source model.StockMovementSource) error {
+	product.Reserved = 0
+
 	// バリデーション
-	if product.Name == "" {
-		return errors.New("product name is required")
+	if err := validateProduct(product); err != nil {
+		return err
 	}
-	if product.Price <= 0 {
-		return errors.New("product price must be greater than 0")
+	if err := s.applySKU(product); err != nil {
+		return err
 	}
-	if product.Stock < 0 {
-		return errors.New("product stock cannot be negative")
+	if err := s.applyCategory(product); err != nil {
+		return err
+	}
+	product.RatingAverage = 0
+	product.RatingCount = 0
+	// 画像URLは透かし入りのプレビューを指すよう画像のアップロードAPIからのみ設定
+	product.ImageURL = ""
+
+	if err := s.productRepo.Create(product); err != nil {
+		return err
 	}
 
-	return s.productRepo.Create(product)
+	// 商品コードの指定が無い場合はIDから割り当て
+	if product.SKU == nil {
+		sku := model.DefaultProductSKU(product.ID)
+		product.SKU = &sku
+		if err := s.productRepo.Update(product); err != nil {
+			return err
+		}
+	}
+
+	// 在庫は作成時には保存されないため、変動履歴と合わせて反映
+	if product.Stock != 0 {
+		movement := source.WithDefaults(model.StockMovementAdjustment, model.StockReasonInitial).Movement(product.ID, nil, product.Stock)
+		if err := s.movementRepo.Adjust(movement); err != nil {
+			return err
+		}
+	}
+
+	s.stockNotifications.ProductStockChanged(product.ID)
+	return nil
 }
 
 func (s *productService) GetProductByID(id uint) (*model.Product, error) {
 	return s.productRepo.GetByID(id)
 }
 
-func (s *productService) UpdateProduct(product *model.Product) error {
+// 商品更新（在庫の変更はsourceの操作として変動履歴に記録）
+func (s *productService) UpdateProduct(product *model.Product, source model.StockMovementSource) error {
 	// 商品の存在確認
-	_, err := s.productRepo.GetByID(product.ID)
+	existing, err := s.productRepo.GetByID(product.ID)
 	if err != nil {
 		return err
 	}
 
+	// 評価はレビューから集計した値を維持
+	product.RatingAverage = existing.RatingAverage
+	product.RatingCount = existing.RatingCount
+
+	// 確保数は在庫予約から管理される値を維持
+	product.Reserved = existing.Reserved
+
+	// バリエーションのある商品の価格・在庫はバリエーションから算出した値を維持
+	if existing.HasVariants() {
+		product.Price = existing.Price
+		product.Stock = existing.Stock
+	}
+
+	// ライセンスのある商品の価格はライセンスの最安値を維持
+	if existing.HasLicenses() {
+		product.Price = existing.Price
+	}
+
+	// 画像URLはアップロードされた先頭の画像（透かし入りのプレビュー）を維持
+	product.ImageURL = existing.ImageURL
+
+	// 商品コードの指定が無い場合は現在の商品コードを維持
+	if product.SKU == nil || strings.TrimSpace(*product.SKU) == "" {
+		product.SKU = existing.SKU
+	}
+
 	// バリデーション
-	if product.Name == "" {
-		return errors.New("product name is required")
+	if err := validateProduct(product); err != nil {
+		return err
 	}
-	if product.Price <= 0 {
-		return errors.New("product price must be greater than 0")
+	if err := s.applySKU(product); err != nil {
+		return err
 	}
-	if product.Stock < 0 {
-		return errors.New("product stock cannot be negative")
+	if err := s.applyCategory(product); err != nil {
+		return err
+	}
+
+	if err := s.productRepo.Update(product); err != nil {
+		return err
 	}
 
-	return s.productRepo.Update(product)
+	// 在庫は更新されないため、変更された差分を変動履歴と合わせて反映
+	if delta := product.Stock - existing.Stock; delta != 0 {
+		movement := source.WithDefaults(model.StockMovementAdjustment, model.StockReasonProductEdit).Movement(product.ID, nil, delta)
+		if err := s.movementRepo.Adjust(movement); err != nil {
+			return err
+		}
+	}
+
+	// 在庫・発注点の変更で通知の条件が変わる
+	s.stockNotifications.ProductStockChanged(product.ID)
+	return nil
 }
 
 func (s *productService) DeleteProduct(id uint) error {
@@ -89,6 +172,7 @@ func (s *productService) ListProducts(page, pageSize int) ([]model.Product, int6
 	return s.productRepo.List(page, pageSize)
 }
 
+// カテゴリ（スラッグ・ID・カテゴリ名）の商品一覧（子孫カテゴリの商品を含む）
 func (s *productService) GetProductsByCategory(category string, page, pageSize int) ([]model.Product, int64, error) {
 	if page < 1 {
 		page = 1
@@ -97,10 +181,20 @@ func (s *productService) GetProductsByCategory(category string, page, pageSize i
 		pageSize = 10
 	}
 
-	return s.productRepo.GetByCategory(category, page, pageSize)
+	resolved, err := s.resolveCategory(category)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resolved == nil {
+		// カテゴリテーブルに存在しない場合は文字列のカテゴリで検索
+		return s.productRepo.GetByCategory(category, page, pageSize)
+	}
+
+	return s.GetProductsByCategoryID(resolved.ID, page, pageSize)
 }
 
-func (s *productService) SearchProducts(keyword string, page, pageSize int) ([]model.Product, int64, error) {
+// カテゴリIDの商品一覧（子孫カテゴリの商品を含む）
+func (s *productService) GetProductsByCategoryID(categoryID uint, page, pageSize int) ([]model.Product, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -108,20 +202,154 @@ func (s *productService) SearchProducts(keyword string, page, pageSize int) ([]m
 		pageSize = 10
 	}
 
-	return s.productRepo.Search(keyword, page, pageSize)
+	if _, err := s.categoryRepo.GetByID(categoryID); err != nil {
+		return nil, 0, err
+	}
+
+	categoryIDs, err := s.categoryRepo.DescendantIDs(categoryID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return s.productRepo.GetByCategoryIDs(categoryIDs, page, pageSize)
 }
 
-func (s *productService) UpdateStock(id uint, quantity int) error {
-	// 商品の存在確認
-	product, err := s.productRepo.GetByID(id)
+// 商品検索（関連度・表記揺れ対応、絞り込み・並び替え・ファセット件数付き）
+func (s *productService) SearchProducts(params model.ProductSearchParams) (*model.ProductSearchResult, error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 || params.PageSize > 100 {
+		params.PageSize = 10
+	}
+
+	params.Keyword = strings.TrimSpace(params.Keyword)
+	if params.Sort == "" {
+		params.Sort = model.ProductSortNewest
+		if params.Keyword != "" {
+			params.Sort = model.ProductSortRelevance
+		}
+	}
+	validSort := false
+	for _, option := range model.ProductSortOptions {
+		if params.Sort == option {
+			validSort = true
+			break
+		}
+	}
+	if !validSort {
+		return nil, errors.New("invalid sort option")
+	}
+
+	if params.MinPrice != nil && params.MaxPrice != nil && *params.MinPrice > *params.MaxPrice {
+		return nil, errors.New("min_price must not be greater than max_price")
+	}
+
+	// カテゴリ指定時は子孫カテゴリの商品も含める
+	if params.CategoryID != nil {
+		if _, err := s.categoryRepo.GetByID(*params.CategoryID); err != nil {
+			return nil, err
+		}
+		categoryIDs, err := s.categoryRepo.DescendantIDs(*params.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		params.CategoryIDs = categoryIDs
+	}
+
+	products, total, err := s.productRepo.Search(params)
+	if err != nil {
+		return nil, err
+	}
+
+	facets, err := s.productRepo.SearchFacets(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ProductSearchResult{
+		Products: products,
+		Total:    total,
+		Sort:     params.Sort,
+		Facets:   *facets,
+	}, nil
+}
+
+// スラッグ・ID・カテゴリ名の順にカテゴリを特定（見つからない場合はnil）
+func (s *productService) resolveCategory(key string) (*model.Category, error) {
+	category, err := s.categoryRepo.GetBySlug(key)
+	if err != nil || category != nil {
+		return category, err
+	}
+
+	if id, err := strconv.ParseUint(key, 10, 32); err == nil {
+		if category, err := s.categoryRepo.GetByID(uint(id)); err == nil {
+			return category, nil
+		}
+	}
+
+	return s.categoryRepo.GetByName(key)
+}
+
+// 商品の入力値の検証（作成・更新・一括インポートで共通）
+func validateProduct(product *model.Product) error {
+	if product.Name == "" {
+		return errors.New("product name is required")
+	}
+	if product.Price <= 0 {
+		return errors.New("product price must be greater than 0")
+	}
+	if product.Stock < 0 {
+		return errors.New("product stock cannot be negative")
+	}
+	if product.Stock < product.Reserved {
+		return errors.New("product stock cannot be less than reserved quantity")
+	}
+	if product.LowStockThreshold < 0 {
+		return errors.New("low stock threshold cannot be negative")
+	}
+	return nil
+}
+
+// 商品コードの長さ
+const maxProductSKULength = 64
+
+// 商品コードの正規化と重複チェック（空の場合は未指定として扱う）
+func (s *productService) applySKU(product *model.Product) error {
+	if product.SKU == nil {
+		return nil
+	}
+
+	sku := strings.TrimSpace(*product.SKU)
+	if sku == "" {
+		product.SKU = nil
+		return nil
+	}
+	if len(sku) > maxProductSKULength {
+		return errors.New("sku must be at most 64 characters")
+	}
+
+	exists, err := s.productRepo.SKUExists(sku, product.ID)
 	if err != nil {
 		return err
 	}
+	if exists {
+		return errors.New("sku already exists")
+	}
+	product.SKU = &sku
+	return nil
+}
 
-	// 在庫不足チェック
-	if product.Stock+quantity < 0 {
-		return errors.New("insufficient stock")
+// カテゴリIDの存在確認と、互換性のためのカテゴリ名の設定
+func (s *productService) applyCategory(product *model.Product) error {
+	if product.CategoryID == nil {
+		return nil
 	}
 
-	return s.productRepo.UpdateStock(id, quantity)
+	category, err := s.categoryRepo.GetByID(*product.CategoryID)
+	if err != nil {
+		return err
+	}
+	product.Category = category.Name
+	return nil
 }
\ No newline at end of file
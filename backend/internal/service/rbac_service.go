@@ -0,0 +1,247 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+// 組み込みの権限一覧
+var defaultPermissions = []model.Permission{
+	{Name: model.PermissionProductsWrite, Description: "商品の作成・更新・削除"},
+	{Name: model.PermissionOrdersRead, Description: "全注文の閲覧"},
+	{Name: model.PermissionOrdersUpdateStatus, Description: "注文ステータスの更新"},
+	{Name: model.PermissionUsersRead, Description: "ユーザー情報の閲覧"},
+	{Name: model.PermissionUsersWrite, Description: "ユーザーのロック解除など"},
+	{Name: model.PermissionUsersDelete, Description: "ユーザーの削除"},
+	{Name: model.PermissionRolesManage, Description: "ロールと権限の管理"},
+	{Name: model.PermissionReviewsModerate, Description: "レビューの承認・非表示"},
+	{Name: model.PermissionInventoryWrite, Description: "在庫の調整・倉庫の管理・倉庫間の移動"},
+	{Name: model.PermissionCouponsWrite, Description: "クーポンの発行・更新・削除"},
+}
+
+type RBACService interface {
+	SeedDefaults() error
+	ListPermissions() ([]model.Permission, error)
+	ListRoles() ([]model.Role, error)
+	GetRole(id uint) (*model.Role, error)
+	CreateRole(name, description string, permissions []string) (*model.Role, error)
+	UpdateRole(id uint, name, description string, permissions []string) (*model.Role, error)
+	DeleteRole(id uint) error
+	GetUserRoles(userID uint) ([]model.Role, error)
+	SetUserRoles(userID uint, roleIDs []uint) ([]model.Role, error)
+}
+
+type rbacService struct {
+	roleRepo     repository.RoleRepository
+	userRepo     repository.UserRepository
+	tokenService TokenService
+}
+
+func NewRBACService(
+	roleRepo repository.RoleRepository,
+	userRepo repository.UserRepository,
+	tokenService TokenService,
+) RBACService {
+	return &rbacService{
+		roleRepo:     roleRepo,
+		userRepo:     userRepo,
+		tokenService: tokenService,
+	}
+}
+
+// 組み込みの権限と管理者ロールを作成し、旧来のrole=adminのユーザーに割り当てる
+func (s *rbacService) SeedDefaults() error {
+	for i := range defaultPermissions {
+		permission := defaultPermissions[i]
+		if err := s.roleRepo.EnsurePermission(&permission); err != nil {
+			return err
+		}
+	}
+
+	permissions, err := s.roleRepo.ListPermissions()
+	if err != nil {
+		return err
+	}
+
+	admin, err := s.roleRepo.GetByName(model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admin == nil {
+		admin = &model.Role{
+			Name:        model.RoleAdmin,
+			Description: "全ての権限を持つ管理者",
+			IsSystem:    true,
+			Permissions: permissions,
+		}
+		if err := s.roleRepo.Create(admin); err != nil {
+			return err
+		}
+	} else {
+		// 権限が追加された場合に備えて常に全権限を付与
+		admin.Permissions = permissions
+		if err := s.roleRepo.Update(admin); err != nil {
+			return err
+		}
+	}
+
+	return s.roleRepo.AssignRoleByLegacyRole(admin.ID, model.RoleAdmin)
+}
+
+func (s *rbacService) ListPermissions() ([]model.Permission, error) {
+	return s.roleRepo.ListPermissions()
+}
+
+func (s *rbacService) ListRoles() ([]model.Role, error) {
+	return s.roleRepo.List()
+}
+
+func (s *rbacService) GetRole(id uint) (*model.Role, error) {
+	return s.roleRepo.GetByID(id)
+}
+
+func (s *rbacService) CreateRole(name, description string, permissions []string) (*model.Role, error) {
+	if name == "" {
+		return nil, errors.New("role name is required")
+	}
+
+	existing, err := s.roleRepo.GetByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("role already exists")
+	}
+
+	perms, err := s.resolvePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.Role{
+		Name:        name,
+		Description: description,
+		Permissions: perms,
+	}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (s *rbacService) UpdateRole(id uint, name, description string, permissions []string) (*model.Role, error) {
+	role, err := s.roleRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.IsSystem {
+		return nil, errors.New("system role cannot be modified")
+	}
+
+	if name != "" && name != role.Name {
+		existing, err := s.roleRepo.GetByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, errors.New("role already exists")
+		}
+		role.Name = name
+	}
+	role.Description = description
+
+	perms, err := s.resolvePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+	role.Permissions = perms
+
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (s *rbacService) DeleteRole(id uint) error {
+	role, err := s.roleRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if role.IsSystem {
+		return errors.New("system role cannot be deleted")
+	}
+
+	return s.roleRepo.Delete(id)
+}
+
+func (s *rbacService) GetUserRoles(userID uint) ([]model.Role, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.GetUserRoles(userID)
+}
+
+// ユーザーのロールを置き換える
+func (s *rbacService) SetUserRoles(userID uint, roleIDs []uint) ([]model.Role, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roleRepo.GetByIDs(roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) != len(uniqueIDs(roleIDs)) {
+		return nil, errors.New("role not found")
+	}
+
+	if err := s.roleRepo.SetUserRoles(userID, roles); err != nil {
+		return nil, err
+	}
+
+	// 発行済みアクセストークンの権限を無効化（リフレッシュ時に新しい権限で再発行される）
+	if err := s.tokenService.InvalidateAccessTokens(userID); err != nil {
+		return nil, err
+	}
+
+	return s.roleRepo.GetUserRoles(userID)
+}
+
+// 権限名から権限を取得（存在しない権限名はエラー）
+func (s *rbacService) resolvePermissions(names []string) ([]model.Permission, error) {
+	permissions, err := s.roleRepo.GetPermissionsByNames(names)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		found[permission.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, fmt.Errorf("unknown permission: %s", name)
+		}
+	}
+
+	return permissions, nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
\ No newline at end of file
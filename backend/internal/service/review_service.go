@@ -0,0 +1,291 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+// レビュー本文の上限（文字数）
+const (
+	maxReviewTitleLength   = 100
+	maxReviewCommentLength = 2000
+)
+
+type ReviewService interface {
+	ListProductReviews(productID uint, sort string, page, pageSize int) ([]model.Review, int64, *model.ReviewSummary, error)
+	ListUserReviews(userID uint) ([]model.Review, error)
+	CreateReview(userID, productID uint, rating int, title, comment string) (*model.Review, error)
+	UpdateReview(userID, reviewID uint, rating int, title, comment string) (*model.Review, error)
+	DeleteReview(userID, reviewID uint) error
+	VoteHelpful(userID, reviewID uint) (*model.Review, error)
+	RemoveHelpfulVote(userID, reviewID uint) (*model.Review, error)
+	ListReviews(status string, page, pageSize int) ([]model.Review, int64, error)
+	ApproveReview(reviewID, moderatorID uint, note string) (*model.Review, error)
+	HideReview(reviewID, moderatorID uint, note string) (*model.Review, error)
+}
+
+type reviewService struct {
+	reviewRepo  repository.ReviewRepository
+	productRepo repository.ProductRepository
+}
+
+func NewReviewService(reviewRepo repository.ReviewRepository, productRepo repository.ProductRepository) ReviewService {
+	return &reviewService{
+		reviewRepo:  reviewRepo,
+		productRepo: productRepo,
+	}
+}
+
+// 商品の公開中のレビューと集計
+func (s *reviewService) ListProductReviews(productID uint, sort string, page, pageSize int) ([]model.Review, int64, *model.ReviewSummary, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	switch sort {
+	case "", repository.ReviewSortNewest, repository.ReviewSortHelpful, repository.ReviewSortRatingDesc, repository.ReviewSortRatingAsc:
+	default:
+		return nil, 0, nil, errors.New("invalid sort option")
+	}
+
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, 0, nil, err
+	}
+
+	reviews, total, err := s.reviewRepo.ListByProductID(productID, sort, page, pageSize)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	summary, err := s.reviewRepo.Summary(productID)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	return reviews, total, summary, nil
+}
+
+func (s *reviewService) ListUserReviews(userID uint) ([]model.Review, error) {
+	return s.reviewRepo.ListByUserID(userID)
+}
+
+// レビュー投稿（配送済みの注文に含まれる商品のみ、1商品につき1件）
+func (s *reviewService) CreateReview(userID, productID uint, rating int, title, comment string) (*model.Review, error) {
+	title, comment, err := validateReview(rating, title, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.reviewRepo.GetByUserAndProduct(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("you have already reviewed this product")
+	}
+
+	orderID, err := s.reviewRepo.FindDeliveredOrderID(userID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if orderID == 0 {
+		return nil, errors.New("only customers with a delivered order can review this product")
+	}
+
+	review := &model.Review{
+		UserID:    userID,
+		ProductID: productID,
+		OrderID:   orderID,
+		Rating:    rating,
+		Title:     title,
+		Comment:   comment,
+		Status:    model.ReviewStatusPending,
+	}
+	if err := s.reviewRepo.Create(review); err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// レビュー編集（再度確認待ちに戻る）
+func (s *reviewService) UpdateReview(userID, reviewID uint, rating int, title, comment string) (*model.Review, error) {
+	review, err := s.getOwnReview(userID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	title, comment, err = validateReview(rating, title, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	wasApproved := review.Status == model.ReviewStatusApproved
+	review.Rating = rating
+	review.Title = title
+	review.Comment = comment
+	review.Status = model.ReviewStatusPending
+	review.ModerationNote = ""
+	review.ModeratedBy = nil
+	review.ModeratedAt = nil
+	if err := s.reviewRepo.Update(review); err != nil {
+		return nil, err
+	}
+
+	if wasApproved {
+		if err := s.reviewRepo.RefreshProductRating(review.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	return review, nil
+}
+
+func (s *reviewService) DeleteReview(userID, reviewID uint) error {
+	review, err := s.getOwnReview(userID, reviewID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.reviewRepo.Delete(review.ID); err != nil {
+		return err
+	}
+
+	if review.Status == model.ReviewStatusApproved {
+		return s.reviewRepo.RefreshProductRating(review.ProductID)
+	}
+	return nil
+}
+
+// 「参考になった」の投票（公開中の他人のレビューのみ）
+func (s *reviewService) VoteHelpful(userID, reviewID uint) (*model.Review, error) {
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Status != model.ReviewStatusApproved {
+		return nil, errors.New("review not found")
+	}
+	if review.UserID == userID {
+		return nil, errors.New("you cannot vote on your own review")
+	}
+
+	added, err := s.reviewRepo.AddVote(reviewID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, errors.New("already voted")
+	}
+
+	return s.reviewRepo.GetByID(reviewID)
+}
+
+func (s *reviewService) RemoveHelpfulVote(userID, reviewID uint) (*model.Review, error) {
+	if _, err := s.reviewRepo.GetByID(reviewID); err != nil {
+		return nil, err
+	}
+
+	removed, err := s.reviewRepo.RemoveVote(reviewID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, errors.New("vote not found")
+	}
+
+	return s.reviewRepo.GetByID(reviewID)
+}
+
+// レビュー一覧（管理者用）
+func (s *reviewService) ListReviews(status string, page, pageSize int) ([]model.Review, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	switch status {
+	case "", model.ReviewStatusPending, model.ReviewStatusApproved, model.ReviewStatusHidden:
+	default:
+		return nil, 0, errors.New("invalid status")
+	}
+
+	return s.reviewRepo.List(status, page, pageSize)
+}
+
+func (s *reviewService) ApproveReview(reviewID, moderatorID uint, note string) (*model.Review, error) {
+	return s.moderate(reviewID, moderatorID, model.ReviewStatusApproved, note)
+}
+
+func (s *reviewService) HideReview(reviewID, moderatorID uint, note string) (*model.Review, error) {
+	return s.moderate(reviewID, moderatorID, model.ReviewStatusHidden, note)
+}
+
+// 公開状態を変更し、商品の平均評価を再計算
+func (s *reviewService) moderate(reviewID, moderatorID uint, status, note string) (*model.Review, error) {
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	review.Status = status
+	review.ModerationNote = strings.TrimSpace(note)
+	review.ModeratedBy = &moderatorID
+	review.ModeratedAt = &now
+	if err := s.reviewRepo.Update(review); err != nil {
+		return nil, err
+	}
+
+	if err := s.reviewRepo.RefreshProductRating(review.ProductID); err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (s *reviewService) getOwnReview(userID, reviewID uint) (*model.Review, error) {
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ユーザーの所有確認
+	if review.UserID != userID {
+		return nil, errors.New("review not found")
+	}
+
+	return review, nil
+}
+
+func validateReview(rating int, title, comment string) (string, string, error) {
+	if rating < 1 || rating > 5 {
+		return "", "", errors.New("rating must be between 1 and 5")
+	}
+
+	title = strings.TrimSpace(title)
+	comment = strings.TrimSpace(comment)
+	if utf8.RuneCountInString(title) > maxReviewTitleLength {
+		return "", "", errors.New("title is too long")
+	}
+	if utf8.RuneCountInString(comment) > maxReviewCommentLength {
+		return "", "", errors.New("comment is too long")
+	}
+
+	return title, comment, nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+// 在庫の調整のメモの上限
+const maxStockNoteLength = 500
+
+type StockMovementService interface {
+	AdjustStock(productID uint, variantID *uint, quantity int, source model.StockMovementSource) (*model.StockMovement, error)
+	ListMovements(productID uint, page, pageSize int) ([]model.StockMovement, int64, error)
+	Reconcile() (*model.StockReconciliation, error)
+	RecordOpeningBalances() (int64, error)
+}
+
+type stockMovementService struct {
+	movementRepo       repository.StockMovementRepository
+	productRepo        repository.ProductRepository
+	warehouseRepo      repository.WarehouseRepository
+	orderRepo          repository.OrderRepository
+	stockNotifications StockNotificationService
+}
+
+func NewStockMovementService(
+	movementRepo repository.StockMovementRepository,
+	productRepo repository.ProductRepository,
+	warehouseRepo repository.WarehouseRepository,
+	orderRepo repository.OrderRepository,
+	stockNotifications StockNotificationService,
+) StockMovementService {
+	return &stockMovementService{
+		movementRepo:       movementRepo,
+		productRepo:        productRepo,
+		warehouseRepo:      warehouseRepo,
+		orderRepo:          orderRepo,
+		stockNotifications: stockNotifications,
+	}
+}
+
+// 管理者による在庫の調整（バリエーションのある商品はバリエーションを指定。倉庫の指定が無い場合は既定の倉庫）
+func (s *stockMovementService) AdjustStock(productID uint, variantID *uint, quantity int, source model.StockMovementSource) (*model.StockMovement, error) {
+	if quantity == 0 {
+		return nil, errors.New("quantity must not be zero")
+	}
+	if err := applyStockAdjustmentReason(&source); err != nil {
+		return nil, err
+	}
+
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if variantID == nil && product.HasVariants() {
+		return nil, errors.New("product has variants; specify variant_id")
+	}
+	if variantID != nil && product.FindVariant(*variantID) == nil {
+		return nil, errors.New("variant not found")
+	}
+
+	if source.WarehouseID != nil {
+		if _, err := s.warehouseRepo.GetByID(*source.WarehouseID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 調整のきっかけになった注文（返品など）
+	if source.ReferenceID != nil {
+		if _, err := s.orderRepo.GetByID(*source.ReferenceID); err != nil {
+			return nil, err
+		}
+		source.ReferenceType = model.StockReferenceOrder
+	}
+
+	movement := source.Movement(productID, variantID, quantity)
+	if err := s.movementRepo.Adjust(movement); err != nil {
+		return nil, err
+	}
+
+	s.stockNotifications.ProductStockChanged(productID)
+	return movement, nil
+}
+
+// 商品の在庫の変動履歴
+func (s *stockMovementService) ListMovements(productID uint, page, pageSize int) ([]model.StockMovement, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.movementRepo.ListByProductID(productID, page, pageSize)
+}
+
+// 変動履歴の合計と在庫数、倉庫ごとの在庫の合計と在庫数を照合
+func (s *stockMovementService) Reconcile() (*model.StockReconciliation, error) {
+	discrepancies, err := s.movementRepo.Reconcile()
+	if err != nil {
+		return nil, err
+	}
+	if discrepancies == nil {
+		discrepancies = []model.StockDiscrepancy{}
+	}
+
+	warehouseDiscrepancies, err := s.movementRepo.ReconcileWarehouses()
+	if err != nil {
+		return nil, err
+	}
+	if warehouseDiscrepancies == nil {
+		warehouseDiscrepancies = []model.WarehouseStockDiscrepancy{}
+	}
+
+	return &model.StockReconciliation{
+		CheckedAt:              time.Now(),
+		Consistent:             len(discrepancies) == 0 && len(warehouseDiscrepancies) == 0,
+		Discrepancies:          discrepancies,
+		WarehouseDiscrepancies: warehouseDiscrepancies,
+	}, nil
+}
+
+// 変動履歴を記録する前からある在庫を初期値として記録（起動時に実行）
+func (s *stockMovementService) RecordOpeningBalances() (int64, error) {
+	return s.movementRepo.RecordOpeningBalances()
+}
+
+// 管理者による在庫の調整の理由を検証し、変動の種類を設定（返品の受け入れは返品として記録）
+func applyStockAdjustmentReason(source *model.StockMovementSource) error {
+	valid := false
+	for _, reason := range model.StockAdjustmentReasons {
+		if source.Reason == reason {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.New("invalid stock adjustment reason")
+	}
+	if len(source.Note) > maxStockNoteLength {
+		return errors.New("note must be at most 500 characters")
+	}
+
+	source.Type = model.StockMovementAdjustment
+	if source.Reason == model.StockReasonCustomerReturn {
+		source.Type = model.StockMovementReturn
+	}
+	return nil
+}
\ No newline at end of file
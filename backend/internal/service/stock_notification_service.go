@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/mailer"
+)
+
+// StockNotifier 在庫に関する通知の送信（メール以外の通知手段に差し替えられる）
+type StockNotifier interface {
+	NotifyLowStock(recipients []string, alert *model.StockAlert) error
+	NotifyBackInStock(subscription *model.StockSubscription, product *model.Product) error
+}
+
+type mailStockNotifier struct {
+	mailer      mailer.Mailer
+	frontendURL string
+}
+
+// メールで送信するStockNotifier
+func NewMailStockNotifier(mailer mailer.Mailer, frontendURL string) StockNotifier {
+	return &mailStockNotifier{
+		mailer:      mailer,
+		frontendURL: frontendURL,
+	}
+}
+
+func (n *mailStockNotifier) NotifyLowStock(recipients []string, alert *model.StockAlert) error {
+	for _, to := range recipients {
+		err := n.mailer.Send(&mailer.Message{
+			To:      to,
+			Subject: fmt.Sprintf("【在庫アラート】%s の在庫が残りわずかです", alert.ProductName),
+			Body: fmt.Sprintf(
+				"以下の商品の在庫が発注点を下回りました。\n\n商品: %s（ID: %d）\n購入できる数: %d\n発注点: %d\n\n在庫の補充をご検討ください。\n",
+				alert.ProductName, alert.ProductID, alert.Available, alert.Threshold,
+			),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *mailStockNotifier) NotifyBackInStock(subscription *model.StockSubscription, product *model.Product) error {
+	productLink := fmt.Sprintf("%s/products/%d", n.frontendURL, product.ID)
+	unsubscribeLink := fmt.Sprintf("%s/stock-notifications/unsubscribe?token=%s", n.frontendURL, url.QueryEscape(subscription.Token))
+	return n.mailer.Send(&mailer.Message{
+		To:      subscription.Email,
+		Subject: fmt.Sprintf("%s が再入荷しました", product.Name),
+		Body: fmt.Sprintf(
+			"入荷通知をご登録いただいた商品が再入荷しました。\n\n%s\n%s\n\n在庫には限りがありますので、お早めにお買い求めください。\n\n入荷通知の登録を解除する場合は以下のリンクを開いてください。\n%s\n",
+			product.Name, productLink, unsubscribeLink,
+		),
+	})
+}
+
+type StockNotificationService interface {
+	ProductStockChanged(productIDs ...uint)
+	CheckProduct(productID uint) error
+	Subscribe(userID, productID uint) (*model.StockSubscription, error)
+	Unsubscribe(userID, productID uint) error
+	UnsubscribeByToken(token string) error
+	ListAlerts(openOnly bool, page, pageSize int) ([]model.StockAlert, int64, error)
+}
+
+type stockNotificationService struct {
+	notificationRepo repository.StockNotificationRepository
+	productRepo      repository.ProductRepository
+	userRepo         repository.UserRepository
+	notifier         StockNotifier
+	alertRecipients  []string
+}
+
+func NewStockNotificationService(
+	notificationRepo repository.StockNotificationRepository,
+	productRepo repository.ProductRepository,
+	userRepo repository.UserRepository,
+	notifier StockNotifier,
+	alertRecipients []string,
+) StockNotificationService {
+	return &stockNotificationService{
+		notificationRepo: notificationRepo,
+		productRepo:      productRepo,
+		userRepo:         userRepo,
+		notifier:         notifier,
+		alertRecipients:  alertRecipients,
+	}
+}
+
+// 在庫が変わった商品の通知を確認（メール送信で在庫の更新を待たせないようバックグラウンドで実行）
+func (s *stockNotificationService) ProductStockChanged(productIDs ...uint) {
+	seen := make(map[uint]bool, len(productIDs))
+	for _, productID := range productIDs {
+		if seen[productID] {
+			continue
+		}
+		seen[productID] = true
+
+		go func(productID uint) {
+			if err := s.CheckProduct(productID); err != nil {
+				log.Printf("Failed to check stock notifications for product %d: %v", productID, err)
+			}
+		}(productID)
+	}
+}
+
+// 発注点を下回った場合は管理者に通知し、在庫が戻った場合は入荷通知を送信
+func (s *stockNotificationService) CheckProduct(productID uint) error {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return err
+	}
+
+	if product.LowStockThreshold > 0 && product.Available <= product.LowStockThreshold {
+		alert := &model.StockAlert{
+			ProductID:   product.ID,
+			ProductName: product.Name,
+			Threshold:   product.LowStockThreshold,
+			Available:   product.Available,
+		}
+		created, err := s.notificationRepo.CreateAlert(alert)
+		if err != nil {
+			return err
+		}
+		if created && len(s.alertRecipients) > 0 {
+			if err := s.notifier.NotifyLowStock(s.alertRecipients, alert); err != nil {
+				log.Printf("Failed to send low stock alert for product %d: %v", product.ID, err)
+			}
+		}
+	} else if err := s.notificationRepo.ResolveAlerts(product.ID); err != nil {
+		return err
+	}
+
+	if product.Available <= 0 {
+		return nil
+	}
+
+	subscriptions, err := s.notificationRepo.ListPendingSubscriptions(product.ID)
+	if err != nil {
+		return err
+	}
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		notified, err := s.notificationRepo.MarkNotified(subscription.ID)
+		if err != nil {
+			return err
+		}
+		if !notified {
+			continue
+		}
+		if err := s.notifier.NotifyBackInStock(subscription, product); err != nil {
+			log.Printf("Failed to send back-in-stock notification %d: %v", subscription.ID, err)
+			if err := s.notificationRepo.ClearNotified(subscription.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 在庫切れの商品の入荷通知を登録
+func (s *stockNotificationService) Subscribe(userID, productID uint) (*model.StockSubscription, error) {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product.Available > 0 {
+		return nil, errors.New("product is in stock")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &model.StockSubscription{
+		ProductID: productID,
+		UserID:    &userID,
+		Email:     strings.ToLower(user.Email),
+		Token:     token,
+	}
+	if err := s.notificationRepo.Subscribe(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *stockNotificationService) Unsubscribe(userID, productID uint) error {
+	deleted, err := s.notificationRepo.DeleteSubscription(productID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("subscription not found")
+	}
+	return nil
+}
+
+// メールの配信停止リンクからの解除
+func (s *stockNotificationService) UnsubscribeByToken(token string) error {
+	if token == "" {
+		return errors.New("invalid token")
+	}
+	deleted, err := s.notificationRepo.DeleteSubscriptionByToken(token)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+func (s *stockNotificationService) ListAlerts(openOnly bool, page, pageSize int) ([]model.StockAlert, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.notificationRepo.ListAlerts(openOnly, page, pageSize)
+}
\ No newline at end of file
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// 失効していないことをRedisに記録しておく期間（この間はDBを参照しない）
+const revocationNegativeCacheTTL = time.Minute
+
+// Redisに記録する「失効していない」の値
+const notRevokedValue = "0"
+
+// RevocationList アクセストークンの失効リスト
+// Redisを優先して参照し、Redisに記録が無い・Redisが使えない場合はDBを参照する
+type RevocationList interface {
+	RevokeToken(jti string, expiresAt time.Time) error
+	IsTokenRevoked(jti string) (bool, error)
+	RevokeUser(userID uint, ttl time.Duration) error
+	IsUserRevoked(userID uint, issuedAt time.Time) (bool, error)
+	DeleteExpired() error
+}
+
+type revocationList struct {
+	redis *redis.Client
+	repo  repository.RevokedTokenRepository
+}
+
+// redisClientはnilでも良い（その場合はDBのみを使用）
+func NewRevocationList(redisClient *redis.Client, repo repository.RevokedTokenRepository) RevocationList {
+	return &revocationList{
+		redis: redisClient,
+		repo:  repo,
+	}
+}
+
+func tokenRevocationKey(jti string) string {
+	return "jti:" + jti
+}
+
+func userRevocationKey(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// 個別トークンの失効（トークンの有効期限まで保持）
+func (l *revocationList) RevokeToken(jti string, expiresAt time.Time) error {
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return nil
+	}
+	return l.store(tokenRevocationKey(jti), now, expiresAt)
+}
+
+func (l *revocationList) IsTokenRevoked(jti string) (bool, error) {
+	revokedAt, err := l.lookup(tokenRevocationKey(jti))
+	if err != nil {
+		return false, err
+	}
+	return revokedAt != nil, nil
+}
+
+// ユーザー単位の失効（この時点より前に発行されたトークンを全て無効化）
+// ttlには発行済みアクセストークンの最大有効期限を指定する
+func (l *revocationList) RevokeUser(userID uint, ttl time.Duration) error {
+	now := time.Now()
+	return l.store(userRevocationKey(userID), now, now.Add(ttl))
+}
+
+func (l *revocationList) IsUserRevoked(userID uint, issuedAt time.Time) (bool, error) {
+	revokedAt, err := l.lookup(userRevocationKey(userID))
+	if err != nil {
+		return false, err
+	}
+	// JWTのiatは秒単位のため、失効時刻も秒単位で比較する
+	return revokedAt != nil && issuedAt.Unix() < revokedAt.Unix(), nil
+}
+
+// 有効期限を過ぎた失効エントリをDBから削除（Redisのエントリは有効期限で消える）
+func (l *revocationList) DeleteExpired() error {
+	return l.repo.DeleteExpired()
+}
+
+// DBには常に書き込み、Redisが使える場合はRedisにも書き込む（「失効していない」の記録は上書きされる）
+func (l *revocationList) store(key string, revokedAt, expiresAt time.Time) error {
+	if err := l.repo.Upsert(&model.RevokedToken{
+		Key:       key,
+		RevokedAt: revokedAt,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return err
+	}
+
+	if l.redis != nil {
+		ctx := context.Background()
+		if err := l.redis.Set(ctx, "revoked:"+key, revokedAt.Unix(), time.Until(expiresAt)).Err(); err != nil {
+			log.Println("Warning: failed to write revocation to Redis:", err)
+		}
+	}
+
+	return nil
+}
+
+// 失効日時を取得（失効していない場合はnil）
+// Redisに記録が無い場合はDBを確認し、結果をRedisに記録する（Redisへの書き込みの失敗・フラッシュ・退避で失効が取り消されないように）
+// 失効していない結果は短期間だけ記録し、失効していないトークンでの認証のたびにDBを参照しないようにする
+func (l *revocationList) lookup(key string) (*time.Time, error) {
+	ctx := context.Background()
+	redisKey := "revoked:" + key
+	if l.redis != nil {
+		value, err := l.redis.Get(ctx, redisKey).Result()
+		if err == nil {
+			if value == notRevokedValue {
+				return nil, nil
+			}
+			if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+				revokedAt := time.Unix(unix, 0)
+				return &revokedAt, nil
+			}
+		} else if err != redis.Nil {
+			log.Println("Warning: failed to read revocation from Redis, falling back to database:", err)
+		}
+	}
+
+	entry, err := l.repo.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		// 同時に失効した場合に失効の記録を上書きしないよう、記録が無い場合のみ書き込む
+		if l.redis != nil {
+			if err := l.redis.SetNX(ctx, redisKey, notRevokedValue, revocationNegativeCacheTTL).Err(); err != nil {
+				log.Println("Warning: failed to write revocation to Redis:", err)
+			}
+		}
+		return nil, nil
+	}
+
+	// Redisから消えていた失効を書き戻す
+	if l.redis != nil {
+		if err := l.redis.Set(ctx, redisKey, entry.RevokedAt.Unix(), time.Until(entry.ExpiresAt)).Err(); err != nil {
+			log.Println("Warning: failed to write revocation to Redis:", err)
+		}
+	}
+	return &entry.RevokedAt, nil
+}
\ No newline at end of file
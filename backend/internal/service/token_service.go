@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/jwtkeys"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// トークンの種別
+const (
+	TokenTypeAccess     = "access"
+	TokenTypeMFAPending = "mfa_pending" // パスワード認証済み・2段階認証待ち
+)
+
+// 2段階認証待ちトークンの有効期限
+const mfaTokenExpiry = 5 * time.Minute
+
+// AccessClaims アクセストークンのクレーム
+type AccessClaims struct {
+	UserID      uint     `json:"user_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	SessionID   string   `json:"sid,omitempty"` // リフレッシュトークンのファミリーID
+	TokenType   string   `json:"token_type"`
+	MFA         bool     `json:"mfa,omitempty"`         // 2段階認証を経て発行されたか
+	Permissions []string `json:"permissions,omitempty"` // 発行時点でロールから算出した権限
+	jwt.RegisteredClaims
+}
+
+type TokenService interface {
+	IssueTokens(user *model.User, mfaVerified bool) (*model.TokenPair, error)
+	Refresh(refreshToken string) (*model.TokenPair, error)
+	ParseAccessToken(tokenString string) (*AccessClaims, error)
+	IssueMFAToken(user *model.User) (string, error)
+	ParseMFAToken(tokenString string) (*AccessClaims, error)
+	Logout(claims *AccessClaims) error
+	RevokeUserSessions(userID uint) error
+	InvalidateAccessTokens(userID uint) error
+	RunCleanup(ctx context.Context, interval time.Duration)
+}
+
+type tokenService struct {
+	userRepo         repository.UserRepository
+	roleRepo         repository.RoleRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	revocations      RevocationList
+	keys             *jwtkeys.KeySet
+	issuer           string
+	accessExpiry     time.Duration
+	refreshExpiry    time.Duration
+}
+
+func NewTokenService(
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	revocations RevocationList,
+	keys *jwtkeys.KeySet,
+	issuer string,
+	accessExpiry time.Duration,
+	refreshExpiry time.Duration,
+) TokenService {
+	return &tokenService{
+		userRepo:         userRepo,
+		roleRepo:         roleRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revocations:      revocations,
+		keys:             keys,
+		issuer:           issuer,
+		accessExpiry:     accessExpiry,
+		refreshExpiry:    refreshExpiry,
+	}
+}
+
+// ログイン時のトークン発行（新しいトークンファミリーを開始）
+func (s *tokenService) IssueTokens(user *model.User, mfaVerified bool) (*model.TokenPair, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(user, familyID, mfaVerified, nil)
+}
+
+// リフレッシュトークンのローテーション
+func (s *tokenService) Refresh(refreshToken string) (*model.TokenPair, error) {
+	current, err := s.refreshTokenRepo.GetByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// 使用済み・失効済みトークンの再利用はファミリー全体を失効させる
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		if err := s.refreshTokenRepo.RevokeFamily(current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, errors.New("refresh token expired")
+	}
+
+	user, err := s.userRepo.GetByID(current.UserID)
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	return s.issue(user, current.FamilyID, current.MFAVerified, current)
+}
+
+// アクセストークンの検証（署名・有効期限・失効リスト）
+func (s *tokenService) ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	claims, err := s.parse(tokenString, TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.revocations.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	revoked, err = s.revocations.IsUserRevoked(claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// 2段階認証待ちトークンの発行（アクセストークンとしては使用できない）
+func (s *tokenService) IssueMFAToken(user *model.User) (string, error) {
+	now := time.Now()
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := AccessClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		TokenType: TokenTypeMFAPending,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   user.Email,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenExpiry)),
+		},
+	}
+
+	return s.sign(claims)
+}
+
+func (s *tokenService) ParseMFAToken(tokenString string) (*AccessClaims, error) {
+	claims, err := s.parse(tokenString, TokenTypeMFAPending)
+	if err != nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	revoked, err := s.revocations.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	return claims, nil
+}
+
+// ログアウト（アクセストークンとセッションのリフレッシュトークンを失効）
+func (s *tokenService) Logout(claims *AccessClaims) error {
+	if err := s.revocations.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" {
+		return s.refreshTokenRepo.RevokeFamily(claims.SessionID)
+	}
+
+	return nil
+}
+
+// ユーザーの全セッションを失効（アカウント削除・権限剥奪時など）
+func (s *tokenService) RevokeUserSessions(userID uint) error {
+	if err := s.refreshTokenRepo.RevokeByUserID(userID); err != nil {
+		return err
+	}
+	return s.InvalidateAccessTokens(userID)
+}
+
+// 発行済みアクセストークンのみ失効（権限変更時。リフレッシュで新しい権限のトークンを取得できる）
+func (s *tokenService) InvalidateAccessTokens(userID uint) error {
+	return s.revocations.RevokeUser(userID, s.accessExpiry)
+}
+
+// 一定間隔で期限切れのリフレッシュトークン・失効エントリを削除（ctxが終了するまで実行）
+func (s *tokenService) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.refreshTokenRepo.DeleteExpired(); err != nil {
+			log.Printf("Failed to delete expired refresh tokens: %v", err)
+		}
+		if err := s.revocations.DeleteExpired(); err != nil {
+			log.Printf("Failed to delete expired token revocations: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 署名と有効期限を検証し、期待する種別のトークンか確認
+func (s *tokenService) parse(tokenString, tokenType string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	// kidに対応する鍵と署名方式が一致するトークンのみ受け付ける
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keys.Keyfunc,
+		jwt.WithValidMethods(s.keys.Algorithms()),
+		jwt.WithIssuer(s.issuer),
+	)
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	if claims.TokenType != tokenType {
+		return nil, errors.New("invalid token type")
+	}
+
+	if claims.UserID == 0 || claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return nil, errors.New("invalid token claims")
+	}
+
+	return claims, nil
+}
+
+func (s *tokenService) sign(claims AccessClaims) (string, error) {
+	claims.Issuer = s.issuer
+	return s.keys.Sign(claims)
+}
+
+func (s *tokenService) issue(user *model.User, familyID string, mfaVerified bool, previous *model.RefreshToken) (*model.TokenPair, error) {
+	now := time.Now()
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.roleRepo.GetPermissionNamesByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// JWTトークン生成
+	claims := AccessClaims{
+		UserID:      user.ID,
+		Email:       user.Email,
+		Role:        user.Role,
+		SessionID:   familyID,
+		TokenType:   TokenTypeAccess,
+		MFA:         mfaVerified,
+		Permissions: permissions,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   user.Email,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessExpiry)),
+		},
+	}
+
+	accessToken, err := s.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// リフレッシュトークン生成（DBにはハッシュ値のみ保存）
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &model.RefreshToken{
+		UserID:      user.ID,
+		TokenHash:   hashToken(refreshToken),
+		FamilyID:    familyID,
+		MFAVerified: mfaVerified,
+		ExpiresAt:   now.Add(s.refreshExpiry),
+	}
+	if err := s.refreshTokenRepo.Create(record); err != nil {
+		return nil, err
+	}
+
+	// ローテーション元を使用済みにする（同時リフレッシュで先を越された場合は再利用とみなす）
+	if previous != nil {
+		ok, err := s.refreshTokenRepo.MarkUsed(previous.ID, record.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if err := s.refreshTokenRepo.RevokeFamily(familyID); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("refresh token reuse detected")
+		}
+	}
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessExpiry.Seconds()),
+	}, nil
+}
+
+// 暗号学的に安全なランダム文字列を生成
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// トークンのSHA-256ハッシュ（16進数）
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
\ No newline at end of file
@@ -2,32 +2,71 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/Naonao3/EC-site/backend/internal/model"
 	"github.com/Naonao3/EC-site/backend/internal/repository"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/Naonao3/EC-site/backend/pkg/mailer"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTokenTTL     = 1 * time.Hour
+	emailVerificationTokenTTL = 24 * time.Hour
 )
 
 type UserService interface {
 	Register(email, password, name string) (*model.User, error)
-	Login(email, password string) (string, error)
+	Login(email, password, ipAddress, userAgent string) (*model.LoginResult, error)
+	RefreshToken(refreshToken string) (*model.TokenPair, error)
+	Logout(claims *AccessClaims) error
 	GetUserByID(id uint) (*model.User, error)
 	GetUserByEmail(email string) (*model.User, error)
 	UpdateUser(user *model.User) error
 	DeleteUser(id uint) error
 	ListUsers(page, pageSize int) ([]model.User, int64, error)
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+	SendVerificationEmail(userID uint) error
+	VerifyEmail(token string) error
+	UnlockUser(id uint) error
+	ListLoginAttempts(userID uint, page, pageSize int) ([]model.LoginAttempt, int64, error)
 }
 
 type userService struct {
-	userRepo  repository.UserRepository
-	jwtSecret string
+	userRepo         repository.UserRepository
+	userTokenRepo    repository.UserTokenRepository
+	loginAttemptRepo repository.LoginAttemptRepository
+	apiKeyRepo       repository.APIKeyRepository
+	tokenService     TokenService
+	loginThrottle    LoginThrottle
+	mailer           mailer.Mailer
+	frontendURL      string
 }
 
-func NewUserService(userRepo repository.UserRepository, jwtSecret string) UserService {
+func NewUserService(
+	userRepo repository.UserRepository,
+	userTokenRepo repository.UserTokenRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	tokenService TokenService,
+	loginThrottle LoginThrottle,
+	mailer mailer.Mailer,
+	frontendURL string,
+) UserService {
 	return &userService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:         userRepo,
+		userTokenRepo:    userTokenRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		apiKeyRepo:       apiKeyRepo,
+		tokenService:     tokenService,
+		loginThrottle:    loginThrottle,
+		mailer:           mailer,
+		frontendURL:      frontendURL,
 	}
 }
 
@@ -55,35 +94,129 @@ func (s *userService) Register(email, password, name string) (*model.User, error
 		return nil, err
 	}
 
+	// 確認メール送信（失敗しても登録自体は成功とする）
+	if err := s.SendVerificationEmail(user.ID); err != nil {
+		log.Println("Warning: failed to send verification email:", err)
+	}
+
 	return user, nil
 }
 
-func (s *userService) Login(email, password string) (string, error) {
+func (s *userService) Login(email, password, ipAddress, userAgent string) (*model.LoginResult, error) {
+	attempt := &model.LoginAttempt{
+		Email:     normalizeEmail(email),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+
+	// 連続失敗による待ち時間・IPブロックの確認
+	if err := s.loginThrottle.Check(email, ipAddress); err != nil {
+		attempt.Reason = "throttled"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+		return nil, err
+	}
+
 	// ユーザー取得
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		// 応答時間で登録済みのメールアドレスを推測されないよう、未登録の場合もパスワードを照合する
+		checkDummyPassword(password)
+
+		attempt.Reason = "unknown_email"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+		if _, err := s.loginThrottle.RegisterFailure(email, ipAddress); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid email or password")
+	}
+	attempt.UserID = &user.ID
+
+	// アカウントロックの確認
+	if user.IsLocked() {
+		attempt.Reason = "locked"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+		return nil, errors.New("account is temporarily locked")
 	}
 
 	// パスワード検証
 	if err := user.CheckPassword(password); err != nil {
-		return "", errors.New("invalid email or password")
+		attempt.Reason = "invalid_password"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+
+		lockedUntil, err := s.loginThrottle.RegisterFailure(email, ipAddress)
+		if err != nil {
+			return nil, err
+		}
+		if lockedUntil != nil {
+			user.LockedUntil = lockedUntil
+			if err := s.userRepo.Update(user); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("account is temporarily locked")
+		}
+		return nil, errors.New("invalid email or password")
 	}
 
-	// JWTトークン生成
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	})
+	// 2段階認証が有効な場合は、トークンの代わりにMFAトークンを返す
+	if user.TOTPEnabled {
+		attempt.Reason = "mfa_required"
+		recordLoginAttempt(s.loginAttemptRepo, attempt)
+
+		mfaToken, err := s.tokenService.IssueMFAToken(user)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	attempt.Success = true
+	recordLoginAttempt(s.loginAttemptRepo, attempt)
+
+	// 失敗カウンターとロックの解除
+	if err := s.loginThrottle.Reset(email); err != nil {
+		return nil, err
+	}
+	if user.LockedUntil != nil {
+		user.LockedUntil = nil
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+	}
 
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	// アクセストークン・リフレッシュトークン発行
+	tokens, err := s.tokenService.IssueTokens(user, false)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return &model.LoginResult{Tokens: tokens, User: user}, nil
+}
 
-	return tokenString, nil
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// 登録済みユーザーのパスワード照合と同じ時間をかける（結果は使わない）
+func checkDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
+// ログイン試行履歴の記録（失敗してもログインは継続）
+func recordLoginAttempt(repo repository.LoginAttemptRepository, attempt *model.LoginAttempt) {
+	if err := repo.Create(attempt); err != nil {
+		log.Println("Warning: failed to record login attempt:", err)
+	}
+}
+
+func (s *userService) RefreshToken(refreshToken string) (*model.TokenPair, error) {
+	return s.tokenService.Refresh(refreshToken)
+}
+
+func (s *userService) Logout(claims *AccessClaims) error {
+	return s.tokenService.Logout(claims)
 }
 
 func (s *userService) GetUserByID(id uint) (*model.User, error) {
@@ -96,12 +229,37 @@ func (s *userService) GetUserByEmail(email string) (*model.User, error) {
 
 func (s *userService) UpdateUser(user *model.User) error {
 	// ユーザーの存在確認
-	_, err := s.userRepo.GetByID(user.ID)
+	existing, err := s.userRepo.GetByID(user.ID)
 	if err != nil {
 		return err
 	}
 
-	return s.userRepo.Update(user)
+	// 更新可能な項目のみ反映（パスワード・ロール・確認状態は変更させない）
+	emailChanged := user.Email != "" && user.Email != existing.Email
+	if emailChanged {
+		if other, _ := s.userRepo.GetByEmail(user.Email); other != nil {
+			return errors.New("email already exists")
+		}
+		existing.Email = user.Email
+		existing.EmailVerifiedAt = nil
+	}
+	if user.Name != "" {
+		existing.Name = user.Name
+	}
+
+	if err := s.userRepo.Update(existing); err != nil {
+		return err
+	}
+	*user = *existing
+
+	// メールアドレス変更時は再確認
+	if emailChanged {
+		if err := s.SendVerificationEmail(user.ID); err != nil {
+			log.Println("Warning: failed to send verification email:", err)
+		}
+	}
+
+	return nil
 }
 
 func (s *userService) DeleteUser(id uint) error {
@@ -111,7 +269,12 @@ func (s *userService) DeleteUser(id uint) error {
 		return err
 	}
 
-	return s.userRepo.Delete(id)
+	if err := s.userRepo.Delete(id); err != nil {
+		return err
+	}
+
+	// 削除したユーザーの発行済みトークンを全て失効
+	return s.tokenService.RevokeUserSessions(id)
 }
 
 func (s *userService) ListUsers(page, pageSize int) ([]model.User, int64, error) {
@@ -123,4 +286,191 @@ func (s *userService) ListUsers(page, pageSize int) ([]model.User, int64, error)
 	}
 
 	return s.userRepo.List(page, pageSize)
+}
+
+// アカウントロック解除（管理者用）
+func (s *userService) UnlockUser(id uint) error {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.loginThrottle.Reset(user.Email); err != nil {
+		return err
+	}
+
+	user.LockedUntil = nil
+	return s.userRepo.Update(user)
+}
+
+// ログイン試行履歴取得（管理者用）
+func (s *userService) ListLoginAttempts(userID uint, page, pageSize int) ([]model.LoginAttempt, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.loginAttemptRepo.ListByUserID(userID, page, pageSize)
+}
+
+// パスワードリセットメール送信
+// ユーザーの存在有無を推測されないよう、未登録のメールアドレスでもエラーを返さない
+func (s *userService) RequestPasswordReset(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := s.createUserToken(user.ID, model.UserTokenPurposePasswordReset, passwordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.frontendURL, url.QueryEscape(token))
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "パスワード再設定のご案内",
+		Body: fmt.Sprintf(
+			"%s 様\n\n以下のリンクからパスワードを再設定してください。\n%s\n\nこのリンクの有効期限は%d分です。\nお心当たりがない場合は、このメールを破棄してください。\n",
+			user.Name, link, int(passwordResetTokenTTL.Minutes()),
+		),
+	})
+}
+
+// パスワード再設定
+func (s *userService) ResetPassword(token, newPassword string) error {
+	userToken, err := s.consumeUserToken(token, model.UserTokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := user.HashPassword(newPassword); err != nil {
+		return err
+	}
+
+	// リセットメールを受け取れた時点でメールアドレスの所有も確認できている
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	// パスワードを再設定したらログインロックも解除
+	user.LockedUntil = nil
+	if err := s.loginThrottle.Reset(user.Email); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	// パスワード変更後は既存のセッションとAPIキーを全て失効（漏えいしたキーで操作を続けられないように）
+	if _, err := s.apiKeyRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+	return s.tokenService.RevokeUserSessions(user.ID)
+}
+
+// メールアドレス確認メール送信
+func (s *userService) SendVerificationEmail(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsEmailVerified() {
+		return errors.New("email already verified")
+	}
+
+	token, err := s.createUserToken(user.ID, model.UserTokenPurposeEmailVerification, emailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.frontendURL, url.QueryEscape(token))
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "メールアドレスの確認",
+		Body: fmt.Sprintf(
+			"%s 様\n\nご登録ありがとうございます。\n以下のリンクからメールアドレスの確認を完了してください。\n%s\n\nこのリンクの有効期限は%d時間です。\n",
+			user.Name, link, int(emailVerificationTokenTTL.Hours()),
+		),
+	})
+}
+
+// メールアドレス確認
+func (s *userService) VerifyEmail(token string) error {
+	userToken, err := s.consumeUserToken(token, model.UserTokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return s.userRepo.Update(user)
+}
+
+// ワンタイムトークンを発行（同じ用途の古いトークンは無効化）
+func (s *userService) createUserToken(userID uint, purpose string, ttl time.Duration) (string, error) {
+	if err := s.userTokenRepo.InvalidateByUser(userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.userTokenRepo.Create(&model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ワンタイムトークンを検証して使用済みにする
+func (s *userService) consumeUserToken(token, purpose string) (*model.UserToken, error) {
+	userToken, err := s.userTokenRepo.GetByHash(hashToken(token), purpose)
+	if err != nil {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	if userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	ok, err := s.userTokenRepo.MarkUsed(userToken.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	return userToken, nil
 }
\ No newline at end of file
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/mailer"
+)
+
+// テスト用のリポジトリ・サービス（使うメソッドのみ実装）
+type fakeUserRepository struct {
+	repository.UserRepository
+	mu    sync.Mutex
+	users map[uint]*model.User
+}
+
+func (r *fakeUserRepository) Create(user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.ID = uint(len(r.users) + 1)
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepository) GetByID(id uint) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepository) GetByEmail(email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+// TOTPのタイムステップは更新しない（userRepositoryと同じ）
+func (r *fakeUserRepository) Update(user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *user
+	if existing, ok := r.users[user.ID]; ok {
+		copied.TOTPLastStep = existing.TOTPLastStep
+	}
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepository) UseTOTPStep(id uint, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok || user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+type fakeUserTokenRepository struct {
+	mu     sync.Mutex
+	tokens []*model.UserToken
+}
+
+func (r *fakeUserTokenRepository) Create(token *model.UserToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = uint(len(r.tokens) + 1)
+	copied := *token
+	r.tokens = append(r.tokens, &copied)
+	return nil
+}
+
+func (r *fakeUserTokenRepository) GetByHash(tokenHash, purpose string) (*model.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash && token.Purpose == purpose {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("token not found")
+}
+
+func (r *fakeUserTokenRepository) MarkUsed(id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeUserTokenRepository) InvalidateByUser(userID uint, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// 発行済みトークンの有効期限を過去にする
+func (r *fakeUserTokenRepository) expireAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
+
+type fakeAPIKeyRepository struct {
+	repository.APIKeyRepository
+	mu   sync.Mutex
+	keys []model.APIKey
+}
+
+func (r *fakeAPIKeyRepository) DeleteByUserID(userID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	kept := r.keys[:0]
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			deleted++
+			continue
+		}
+		kept = append(kept, key)
+	}
+	r.keys = kept
+	return deleted, nil
+}
+
+func (r *fakeAPIKeyRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.keys)
+}
+
+type fakeLoginThrottle struct {
+	LoginThrottle
+}
+
+func (t *fakeLoginThrottle) Reset(email string) error {
+	return nil
+}
+
+type fakeTokenService struct {
+	TokenService
+	mu      sync.Mutex
+	revoked []uint
+}
+
+func (s *fakeTokenService) IssueTokens(user *model.User, mfaVerified bool) (*model.TokenPair, error) {
+	return &model.TokenPair{AccessToken: "access-token", RefreshToken: "refresh-token"}, nil
+}
+
+func (s *fakeTokenService) RevokeUserSessions(userID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+var mailTokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+// 最後に送信したメールのリンクからトークンを取り出す
+func lastMailToken(t *testing.T, mail *mailer.MemoryMailer) string {
+	t.Helper()
+	messages := mail.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no mail sent")
+	}
+	match := mailTokenPattern.FindStringSubmatch(messages[len(messages)-1].Body)
+	if match == nil {
+		t.Fatal("mail does not contain a token link")
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+type userServiceFixture struct {
+	service   *userService
+	users     *fakeUserRepository
+	tokens    *fakeUserTokenRepository
+	apiKeys   *fakeAPIKeyRepository
+	sessions  *fakeTokenService
+	mail      *mailer.MemoryMailer
+	userID    uint
+	userEmail string
+}
+
+func newUserServiceFixture(t *testing.T) *userServiceFixture {
+	t.Helper()
+	user := &model.User{ID: 1, Email: "user@example.com", Name: "User"}
+	if err := user.HashPassword("old-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &userServiceFixture{
+		users:     &fakeUserRepository{users: map[uint]*model.User{user.ID: user}},
+		tokens:    &fakeUserTokenRepository{},
+		apiKeys:   &fakeAPIKeyRepository{keys: []model.APIKey{{ID: 1, UserID: user.ID, Name: "script"}}},
+		sessions:  &fakeTokenService{},
+		mail:      mailer.NewMemoryMailer(),
+		userID:    user.ID,
+		userEmail: user.Email,
+	}
+	f.service = NewUserService(f.users, f.tokens, nil, f.apiKeys, f.sessions, &fakeLoginThrottle{}, f.mail, "http://localhost:3000").(*userService)
+	return f
+}
+
+func TestPasswordResetToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// トークン発行後・使用前の操作
+		prepare func(t *testing.T, f *userServiceFixture, token string) string
+		wantErr bool
+	}{
+		{
+			name:    "valid token",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string { return token },
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string {
+				f.tokens.expireAll()
+				return token
+			},
+			wantErr: true,
+		},
+		{
+			name: "reused token",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string {
+				if err := f.service.ResetPassword(token, "first-password"); err != nil {
+					t.Fatalf("first reset failed: %v", err)
+				}
+				return token
+			},
+			wantErr: true,
+		},
+		{
+			name: "token replaced by a newer request",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string {
+				if err := f.service.RequestPasswordReset(f.userEmail); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantErr: true,
+		},
+		{
+			name: "email verification token",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string {
+				if err := f.service.SendVerificationEmail(f.userID); err != nil {
+					t.Fatal(err)
+				}
+				return lastMailToken(t, f.mail)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown token",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string { return "unknown" },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUserServiceFixture(t)
+			if err := f.service.RequestPasswordReset(f.userEmail); err != nil {
+				t.Fatal(err)
+			}
+			token := tt.prepare(t, f, lastMailToken(t, f.mail))
+			revokedBefore := len(f.sessions.revoked)
+
+			err := f.service.ResetPassword(token, "new-password")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResetPassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			user, _ := f.users.GetByID(f.userID)
+			changed := user.CheckPassword("new-password") == nil
+			if changed == tt.wantErr {
+				t.Errorf("password changed = %v, want %v", changed, !tt.wantErr)
+			}
+			if !tt.wantErr && len(f.sessions.revoked) == revokedBefore {
+				t.Error("sessions were not revoked after password reset")
+			}
+			if !tt.wantErr && f.apiKeys.count() != 0 {
+				t.Error("api keys were not revoked after password reset")
+			}
+		})
+	}
+}
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	f := newUserServiceFixture(t)
+	if err := f.service.RequestPasswordReset("unknown@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v, want nil", err)
+	}
+	if len(f.mail.Messages()) != 0 {
+		t.Error("mail was sent for an unknown email")
+	}
+}
+
+func TestEmailVerificationToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		prepare      func(t *testing.T, f *userServiceFixture, token string) string
+		wantErr      bool
+		wantVerified bool
+	}{
+		{
+			name:         "valid token",
+			prepare:      func(t *testing.T, f *userServiceFixture, token string) string { return token },
+			wantVerified: true,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string {
+				f.tokens.expireAll()
+				return token
+			},
+			wantErr: true,
+		},
+		{
+			name: "reused token",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string {
+				if err := f.service.VerifyEmail(token); err != nil {
+					t.Fatalf("first verification failed: %v", err)
+				}
+				return token
+			},
+			wantErr:      true,
+			wantVerified: true,
+		},
+		{
+			name: "password reset token",
+			prepare: func(t *testing.T, f *userServiceFixture, token string) string {
+				if err := f.service.RequestPasswordReset(f.userEmail); err != nil {
+					t.Fatal(err)
+				}
+				return lastMailToken(t, f.mail)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUserServiceFixture(t)
+			if err := f.service.SendVerificationEmail(f.userID); err != nil {
+				t.Fatal(err)
+			}
+			token := tt.prepare(t, f, lastMailToken(t, f.mail))
+
+			err := f.service.VerifyEmail(token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyEmail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			user, _ := f.users.GetByID(f.userID)
+			if user.IsEmailVerified() != tt.wantVerified {
+				t.Errorf("email verified = %v, want %v", user.IsEmailVerified(), tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestSendVerificationEmailAlreadyVerified(t *testing.T) {
+	f := newUserServiceFixture(t)
+	now := time.Now()
+	f.users.users[f.userID].EmailVerifiedAt = &now
+
+	if err := f.service.SendVerificationEmail(f.userID); err == nil {
+		t.Fatal("SendVerificationEmail() error = nil, want error for a verified email")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+)
+
+type VariantService interface {
+	ListOptions(productID uint) ([]model.ProductOption, error)
+	SetOptions(productID uint, options []model.ProductOption) ([]model.ProductOption, error)
+	ListVariants(productID uint) ([]model.ProductVariant, error)
+	CreateVariant(productID uint, variant *model.ProductVariant, source model.StockMovementSource) error
+	UpdateVariant(productID uint, variant *model.ProductVariant, source model.StockMovementSource) error
+	DeleteVariant(productID, id uint, source model.StockMovementSource) error
+	UpdateVariantStock(productID, id uint, quantity int, source model.StockMovementSource) (*model.ProductVariant, error)
+}
+
+type variantService struct {
+	variantRepo        repository.VariantRepository
+	productRepo        repository.ProductRepository
+	movementRepo       repository.StockMovementRepository
+	stockNotifications StockNotificationService
+}
+
+func NewVariantService(
+	variantRepo repository.VariantRepository,
+	productRepo repository.ProductRepository,
+	movementRepo repository.StockMovementRepository,
+	stockNotifications StockNotificationService,
+) VariantService {
+	return &variantService{
+		variantRepo:        variantRepo,
+		productRepo:        productRepo,
+		movementRepo:       movementRepo,
+		stockNotifications: stockNotifications,
+	}
+}
+
+func (s *variantService) ListOptions(productID uint) ([]model.ProductOption, error) {
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+	return s.variantRepo.ListOptions(productID)
+}
+
+// 商品の選択肢を置き換える（既存のバリエーションが使っている値は削除できない）
+func (s *variantService) SetOptions(productID uint, options []model.ProductOption) ([]model.ProductOption, error) {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(options))
+	for i := range options {
+		option := &options[i]
+		option.Name = strings.TrimSpace(option.Name)
+		if option.Name == "" {
+			return nil, errors.New("option name is required")
+		}
+		if names[option.Name] {
+			return nil, fmt.Errorf("duplicate option: %s", option.Name)
+		}
+		names[option.Name] = true
+
+		values := make([]string, 0, len(option.Values))
+		seen := make(map[string]bool, len(option.Values))
+		for _, value := range option.Values {
+			value = strings.TrimSpace(value)
+			if value == "" || seen[value] {
+				continue
+			}
+			seen[value] = true
+			values = append(values, value)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("option %s must have at least one value", option.Name)
+		}
+		option.Values = values
+		option.Position = i
+	}
+
+	for i := range product.Variants {
+		if err := validateVariantOptions(options, product.Variants[i].Options); err != nil {
+			return nil, fmt.Errorf("variant %s: %w", product.Variants[i].SKU, err)
+		}
+	}
+
+	if err := s.variantRepo.ReplaceOptions(productID, options); err != nil {
+		return nil, err
+	}
+
+	return s.variantRepo.ListOptions(productID)
+}
+
+func (s *variantService) ListVariants(productID uint) ([]model.ProductVariant, error) {
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+	return s.variantRepo.ListByProductID(productID)
+}
+
+// バリエーション作成（初期在庫はsourceの操作として変動履歴に記録）
+func (s *variantService) CreateVariant(productID uint, variant *model.ProductVariant, source model.StockMovementSource) error {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return err
+	}
+
+	// ライセンスで価格を決める商品とは併用できない
+	if product.HasLicenses() {
+		return errors.New("products sold with licenses cannot have variants")
+	}
+
+	variant.ID = 0
+	variant.ProductID = productID
+	variant.Reserved = 0
+	if err := s.validateVariant(product, variant); err != nil {
+		return err
+	}
+
+	if err := s.variantRepo.Create(variant); err != nil {
+		return err
+	}
+
+	// 最初のバリエーションで在庫の管理をバリエーションごとに切り替え、各倉庫の商品単位の在庫を履歴上で0にする
+	if !product.HasVariants() && product.Stock != 0 {
+		writeOff := source.WithDefaults(model.StockMovementAdjustment, "")
+		writeOff.Reason = model.StockReasonVariantsEnabled
+		if err := s.movementRepo.WriteOff(productID, nil, writeOff); err != nil {
+			return err
+		}
+	}
+
+	// 在庫は作成時には保存されないため、変動履歴と合わせて反映
+	if variant.Stock != 0 {
+		movement := source.WithDefaults(model.StockMovementAdjustment, model.StockReasonInitial).Movement(productID, &variant.ID, variant.Stock)
+		if err := s.movementRepo.Adjust(movement); err != nil {
+			return err
+		}
+	}
+
+	return s.syncProductSummary(productID)
+}
+
+// バリエーション更新（在庫の変更はsourceの操作として変動履歴に記録）
+func (s *variantService) UpdateVariant(productID uint, variant *model.ProductVariant, source model.StockMovementSource) error {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return err
+	}
+
+	existing := product.FindVariant(variant.ID)
+	if existing == nil {
+		return errors.New("variant not found")
+	}
+
+	variant.ProductID = productID
+	variant.CreatedAt = existing.CreatedAt
+	// 確保数は在庫予約から管理される値を維持
+	variant.Reserved = existing.Reserved
+	if err := s.validateVariant(product, variant); err != nil {
+		return err
+	}
+	if variant.Stock < variant.Reserved {
+		return errors.New("variant stock cannot be less than reserved quantity")
+	}
+
+	if err := s.variantRepo.Update(variant); err != nil {
+		return err
+	}
+
+	// 在庫は更新されないため、変更された差分を変動履歴と合わせて反映
+	if delta := variant.Stock - existing.Stock; delta != 0 {
+		movement := source.WithDefaults(model.StockMovementAdjustment, model.StockReasonProductEdit).Movement(productID, &variant.ID, delta)
+		if err := s.movementRepo.Adjust(movement); err != nil {
+			return err
+		}
+	}
+
+	return s.syncProductSummary(productID)
+}
+
+// バリエーション削除（各倉庫に残っていた在庫は削除として変動履歴に記録）
+func (s *variantService) DeleteVariant(productID, id uint, source model.StockMovementSource) error {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return err
+	}
+	variant := product.FindVariant(id)
+	if variant == nil {
+		return errors.New("variant not found")
+	}
+
+	if err := s.variantRepo.Delete(productID, id); err != nil {
+		return err
+	}
+
+	if variant.Stock != 0 {
+		writeOff := source.WithDefaults(model.StockMovementAdjustment, "")
+		writeOff.Reason = model.StockReasonVariantDeleted
+		if err := s.movementRepo.WriteOff(productID, &variant.ID, writeOff); err != nil {
+			return err
+		}
+	}
+
+	// 最後のバリエーションを削除した場合は商品単位の管理に戻り、商品に残る在庫を既定の倉庫の商品単位の在庫にする
+	if len(product.Variants) == 1 && product.Stock != 0 {
+		movement := source.WithDefaults(model.StockMovementAdjustment, "").Movement(productID, nil, product.Stock)
+		movement.Reason = model.StockReasonVariantsRemoved
+		if err := s.movementRepo.Record(movement); err != nil {
+			return err
+		}
+	}
+
+	return s.syncProductSummary(productID)
+}
+
+// バリエーションの在庫を増減（理由の指定が無い場合は誤りの訂正として記録）
+func (s *variantService) UpdateVariantStock(productID, id uint, quantity int, source model.StockMovementSource) (*model.ProductVariant, error) {
+	if source.Reason == "" {
+		source.Reason = model.StockReasonCorrection
+	}
+	if err := applyStockAdjustmentReason(&source); err != nil {
+		return nil, err
+	}
+
+	variant, err := s.variantRepo.GetByID(productID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 在庫不足チェック（未決済の注文で確保されている分は減らせない）
+	if variant.Stock+quantity < variant.Reserved {
+		return nil, errors.New("insufficient stock")
+	}
+
+	if err := s.movementRepo.Adjust(source.Movement(productID, &id, quantity)); err != nil {
+		return nil, err
+	}
+	if err := s.syncProductSummary(productID); err != nil {
+		return nil, err
+	}
+
+	return s.variantRepo.GetByID(productID, id)
+}
+
+// 商品の価格・在庫をバリエーションから再計算し、在庫の通知を確認
+func (s *variantService) syncProductSummary(productID uint) error {
+	if err := s.variantRepo.SyncProductSummary(productID); err != nil {
+		return err
+	}
+	s.stockNotifications.ProductStockChanged(productID)
+	return nil
+}
+
+func (s *variantService) validateVariant(product *model.Product, variant *model.ProductVariant) error {
+	variant.SKU = strings.TrimSpace(variant.SKU)
+	if variant.SKU == "" {
+		return errors.New("sku is required")
+	}
+	if variant.Price <= 0 {
+		return errors.New("variant price must be greater than 0")
+	}
+	if variant.Stock < 0 {
+		return errors.New("variant stock cannot be negative")
+	}
+
+	existing, err := s.variantRepo.GetBySKU(variant.SKU)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != variant.ID {
+		return errors.New("sku already exists")
+	}
+
+	if err := validateVariantOptions(product.Options, variant.Options); err != nil {
+		return err
+	}
+
+	// 同じ選択肢の組み合わせのバリエーションは1つだけ
+	for i := range product.Variants {
+		other := product.Variants[i]
+		if other.ID != variant.ID && sameOptions(other.Options, variant.Options) {
+			return fmt.Errorf("variant with the same options already exists: %s", other.SKU)
+		}
+	}
+
+	if variant.Name == "" {
+		variant.Name = variantName(product.Options, variant.Options)
+	}
+
+	return nil
+}
+
+// バリエーションが商品の全ての選択肢に定義済みの値を持つか
+func validateVariantOptions(options []model.ProductOption, values map[string]string) error {
+	if len(values) != len(options) {
+		return errors.New("variant must specify a value for every option")
+	}
+
+	for _, option := range options {
+		value, ok := values[option.Name]
+		if !ok {
+			return fmt.Errorf("missing value for option: %s", option.Name)
+		}
+		valid := false
+		for _, v := range option.Values {
+			if v == value {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid value for option %s: %s", option.Name, value)
+		}
+	}
+
+	return nil
+}
+
+func sameOptions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if b[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// 選択肢の順に値を並べた表示名（例: "L / PNG"）
+func variantName(options []model.ProductOption, values map[string]string) string {
+	parts := make([]string, 0, len(options))
+	for _, option := range options {
+		parts = append(parts, values[option.Name])
+	}
+	return strings.Join(parts, " / ")
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Naonao3/EC-site/backend/internal/model"
+	"github.com/Naonao3/EC-site/backend/internal/repository"
+	"github.com/Naonao3/EC-site/backend/pkg/jpaddress"
+	"gorm.io/gorm"
+)
+
+// 起動時に作成する既定の倉庫のコード
+const defaultWarehouseCode = "DEFAULT"
+
+type WarehouseService interface {
+	CreateWarehouse(warehouse *model.Warehouse) error
+	UpdateWarehouse(warehouse *model.Warehouse) error
+	ListWarehouses() ([]model.Warehouse, error)
+	ListWarehouseStock(warehouseID uint, page, pageSize int) ([]model.WarehouseStock, int64, error)
+	ListProductStock(productID uint) ([]model.WarehouseStock, error)
+	TransferStock(fromWarehouseID, toWarehouseID, productID uint, variantID *uint, quantity int, source model.StockMovementSource) ([]model.StockMovement, error)
+	Allocate(items []model.OrderItem, prefecture string) ([]model.WarehouseAllocation, error)
+	ListOrderAllocations(orderID uint) ([]model.InventoryReservation, error)
+	EnsureDefaultWarehouse(prefecture string) (*model.Warehouse, error)
+	WithTx(tx *gorm.DB) WarehouseService
+}
+
+type warehouseService struct {
+	warehouseRepo   repository.WarehouseRepository
+	movementRepo    repository.StockMovementRepository
+	productRepo     repository.ProductRepository
+	orderRepo       repository.OrderRepository
+	reservationRepo repository.ReservationRepository
+}
+
+func NewWarehouseService(
+	warehouseRepo repository.WarehouseRepository,
+	movementRepo repository.StockMovementRepository,
+	productRepo repository.ProductRepository,
+	orderRepo repository.OrderRepository,
+	reservationRepo repository.ReservationRepository,
+) WarehouseService {
+	return &warehouseService{
+		warehouseRepo:   warehouseRepo,
+		movementRepo:    movementRepo,
+		productRepo:     productRepo,
+		orderRepo:       orderRepo,
+		reservationRepo: reservationRepo,
+	}
+}
+
+// トランザクション内で出荷元を決めるサービス（注文の作成と同時にコミット・ロールバックされる）
+func (s *warehouseService) WithTx(tx *gorm.DB) WarehouseService {
+	return &warehouseService{
+		warehouseRepo:   s.warehouseRepo.WithTx(tx),
+		movementRepo:    s.movementRepo,
+		productRepo:     s.productRepo.WithTx(tx),
+		orderRepo:       s.orderRepo.WithTx(tx),
+		reservationRepo: s.reservationRepo.WithTx(tx),
+	}
+}
+
+// 倉庫作成（IsDefaultの場合は既定の倉庫を切り替える）
+func (s *warehouseService) CreateWarehouse(warehouse *model.Warehouse) error {
+	warehouse.ID = 0
+	if err := s.validateWarehouse(warehouse); err != nil {
+		return err
+	}
+	if warehouse.IsDefault && !warehouse.Active {
+		return errors.New("default warehouse must be active")
+	}
+
+	if err := s.warehouseRepo.Create(warehouse); err != nil {
+		return err
+	}
+	if warehouse.IsDefault {
+		return s.warehouseRepo.SetDefault(warehouse.ID)
+	}
+	return nil
+}
+
+// 倉庫更新（既定の倉庫は別の倉庫を既定にすることでのみ切り替えられる）
+func (s *warehouseService) UpdateWarehouse(warehouse *model.Warehouse) error {
+	existing, err := s.warehouseRepo.GetByID(warehouse.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.validateWarehouse(warehouse); err != nil {
+		return err
+	}
+
+	if existing.IsDefault && !warehouse.IsDefault {
+		return errors.New("cannot unset the default warehouse; set another warehouse as default")
+	}
+	if warehouse.IsDefault && !warehouse.Active {
+		return errors.New("default warehouse must be active")
+	}
+
+	warehouse.CreatedAt = existing.CreatedAt
+	if err := s.warehouseRepo.Update(warehouse); err != nil {
+		return err
+	}
+	if warehouse.IsDefault && !existing.IsDefault {
+		return s.warehouseRepo.SetDefault(warehouse.ID)
+	}
+	return nil
+}
+
+func (s *warehouseService) ListWarehouses() ([]model.Warehouse, error) {
+	return s.warehouseRepo.List()
+}
+
+// 倉庫の在庫一覧
+func (s *warehouseService) ListWarehouseStock(warehouseID uint, page, pageSize int) ([]model.WarehouseStock, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	if _, err := s.warehouseRepo.GetByID(warehouseID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.warehouseRepo.ListStocksByWarehouse(warehouseID, page, pageSize)
+}
+
+// 商品の倉庫ごとの在庫
+func (s *warehouseService) ListProductStock(productID uint) ([]model.WarehouseStock, error) {
+	if _, err := s.productRepo.GetByID(productID); err != nil {
+		return nil, err
+	}
+	return s.warehouseRepo.ListStocksByProduct(productID)
+}
+
+// 倉庫間の在庫の移動（バリエーションのある商品はバリエーションを指定）
+func (s *warehouseService) TransferStock(fromWarehouseID, toWarehouseID, productID uint, variantID *uint, quantity int, source model.StockMovementSource) ([]model.StockMovement, error) {
+	if quantity <= 0 {
+		return nil, errors.New("quantity must be positive")
+	}
+	if fromWarehouseID == toWarehouseID {
+		return nil, errors.New("source and destination warehouses must differ")
+	}
+	if len(source.Note) > maxStockNoteLength {
+		return nil, errors.New("note must be at most 500 characters")
+	}
+
+	if _, err := s.warehouseRepo.GetByID(fromWarehouseID); err != nil {
+		return nil, err
+	}
+	if _, err := s.warehouseRepo.GetByID(toWarehouseID); err != nil {
+		return nil, err
+	}
+
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if variantID == nil && product.HasVariants() {
+		return nil, errors.New("product has variants; specify variant_id")
+	}
+	if variantID != nil && product.FindVariant(*variantID) == nil {
+		return nil, errors.New("variant not found")
+	}
+
+	return s.movementRepo.Transfer(fromWarehouseID, toWarehouseID, productID, variantID, quantity, source)
+}
+
+// 注文明細の出荷元の倉庫を決める（配送先の都道府県に近い倉庫を優先）
+// 1つの倉庫から全て出荷できる場合はその倉庫、できない場合は商品ごとに近い倉庫から出荷し、
+// 1つの倉庫で足りない商品は近い倉庫から順に分けて出荷する
+func (s *warehouseService) Allocate(items []model.OrderItem, prefecture string) ([]model.WarehouseAllocation, error) {
+	warehouses, err := s.warehouseRepo.ListActive()
+	if err != nil {
+		return nil, err
+	}
+	if len(warehouses) == 0 {
+		return nil, errors.New("no active warehouse")
+	}
+	sortWarehousesByDistance(warehouses, prefecture)
+
+	// 同じ商品（バリエーション）の明細はまとめて在庫を確認する
+	type skuKey struct {
+		productID uint
+		variantID uint
+	}
+	var skus []skuKey
+	itemsBySKU := make(map[skuKey][]model.OrderItem)
+	demand := make(map[skuKey]int)
+	for _, item := range items {
+		key := skuKey{productID: item.ProductID}
+		if item.VariantID != nil {
+			key.variantID = *item.VariantID
+		}
+		if _, ok := itemsBySKU[key]; !ok {
+			skus = append(skus, key)
+		}
+		itemsBySKU[key] = append(itemsBySKU[key], item)
+		demand[key] += item.Quantity
+	}
+
+	available := make(map[skuKey]map[uint]int, len(skus))
+	for _, key := range skus {
+		var variantID *uint
+		if key.variantID != 0 {
+			id := key.variantID
+			variantID = &id
+		}
+		stocks, err := s.warehouseRepo.ListStocks(key.productID, variantID)
+		if err != nil {
+			return nil, err
+		}
+		available[key] = make(map[uint]int, len(stocks))
+		for _, stock := range stocks {
+			available[key][stock.WarehouseID] = stock.Available
+		}
+	}
+
+	// 全ての商品を出荷できる最も近い倉庫
+	var single *model.Warehouse
+	for i := range warehouses {
+		fulfills := true
+		for _, key := range skus {
+			if available[key][warehouses[i].ID] < demand[key] {
+				fulfills = false
+				break
+			}
+		}
+		if fulfills {
+			single = &warehouses[i]
+			break
+		}
+	}
+
+	var allocations []model.WarehouseAllocation
+	for _, key := range skus {
+		candidates := warehouses
+		if single != nil {
+			candidates = []model.Warehouse{*single}
+		} else {
+			// 商品を1つの倉庫から出荷できる場合はその倉庫を優先
+			for i := range warehouses {
+				if available[key][warehouses[i].ID] >= demand[key] {
+					candidates = append([]model.Warehouse{warehouses[i]}, warehouses...)
+					break
+				}
+			}
+		}
+
+		for _, item := range itemsBySKU[key] {
+			remaining := item.Quantity
+			for _, warehouse := range candidates {
+				if remaining == 0 {
+					break
+				}
+				quantity := available[key][warehouse.ID]
+				if quantity > remaining {
+					quantity = remaining
+				}
+				if quantity <= 0 {
+					continue
+				}
+				available[key][warehouse.ID] -= quantity
+				remaining -= quantity
+				allocations = append(allocations, model.WarehouseAllocation{
+					OrderItemID: item.ID,
+					WarehouseID: warehouse.ID,
+					Quantity:    quantity,
+				})
+			}
+			if remaining > 0 {
+				return nil, errors.New("insufficient stock")
+			}
+		}
+	}
+	return allocations, nil
+}
+
+// 注文明細ごとの出荷元の倉庫（在庫予約）
+func (s *warehouseService) ListOrderAllocations(orderID uint) ([]model.InventoryReservation, error) {
+	if _, err := s.orderRepo.GetByID(orderID); err != nil {
+		return nil, err
+	}
+	return s.reservationRepo.ListByOrderID(orderID)
+}
+
+// 既定の倉庫が無い場合は作成し、倉庫の導入前の在庫を既定の倉庫に移行（起動時に実行）
+func (s *warehouseService) EnsureDefaultWarehouse(prefecture string) (*model.Warehouse, error) {
+	warehouse, err := s.warehouseRepo.GetDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	if warehouse == nil {
+		if !jpaddress.IsPrefecture(prefecture) {
+			return nil, errors.New("invalid default warehouse prefecture: " + prefecture)
+		}
+		warehouse = &model.Warehouse{
+			Code:       defaultWarehouseCode,
+			Name:       "既定の倉庫",
+			Prefecture: prefecture,
+			Active:     true,
+		}
+		if err := s.warehouseRepo.Create(warehouse); err != nil {
+			return nil, err
+		}
+		if err := s.warehouseRepo.SetDefault(warehouse.ID); err != nil {
+			return nil, err
+		}
+		warehouse.IsDefault = true
+	}
+
+	if err := s.warehouseRepo.MigrateLegacyStock(warehouse.ID); err != nil {
+		return nil, err
+	}
+	return warehouse, nil
+}
+
+func (s *warehouseService) validateWarehouse(warehouse *model.Warehouse) error {
+	warehouse.Code = strings.TrimSpace(warehouse.Code)
+	warehouse.Name = strings.TrimSpace(warehouse.Name)
+	warehouse.Prefecture = strings.TrimSpace(warehouse.Prefecture)
+
+	if warehouse.Code == "" {
+		return errors.New("warehouse code is required")
+	}
+	if len(warehouse.Code) > 32 {
+		return errors.New("warehouse code must be at most 32 characters")
+	}
+	if warehouse.Name == "" {
+		return errors.New("warehouse name is required")
+	}
+	if len([]rune(warehouse.Name)) > 100 {
+		return errors.New("warehouse name must be at most 100 characters")
+	}
+	if !jpaddress.IsPrefecture(warehouse.Prefecture) {
+		return errors.New("invalid prefecture")
+	}
+
+	exists, err := s.warehouseRepo.CodeExists(warehouse.Code, warehouse.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("warehouse code already exists")
+	}
+	return nil
+}
+
+// 配送先の都道府県に近い順（距離が分からない倉庫は最後）、同じ距離は優先順・IDの順に並べる
+func sortWarehousesByDistance(warehouses []model.Warehouse, prefecture string) {
+	distances := make(map[uint]float64, len(warehouses))
+	for _, warehouse := range warehouses {
+		distance, ok := jpaddress.Distance(prefecture, warehouse.Prefecture)
+		if !ok {
+			distance = math.Inf(1)
+		}
+		distances[warehouse.ID] = distance
+	}
+
+	sort.SliceStable(warehouses, func(i, j int) bool {
+		di, dj := distances[warehouses[i].ID], distances[warehouses[j].ID]
+		if di != dj {
+			return di < dj
+		}
+		if warehouses[i].Priority != warehouses[j].Priority {
+			return warehouses[i].Priority < warehouses[j].Priority
+		}
+		return warehouses[i].ID < warehouses[j].ID
+	})
+}
\ No newline at end of file
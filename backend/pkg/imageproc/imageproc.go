@@ -0,0 +1,184 @@
+// Package imageproc はアップロード画像の検証・縮小・エンコードを標準ライブラリのみで行う。
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// 対応する画像形式（Content-Type）
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+	ContentTypeGIF  = "image/gif"
+)
+
+// 展開後のピクセル数の上限（圧縮爆弾対策）
+const maxPixels = 50_000_000
+
+// ErrUnsupportedFormat 対応していない画像形式
+var ErrUnsupportedFormat = errors.New("unsupported image format (jpeg, png and gif are allowed)")
+
+// DetectContentType 先頭バイトから画像形式を判定（拡張子や申告されたContent-Typeは信用しない）
+func DetectContentType(data []byte) (string, error) {
+	switch contentType := http.DetectContentType(data); contentType {
+	case ContentTypeJPEG, ContentTypePNG, ContentTypeGIF:
+		return contentType, nil
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+// Decode 画像を検証して展開（GIFは先頭フレームのみ）
+func Decode(data []byte) (image.Image, string, error) {
+	contentType, err := DetectContentType(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, "", fmt.Errorf("image dimensions %dx%d are not allowed", cfg.Width, cfg.Height)
+	}
+
+	var img image.Image
+	switch contentType {
+	case ContentTypeJPEG:
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case ContentTypePNG:
+		img, err = png.Decode(bytes.NewReader(data))
+	case ContentTypeGIF:
+		img, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image: %w", err)
+	}
+
+	return img, contentType, nil
+}
+
+// Fit 縦横比を保ったままmaxWidth×maxHeightに収まるよう縮小（拡大はしない）
+func Fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxWidth && height <= maxHeight {
+		return img
+	}
+
+	scale := float64(maxWidth) / float64(width)
+	if s := float64(maxHeight) / float64(height); s < scale {
+		scale = s
+	}
+	newWidth := max(1, int(float64(width)*scale+0.5))
+	newHeight := max(1, int(float64(height)*scale+0.5))
+
+	return Resize(img, newWidth, newHeight)
+}
+
+// Resize 指定サイズに縮小（各出力ピクセルに対応する領域の平均色を取る面積平均法）
+func Resize(img image.Image, width, height int) *image.NRGBA {
+	src := toNRGBA(img)
+	srcBounds := src.Bounds()
+	srcWidth, srcHeight := srcBounds.Dx(), srcBounds.Dy()
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xRatio := float64(srcWidth) / float64(width)
+	yRatio := float64(srcHeight) / float64(height)
+
+	for y := 0; y < height; y++ {
+		y0 := int(float64(y) * yRatio)
+		y1 := max(y0+1, min(srcHeight, int(float64(y+1)*yRatio+0.999)))
+		for x := 0; x < width; x++ {
+			x0 := int(float64(x) * xRatio)
+			x1 := max(x0+1, min(srcWidth, int(float64(x+1)*xRatio+0.999)))
+
+			// アルファ値で重み付けした平均（透明部分の色が滲まないようにする）
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(srcBounds.Min.X+x0, srcBounds.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					pa := uint64(src.Pix[offset+3])
+					r += uint64(src.Pix[offset]) * pa
+					g += uint64(src.Pix[offset+1]) * pa
+					b += uint64(src.Pix[offset+2]) * pa
+					a += pa
+					count++
+					offset += 4
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			if a > 0 {
+				dst.Pix[i] = uint8(r / a)
+				dst.Pix[i+1] = uint8(g / a)
+				dst.Pix[i+2] = uint8(b / a)
+			}
+			dst.Pix[i+3] = uint8(a / count)
+		}
+	}
+
+	return dst
+}
+
+// Encode 画像をエンコード（透過を含む可能性がある形式はPNG、それ以外はJPEG）
+func Encode(img image.Image, sourceContentType string, jpegQuality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if sourceContentType == ContentTypeJPEG {
+		if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), ContentTypeJPEG, nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), ContentTypePNG, nil
+}
+
+// Extension Content-Typeに対応する拡張子
+func Extension(contentType string) string {
+	switch contentType {
+	case ContentTypeJPEG:
+		return ".jpg"
+	case ContentTypePNG:
+		return ".png"
+	case ContentTypeGIF:
+		return ".gif"
+	default:
+		return ""
+	}
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(bounds)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// 白背景に合成（JPEGは透過を扱えないため）
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
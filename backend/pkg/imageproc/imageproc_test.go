@@ -0,0 +1,129 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// IHDRの幅・高さだけを書き換えたPNG（画素データは1×1のまま）
+func pngWithDimensions(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	data := encodePNG(t, 1, 1)
+	// シグネチャ(8) + 長さ(4) + "IHDR"(4) の後に幅・高さ、IHDRのデータ(13)の後にCRC
+	binary.BigEndian.PutUint32(data[16:20], width)
+	binary.BigEndian.PutUint32(data[20:24], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		wantErr string
+	}{
+		{
+			name: "valid png",
+			data: func(t *testing.T) []byte { return encodePNG(t, 4, 3) },
+		},
+		{
+			name:    "unsupported format",
+			data:    func(t *testing.T) []byte { return []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>") },
+			wantErr: ErrUnsupportedFormat.Error(),
+		},
+		{
+			name:    "pixel limit exceeded",
+			data:    func(t *testing.T) []byte { return pngWithDimensions(t, 10000, 5001) },
+			wantErr: "are not allowed",
+		},
+		{
+			name:    "huge dimensions",
+			data:    func(t *testing.T) []byte { return pngWithDimensions(t, 100000, 100000) },
+			wantErr: "are not allowed",
+		},
+		{
+			// 上限内のサイズは展開まで進む（画素データが足りないため展開で失敗する）
+			name:    "at the pixel limit",
+			data:    func(t *testing.T) []byte { return pngWithDimensions(t, 10000, 5000) },
+			wantErr: "invalid image",
+		},
+		{
+			name:    "truncated image",
+			data:    func(t *testing.T) []byte { return encodePNG(t, 4, 3)[:40] },
+			wantErr: "invalid image",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, contentType, err := Decode(tt.data(t))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if contentType != ContentTypePNG || img.Bounds().Dx() != 4 || img.Bounds().Dy() != 3 {
+					t.Errorf("Decode() = %v, %q", img.Bounds(), contentType)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height         int
+		maxWidth, maxHeight   int
+		wantWidth, wantHeight int
+	}{
+		{name: "landscape thumbnail", width: 1000, height: 500, maxWidth: 200, maxHeight: 200, wantWidth: 200, wantHeight: 100},
+		{name: "portrait thumbnail", width: 300, height: 900, maxWidth: 200, maxHeight: 200, wantWidth: 67, wantHeight: 200},
+		{name: "smaller image is not enlarged", width: 120, height: 80, maxWidth: 200, maxHeight: 200, wantWidth: 120, wantHeight: 80},
+		{name: "extreme aspect ratio keeps at least one pixel", width: 4000, height: 2, maxWidth: 200, maxHeight: 200, wantWidth: 200, wantHeight: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			got := Fit(img, tt.maxWidth, tt.maxHeight).Bounds()
+			if got.Dx() != tt.wantWidth || got.Dy() != tt.wantHeight {
+				t.Errorf("Fit() = %dx%d, want %dx%d", got.Dx(), got.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestResizeAveragesColors(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	img.Set(1, 0, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+
+	got := Resize(img, 1, 1).NRGBAAt(0, 0)
+	want := color.NRGBA{R: 100, G: 50, B: 25, A: 255}
+	if got != want {
+		t.Errorf("Resize() pixel = %v, want %v", got, want)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,127 @@
+package imageproc
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+)
+
+// 透かし文字に使う5×7ドットのフォント（各行の下位5ビットが左から右のドット）
+var watermarkGlyphs = map[rune][7]uint8{
+	'A': {0b01110, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'B': {0b11110, 0b10001, 0b10001, 0b11110, 0b10001, 0b10001, 0b11110},
+	'C': {0b01110, 0b10001, 0b10000, 0b10000, 0b10000, 0b10001, 0b01110},
+	'D': {0b11110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b11110},
+	'E': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b11111},
+	'F': {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b10000},
+	'G': {0b01110, 0b10001, 0b10000, 0b10111, 0b10001, 0b10001, 0b01111},
+	'H': {0b10001, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'I': {0b01110, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'J': {0b00111, 0b00010, 0b00010, 0b00010, 0b00010, 0b10010, 0b01100},
+	'K': {0b10001, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010, 0b10001},
+	'L': {0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b11111},
+	'M': {0b10001, 0b11011, 0b10101, 0b10101, 0b10001, 0b10001, 0b10001},
+	'N': {0b10001, 0b10001, 0b11001, 0b10101, 0b10011, 0b10001, 0b10001},
+	'O': {0b01110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'P': {0b11110, 0b10001, 0b10001, 0b11110, 0b10000, 0b10000, 0b10000},
+	'Q': {0b01110, 0b10001, 0b10001, 0b10001, 0b10101, 0b10010, 0b01101},
+	'R': {0b11110, 0b10001, 0b10001, 0b11110, 0b10100, 0b10010, 0b10001},
+	'S': {0b01111, 0b10000, 0b10000, 0b01110, 0b00001, 0b00001, 0b11110},
+	'T': {0b11111, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100},
+	'U': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'V': {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01010, 0b00100},
+	'W': {0b10001, 0b10001, 0b10001, 0b10101, 0b10101, 0b10101, 0b01010},
+	'X': {0b10001, 0b10001, 0b01010, 0b00100, 0b01010, 0b10001, 0b10001},
+	'Y': {0b10001, 0b10001, 0b10001, 0b01010, 0b00100, 0b00100, 0b00100},
+	'Z': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b11111},
+	'0': {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	'1': {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'2': {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	'3': {0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	'4': {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	'5': {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	'6': {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	'7': {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	'8': {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	'9': {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+	'-': {0b00000, 0b00000, 0b00000, 0b11111, 0b00000, 0b00000, 0b00000},
+	'.': {0b00000, 0b00000, 0b00000, 0b00000, 0b00000, 0b01100, 0b01100},
+}
+
+// 1文字分のセル（5×7のドットと字間・行間）
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+	cellWidth   = glyphWidth + 1
+)
+
+// 透かしの不透明度（0〜255）
+const (
+	watermarkTextAlpha   = 110
+	watermarkShadowAlpha = 70
+)
+
+// Watermark 画像全体に半透明の文字を敷き詰めた透かし入りの画像を返す（英数字・ハイフン・ピリオド以外は空白扱い）
+func Watermark(img image.Image, text string) *image.NRGBA {
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	glyphs := []rune(strings.ToUpper(strings.TrimSpace(text)))
+	if len(glyphs) == 0 {
+		return dst
+	}
+
+	// 文字列の幅が画像の幅の約1/3になる倍率
+	width, height := bounds.Dx(), bounds.Dy()
+	scale := max(1, width/(3*len(glyphs)*cellWidth))
+	textWidth := len(glyphs) * cellWidth * scale
+	textHeight := glyphHeight * scale
+	stepX := textWidth + textWidth/2
+	stepY := textHeight * 4
+
+	// 行ごとに横へずらして斜めに並ぶように敷き詰める
+	for row, y := 0, textHeight; y < height; row, y = row+1, y+stepY {
+		offset := (row * stepX / 3) % stepX
+		for x := offset - stepX; x < width; x += stepX {
+			drawText(dst, glyphs, x+scale, y+scale, scale, 0, 0, 0, watermarkShadowAlpha)
+			drawText(dst, glyphs, x, y, scale, 255, 255, 255, watermarkTextAlpha)
+		}
+	}
+
+	return dst
+}
+
+func drawText(dst *image.NRGBA, glyphs []rune, x, y, scale int, r, g, b, alpha uint8) {
+	for i, ch := range glyphs {
+		glyph, ok := watermarkGlyphs[ch]
+		if !ok {
+			continue
+		}
+		originX := x + i*cellWidth*scale
+		for gy := 0; gy < glyphHeight; gy++ {
+			for gx := 0; gx < glyphWidth; gx++ {
+				if glyph[gy]&(1<<(glyphWidth-1-gx)) == 0 {
+					continue
+				}
+				fillBlend(dst, originX+gx*scale, y+gy*scale, scale, r, g, b, alpha)
+			}
+		}
+	}
+}
+
+// scale×scaleの正方形を指定色で半透明に重ねる（画像外ははみ出した分を無視）
+func fillBlend(dst *image.NRGBA, x, y, size int, r, g, b, alpha uint8) {
+	rect := image.Rect(x, y, x+size, y+size).Intersect(dst.Bounds())
+	a := uint32(alpha)
+	for py := rect.Min.Y; py < rect.Max.Y; py++ {
+		i := dst.PixOffset(rect.Min.X, py)
+		for px := rect.Min.X; px < rect.Max.X; px++ {
+			dst.Pix[i] = uint8((uint32(dst.Pix[i])*(255-a) + uint32(r)*a) / 255)
+			dst.Pix[i+1] = uint8((uint32(dst.Pix[i+1])*(255-a) + uint32(g)*a) / 255)
+			dst.Pix[i+2] = uint8((uint32(dst.Pix[i+2])*(255-a) + uint32(b)*a) / 255)
+			dst.Pix[i+3] = uint8(uint32(dst.Pix[i+3]) + (255-uint32(dst.Pix[i+3]))*a/255)
+			i += 4
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package jpaddress
+
+import (
+	"math"
+)
+
+// 都道府県庁所在地の緯度・経度
+var prefectureCapitals = map[string][2]float64{
+	"北海道": {43.064, 141.347}, "青森県": {40.824, 140.740}, "岩手県": {39.704, 141.153},
+	"宮城県": {38.269, 140.872}, "秋田県": {39.719, 140.102}, "山形県": {38.240, 140.364},
+	"福島県": {37.750, 140.468}, "茨城県": {36.342, 140.447}, "栃木県": {36.566, 139.884},
+	"群馬県": {36.391, 139.061}, "埼玉県": {35.857, 139.649}, "千葉県": {35.605, 140.123},
+	"東京都": {35.690, 139.692}, "神奈川県": {35.448, 139.642}, "新潟県": {37.902, 139.023},
+	"富山県": {36.695, 137.211}, "石川県": {36.594, 136.626}, "福井県": {36.065, 136.222},
+	"山梨県": {35.664, 138.568}, "長野県": {36.651, 138.181}, "岐阜県": {35.391, 136.722},
+	"静岡県": {34.977, 138.383}, "愛知県": {35.180, 136.907}, "三重県": {34.730, 136.509},
+	"滋賀県": {35.004, 135.868}, "京都府": {35.021, 135.756}, "大阪府": {34.686, 135.520},
+	"兵庫県": {34.691, 135.183}, "奈良県": {34.685, 135.833}, "和歌山県": {34.226, 135.167},
+	"鳥取県": {35.504, 134.238}, "島根県": {35.472, 133.051}, "岡山県": {34.662, 133.935},
+	"広島県": {34.397, 132.460}, "山口県": {34.186, 131.471}, "徳島県": {34.066, 134.559},
+	"香川県": {34.340, 134.043}, "愛媛県": {33.842, 132.766}, "高知県": {33.560, 133.531},
+	"福岡県": {33.607, 130.418}, "佐賀県": {33.249, 130.299}, "長崎県": {32.745, 129.874},
+	"熊本県": {32.790, 130.742}, "大分県": {33.238, 131.613}, "宮崎県": {31.911, 131.424},
+	"鹿児島県": {31.560, 130.558}, "沖縄県": {26.212, 127.681},
+}
+
+// 地球の半径（km）
+const earthRadiusKm = 6371.0
+
+// 2つの都道府県の県庁所在地間の距離（km）。都道府県名が正しくない場合はfalseを返す
+func Distance(from, to string) (float64, bool) {
+	a, ok := prefectureCapitals[from]
+	if !ok {
+		return 0, false
+	}
+	b, ok := prefectureCapitals[to]
+	if !ok {
+		return 0, false
+	}
+
+	lat1, lat2 := a[0]*math.Pi/180, b[0]*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b[1] - a[1]) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h)), true
+}
\ No newline at end of file
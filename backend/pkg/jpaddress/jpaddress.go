@@ -0,0 +1,62 @@
+package jpaddress
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// 日本の住所（郵便番号・都道府県・電話番号）の検証と正規化
+
+// Prefectures 都道府県（JIS X 0401 の順）
+var Prefectures = []string{
+	"北海道", "青森県", "岩手県", "宮城県", "秋田県", "山形県", "福島県",
+	"茨城県", "栃木県", "群馬県", "埼玉県", "千葉県", "東京都", "神奈川県",
+	"新潟県", "富山県", "石川県", "福井県", "山梨県", "長野県", "岐阜県",
+	"静岡県", "愛知県", "三重県", "滋賀県", "京都府", "大阪府", "兵庫県",
+	"奈良県", "和歌山県", "鳥取県", "島根県", "岡山県", "広島県", "山口県",
+	"徳島県", "香川県", "愛媛県", "高知県", "福岡県", "佐賀県", "長崎県",
+	"熊本県", "大分県", "宮崎県", "鹿児島県", "沖縄県",
+}
+
+var (
+	postalCodePattern = regexp.MustCompile(`^(\d{3})-?(\d{4})$`)
+	phonePattern      = regexp.MustCompile(`^0\d{9,10}$`)
+
+	// 全角数字・各種ハイフンを半角に揃える
+	widthReplacer = strings.NewReplacer(
+		"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+		"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+		"－", "-", "ー", "-", "‐", "-", "―", "-", "−", "-",
+		"〒", "", " ", "", "　", "",
+	)
+)
+
+// 郵便番号を "123-4567" の形式に正規化（7桁でない場合はエラー）
+func NormalizePostalCode(postalCode string) (string, error) {
+	m := postalCodePattern.FindStringSubmatch(widthReplacer.Replace(strings.TrimSpace(postalCode)))
+	if m == nil {
+		return "", errors.New("invalid postal code: must be 7 digits (e.g. 123-4567)")
+	}
+	return m[1] + "-" + m[2], nil
+}
+
+// 都道府県名として正しいか
+func IsPrefecture(name string) bool {
+	for _, prefecture := range Prefectures {
+		if prefecture == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 電話番号をハイフン無しの10〜11桁に正規化
+func NormalizePhone(phone string) (string, error) {
+	normalized := strings.ReplaceAll(widthReplacer.Replace(strings.TrimSpace(phone)), "-", "")
+	normalized = strings.NewReplacer("(", "", ")", "", "（", "", "）", "").Replace(normalized)
+	if !phonePattern.MatchString(normalized) {
+		return "", errors.New("invalid phone number: must be 10 or 11 digits starting with 0")
+	}
+	return normalized, nil
+}
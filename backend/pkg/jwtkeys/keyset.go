@@ -0,0 +1,234 @@
+// Package jwtkeys JWTの署名鍵・検証鍵の管理
+//
+// 鍵ディレクトリ内の "<kid>.pem"（秘密鍵）と "<kid>.pub.pem"（検証専用の公開鍵）を読み込む。
+// 鍵のローテーション手順:
+//  1. 新しい鍵を追加して再起動（署名には使わず、JWKSにのみ公開される）
+//  2. 検証側のJWKSキャッシュが更新された後、JWT_ACTIVE_KEY_IDを新しい鍵に切り替えて再起動
+//  3. アクセストークンの有効期限が過ぎたら古い鍵を削除（または .pub.pem のみ残す）
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key 署名・検証に使用する鍵
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer    // 検証専用の鍵ではnil
+	Public  crypto.PublicKey // HMACではnil
+	hmacKey []byte
+}
+
+// KeySet 署名に使用する鍵1つと、検証に使用する鍵の集合
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// 共有シークレットによるHS256（鍵ディレクトリを使用しない場合）
+func NewHMAC(secret string) *KeySet {
+	key := &Key{Method: jwt.SigningMethodHS256, hmacKey: []byte(secret)}
+	return &KeySet{active: key, keys: map[string]*Key{"": key}}
+}
+
+// ディレクトリから鍵を読み込む（activeIDの鍵で署名する）
+func LoadDir(dir, activeID string) (*KeySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &KeySet{keys: make(map[string]*Key)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".pem") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		var key *Key
+		if kid := strings.TrimSuffix(name, ".pub.pem"); kid != name {
+			key, err = ParsePublicKey(kid, data)
+		} else {
+			key, err = ParsePrivateKey(strings.TrimSuffix(name, ".pem"), data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		// 同じkidの秘密鍵と公開鍵がある場合は秘密鍵を優先
+		if existing, ok := set.keys[key.ID]; ok && existing.Private != nil {
+			continue
+		}
+		set.keys[key.ID] = key
+	}
+
+	if len(set.keys) == 0 {
+		return nil, fmt.Errorf("no signing keys found in %s", dir)
+	}
+
+	// 未指定の場合は鍵が1つだけのときのみ自動で選択
+	if activeID == "" {
+		if len(set.keys) != 1 {
+			return nil, errors.New("JWT_ACTIVE_KEY_ID is required when multiple keys are present")
+		}
+		for id := range set.keys {
+			activeID = id
+		}
+	}
+
+	active, ok := set.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeID)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+// PEM形式の秘密鍵（PKCS#8 または PKCS#1 のRSA）を読み込む
+func ParsePrivateKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+}
+
+// PEM形式の公開鍵（PKIX）を読み込む
+func ParsePublicKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, Public: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", parsed)
+	}
+}
+
+// 署名に使用する鍵のID
+func (s *KeySet) ActiveKeyID() string {
+	return s.active.ID
+}
+
+// アクティブな鍵で署名（kidヘッダーを付与）
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	if s.active.hmacKey != nil {
+		return token.SignedString(s.active.hmacKey)
+	}
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.Private)
+}
+
+// jwt.Parse用のKeyfunc（kidに対応する鍵を選び、署名方式が一致するか確認）
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	if key.hmacKey != nil {
+		return key.hmacKey, nil
+	}
+	return key.Public, nil
+}
+
+// 検証に使用する署名方式の一覧（jwt.WithValidMethods用）
+func (s *KeySet) Algorithms() []string {
+	seen := make(map[string]bool)
+	var algs []string
+	for _, key := range s.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// 新しい秘密鍵を生成してPEM形式（PKCS#8）で返す（algはRS256またはEdDSA）
+func GenerateKey(alg string) ([]byte, error) {
+	var private interface{}
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		key, err := rsa.GenerateKey(rand.Reader, 3072)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case jwt.SigningMethodEdDSA.Alg():
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
\ No newline at end of file
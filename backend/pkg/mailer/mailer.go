@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"fmt"
+
+	"github.com/Naonao3/EC-site/backend/config"
+)
+
+// Message 送信するメール
+type Message struct {
+	To      string
+	Subject string
+	Body    string // text/plain
+}
+
+// Mailer メール送信の抽象化
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// 設定に応じたMailerを生成
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg.Mail), nil
+	case "file":
+		return NewFileMailer(cfg.Mail.DropDir, cfg.Mail.From)
+	case "memory":
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", cfg.Mail.Driver)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,140 @@
+// Package oidctest テスト用のOpenID Connectプロバイダー
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "test-key"
+
+// Issuer ディスカバリー・JWKS・トークンエンドポイントを持つhttptestのサーバー
+type Issuer struct {
+	*httptest.Server
+	// ディスカバリーで返す発行者（空の場合はサーバーのURL）
+	DiscoveryIssuer string
+
+	key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	codes     map[string]jwt.MapClaims
+	verifiers map[string]string
+}
+
+func NewIssuer() (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := &Issuer{
+		key:       key,
+		codes:     make(map[string]jwt.MapClaims),
+		verifiers: make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.handleDiscovery)
+	mux.HandleFunc("/jwks", issuer.handleJWKS)
+	mux.HandleFunc("/token", issuer.handleToken)
+	issuer.Server = httptest.NewServer(mux)
+
+	return issuer, nil
+}
+
+// Sign IDトークンに署名（RS256、kidはJWKSの鍵）
+func (i *Issuer) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(i.key)
+}
+
+// IssueCode 認可コードを発行（トークンエンドポイントでclaimsのIDトークンを返す）
+func (i *Issuer) IssueCode(code string, claims jwt.MapClaims) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.codes[code] = claims
+}
+
+// CodeVerifier 認可コードの交換時に送られたPKCEのコードベリファイア
+func (i *Issuer) CodeVerifier(code string) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.verifiers[code]
+}
+
+func (i *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	issuer := i.DiscoveryIssuer
+	if issuer == "" {
+		issuer = i.URL
+	}
+	writeJSON(w, map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": i.URL + "/authorize",
+		"token_endpoint":         i.URL + "/token",
+		"jwks_uri":               i.URL + "/jwks",
+	})
+}
+
+func (i *Issuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	publicKey := i.key.PublicKey
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}},
+	})
+}
+
+// 発行済みの認可コードは1回だけ交換できる
+func (i *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	i.mu.Lock()
+	claims, ok := i.codes[code]
+	delete(i.codes, code)
+	if ok {
+		i.verifiers[code] = r.PostForm.Get("code_verifier")
+	}
+	i.mu.Unlock()
+	if !ok {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	idToken, err := i.Sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
\ No newline at end of file
@@ -0,0 +1,226 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Naonao3/EC-site/backend/pkg/oidc"
+	"github.com/Naonao3/EC-site/backend/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID = "client-id"
+	testNonce    = "nonce"
+)
+
+func newTestIssuer(t *testing.T) *oidctest.Issuer {
+	t.Helper()
+	issuer, err := oidctest.NewIssuer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func newTestProvider(issuer *oidctest.Issuer) *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Name:        "test",
+		Issuer:      issuer.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:3000/callback",
+	})
+}
+
+func validClaims(issuer *oidctest.Issuer) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   issuer.URL,
+		"sub":   "subject",
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": testNonce,
+		"email": "user@example.com",
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// 署名済みのIDトークンを返す
+		token   func(t *testing.T, issuer *oidctest.Issuer, claims jwt.MapClaims) string
+		nonce   string
+		wantErr string
+	}{
+		{
+			name:  "valid token",
+			token: signed(nil),
+			nonce: testNonce,
+		},
+		{
+			name:    "wrong issuer",
+			token:   signed(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }),
+			nonce:   testNonce,
+			wantErr: "issuer",
+		},
+		{
+			name:    "wrong audience",
+			token:   signed(func(c jwt.MapClaims) { c["aud"] = "other-client" }),
+			nonce:   testNonce,
+			wantErr: "audience",
+		},
+		{
+			name:    "expired token",
+			token:   signed(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }),
+			nonce:   testNonce,
+			wantErr: "expired",
+		},
+		{
+			name:    "missing expiration",
+			token:   signed(func(c jwt.MapClaims) { delete(c, "exp") }),
+			nonce:   testNonce,
+			wantErr: "exp",
+		},
+		{
+			name:    "missing subject",
+			token:   signed(func(c jwt.MapClaims) { delete(c, "sub") }),
+			nonce:   testNonce,
+			wantErr: "missing subject",
+		},
+		{
+			name:    "nonce mismatch",
+			token:   signed(nil),
+			nonce:   "other-nonce",
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "empty nonce",
+			token:   signed(func(c jwt.MapClaims) { c["nonce"] = "" }),
+			nonce:   "",
+			wantErr: "nonce mismatch",
+		},
+		{
+			name: "signed by another key",
+			token: func(t *testing.T, issuer *oidctest.Issuer, claims jwt.MapClaims) string {
+				key, err := rsa.GenerateKey(rand.Reader, 2048)
+				if err != nil {
+					t.Fatal(err)
+				}
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+				token.Header["kid"] = "test-key"
+				raw, err := token.SignedString(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+			nonce:   testNonce,
+			wantErr: "signature",
+		},
+		{
+			name: "HMAC token without client secret",
+			token: func(t *testing.T, issuer *oidctest.Issuer, claims jwt.MapClaims) string {
+				raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testClientID))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+			nonce:   testNonce,
+			wantErr: "client secret is required",
+		},
+		{
+			name: "unsigned token",
+			token: func(t *testing.T, issuer *oidctest.Issuer, claims jwt.MapClaims) string {
+				raw, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+			nonce:   testNonce,
+			wantErr: "signing method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newTestIssuer(t)
+			provider := newTestProvider(issuer)
+
+			claims, err := provider.VerifyIDToken(context.Background(), tt.token(t, issuer, validClaims(issuer)), tt.nonce)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyIDToken() error = %v", err)
+				}
+				if claims.Subject != "subject" || claims.Email != "user@example.com" {
+					t.Errorf("VerifyIDToken() claims = %+v", claims)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("VerifyIDToken() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// クレームを変更してテスト用の発行者の鍵で署名する
+func signed(modify func(jwt.MapClaims)) func(t *testing.T, issuer *oidctest.Issuer, claims jwt.MapClaims) string {
+	return func(t *testing.T, issuer *oidctest.Issuer, claims jwt.MapClaims) string {
+		if modify != nil {
+			modify(claims)
+		}
+		raw, err := issuer.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.DiscoveryIssuer = "https://evil.example.com"
+	provider := newTestProvider(issuer)
+
+	if _, err := provider.AuthCodeURL(context.Background(), "state", testNonce, "verifier"); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("AuthCodeURL() error = %v, want issuer mismatch", err)
+	}
+
+	raw, err := issuer.Sign(validClaims(issuer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.VerifyIDToken(context.Background(), raw, testNonce); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("VerifyIDToken() error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	issuer := newTestIssuer(t)
+	provider := newTestProvider(issuer)
+	issuer.IssueCode("code", validClaims(issuer))
+
+	token, err := provider.Exchange(context.Background(), "code", "verifier")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if token.IDToken == "" {
+		t.Error("Exchange() returned no id_token")
+	}
+	if got := issuer.CodeVerifier("code"); got != "verifier" {
+		t.Errorf("code_verifier = %q, want %q", got, "verifier")
+	}
+
+	// 交換済みの認可コードは使えない
+	if _, err := provider.Exchange(context.Background(), "code", "verifier"); err == nil {
+		t.Fatal("Exchange() error = nil, want error for a reused code")
+	}
+}
\ No newline at end of file
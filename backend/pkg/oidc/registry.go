@@ -0,0 +1,34 @@
+package oidc
+
+import (
+	"sort"
+)
+
+// Registry 名前でプロバイダーを引くための登録簿
+type Registry struct {
+	providers map[string]*Provider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]*Provider)}
+}
+
+// プロバイダーを登録（同名のプロバイダーは上書き）
+func (r *Registry) Register(provider *Provider) {
+	r.providers[provider.Name()] = provider
+}
+
+func (r *Registry) Get(name string) (*Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// 登録済みのプロバイダー名（名前順）
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
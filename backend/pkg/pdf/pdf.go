@@ -0,0 +1,101 @@
+// Package pdf はライセンス証明書などの1ページのPDFを標準ライブラリのみで生成する。
+// 日本語を表示できるよう、埋め込み不要の日本語標準フォント（HeiseiKakuGo-W5）を使用する。
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+)
+
+// A4のページサイズ（ポイント）
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+)
+
+// Document 1ページのPDF
+type Document struct {
+	content bytes.Buffer
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+// Text 左下を原点とした座標(x, y)に文字列を描画
+func (d *Document) Text(x, y, size float64, text string) {
+	fmt.Fprintf(&d.content, "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, y, encodeText(text))
+}
+
+// CenteredText 横方向の中央に文字列を描画
+func (d *Document) CenteredText(y, size float64, text string) {
+	d.Text((PageWidth-TextWidth(text, size))/2, y, size, text)
+}
+
+// Line 線を描画
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&d.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// Rect 枠線を描画
+func (d *Document) Rect(x, y, width, height, lineWidth float64) {
+	fmt.Fprintf(&d.content, "%.2f w %.2f %.2f %.2f %.2f re S\n", lineWidth, x, y, width, height)
+}
+
+// TextWidth 文字列の幅（半角は文字サイズの半分、全角は文字サイズと同じ幅）
+func TextWidth(text string, size float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		if r < 0x80 {
+			width += size / 2
+		} else {
+			width += size
+		}
+	}
+	return width
+}
+
+// Bytes PDFファイルとして出力
+func (d *Document) Bytes() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 4 0 R >> >> /Contents 7 0 R >>", PageWidth, PageHeight),
+		// 半角英数字を半角幅で表示するUniJIS-UCS2-HW-Hエンコーディング
+		"<< /Type /Font /Subtype /Type0 /BaseFont /HeiseiKakuGo-W5 /Encoding /UniJIS-UCS2-HW-H /DescendantFonts [5 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /HeiseiKakuGo-W5 /CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 2 >> /FontDescriptor 6 0 R /DW 1000 /W [1 95 500 231 325 500] >>",
+		"<< /Type /FontDescriptor /FontName /HeiseiKakuGo-W5 /Flags 4 /FontBBox [-92 -250 1010 922] /ItalicAngle 0 /Ascent 752 /Descent -221 /CapHeight 737 /StemV 114 >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", d.content.Len(), d.content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// UTF-16BEの16進数文字列（基本多言語面以外の文字は?に置き換える）
+func encodeText(text string) string {
+	var buf bytes.Buffer
+	for _, r := range text {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&buf, "%04X", r)
+	}
+	return buf.String()
+}
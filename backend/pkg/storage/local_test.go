@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "simple key", key: "products/1/original.jpg"},
+		{name: "dotted file name", key: "products/1/.thumbnail.jpg"},
+		{name: "empty", key: "", wantErr: true},
+		{name: "absolute path", key: "/etc/passwd", wantErr: true},
+		{name: "parent directory", key: "../secret", wantErr: true},
+		{name: "parent directory in the middle", key: "products/../../secret", wantErr: true},
+		{name: "current directory", key: "products/./1", wantErr: true},
+		{name: "empty segment", key: "products//1", wantErr: true},
+		{name: "trailing slash", key: "products/1/", wantErr: true},
+		{name: "backslash", key: `products\..\secret`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalRejectsKeysOutsideDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "uploads")
+	s, err := NewLocal(dir, "http://localhost/uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "../outside.txt", []byte("data"), "text/plain"); err == nil {
+		t.Error("Put() error = nil, want error for a key outside the directory")
+	}
+	if _, err := os.Stat(filepath.Join(root, "outside.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file was written outside the storage directory: %v", err)
+	}
+	if _, err := s.Get(ctx, "../outside.txt"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want invalid key error", err)
+	}
+	if err := s.Delete(ctx, "../outside.txt"); err == nil {
+		t.Error("Delete() error = nil, want error for a key outside the directory")
+	}
+}
+
+func TestLocalPutGetDelete(t *testing.T) {
+	s, err := NewLocal(t.TempDir(), "http://localhost/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "products/1/original.png"
+
+	if err := s.Put(ctx, key, []byte("image"), "image/png"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	r, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != "image" {
+		t.Fatalf("Get() = %q, %v", data, err)
+	}
+
+	if got, want := s.URL(key), "http://localhost/uploads/"+key; got != want {
+		t.Errorf("URL() = %q, want %q", got, want)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+	// 存在しないキーの削除はエラーにしない
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of a missing key error = %v", err)
+	}
+}
\ No newline at end of file
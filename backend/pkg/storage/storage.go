@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Naonao3/EC-site/backend/config"
+)
+
+// ErrNotFound 指定したキーのオブジェクトが存在しない
+var ErrNotFound = errors.New("object not found")
+
+// Storage アップロードしたファイルの保存先の抽象化
+type Storage interface {
+	// Put オブジェクトを保存（同じキーがある場合は上書き）
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get オブジェクトを取得（呼び出し側でCloseする）
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete オブジェクトを削除（存在しない場合もエラーにしない）
+	Delete(ctx context.Context, key string) error
+	// URL 公開用のURL
+	URL(key string) string
+}
+
+// 設定に応じたStorageを生成
+func NewStorage(cfg *config.Config) (Storage, error) {
+	switch cfg.Storage.Driver {
+	case "local":
+		publicURL := cfg.Storage.PublicURL
+		if publicURL == "" {
+			publicURL = "http://localhost:" + cfg.Server.Port + "/uploads"
+		}
+		return NewLocal(cfg.Storage.LocalDir, publicURL)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:        cfg.Storage.S3Endpoint,
+			Region:          cfg.Storage.S3Region,
+			Bucket:          cfg.Storage.S3Bucket,
+			AccessKeyID:     cfg.Storage.S3AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3SecretAccessKey,
+			UsePathStyle:    cfg.Storage.S3UsePathStyle,
+			PublicURL:       cfg.Storage.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Storage.Driver)
+	}
+}
+
+// 購入者のみがダウンロードできる元画像用のStorageを生成（公開URLでは配信しない）
+func NewPrivateStorage(cfg *config.Config) (Storage, error) {
+	switch cfg.Storage.Driver {
+	case "local":
+		return NewLocal(cfg.Storage.PrivateDir, "")
+	case "s3":
+		bucket := cfg.Storage.S3PrivateBucket
+		if bucket == "" {
+			bucket = cfg.Storage.S3Bucket
+		}
+		return NewS3(S3Config{
+			Endpoint:        cfg.Storage.S3Endpoint,
+			Region:          cfg.Storage.S3Region,
+			Bucket:          bucket,
+			AccessKeyID:     cfg.Storage.S3AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3SecretAccessKey,
+			UsePathStyle:    cfg.Storage.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Storage.Driver)
+	}
+}
+
+// キーの検証（ディレクトリトラバーサル防止）
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key: %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid storage key: %q", key)
+		}
+	}
+	return nil
+}
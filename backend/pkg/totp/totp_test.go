@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 付録Bのテストベクトル（SHA-1, 8桁のコードの下6桁）
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+
+func TestGenerateCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := GenerateCode(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("GenerateCode(%d) error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(step int64) string {
+		c, err := GenerateCode(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: code(step), wantStep: step, wantOK: true},
+		{name: "previous step within skew", code: code(step - 1), wantStep: step - 1, wantOK: true},
+		{name: "next step within skew", code: code(step + 1), wantStep: step + 1, wantOK: true},
+		{name: "outside skew", code: code(step - 2)},
+		{name: "spaces are ignored", code: code(step)[:3] + " " + code(step)[3:], wantStep: step, wantOK: true},
+		{name: "wrong length", code: "12345"},
+		{name: "wrong code", code: "000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Validate(rfcSecret, tt.code, now, 1)
+			if ok != tt.wantOK || (ok && gotStep != tt.wantStep) {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateCodeInvalidSecret(t *testing.T) {
+	if _, err := GenerateCode("not base32!", 1); err == nil {
+		t.Error("GenerateCode() error = nil, want error")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Shiba Image Store", "user@example.com", rfcSecret)
+	for _, want := range []string{"otpauth://totp/Shiba%20Image%20Store:user@example.com?", "secret=" + rfcSecret, "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("URI() = %q, want it to contain %q", uri, want)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,40 @@
+-- ==========================================
+-- 階層カテゴリ移行マイグレーション
+-- ==========================================
+-- バックエンド起動時にも同じ移行（CategoryService.MigrateLegacyCategories）が実行されるため、
+-- どちらを先に実行しても結果は同じになる
+
+-- categoriesテーブルに表示順と更新日時を追加
+ALTER TABLE categories
+ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- 文字列のカテゴリ名（互換性のため残す）とカテゴリIDを両方持てるようにする
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS category VARCHAR(100),
+ALTER COLUMN category_id DROP NOT NULL;
+
+-- 既存の文字列カテゴリのうち、カテゴリテーブルに存在しないものを行として追加
+INSERT INTO categories (name, slug)
+SELECT DISTINCT p.category, lower(regexp_replace(trim(p.category), '[^[:alnum:]]+', '-', 'g'))
+FROM products p
+WHERE p.category_id IS NULL
+    AND p.category IS NOT NULL
+    AND p.category <> ''
+    AND NOT EXISTS (
+        SELECT 1 FROM categories c WHERE c.name = p.category OR c.slug = p.category
+    )
+ON CONFLICT (slug) DO NOTHING;
+
+-- 商品にカテゴリIDを設定
+UPDATE products p
+SET category_id = c.id
+FROM categories c
+WHERE p.category_id IS NULL
+    AND (c.name = p.category OR c.slug = p.category);
+
+-- マイグレーション完了確認用
+SELECT
+    COUNT(*) FILTER (WHERE category_id IS NOT NULL) AS categorized,
+    COUNT(*) FILTER (WHERE category_id IS NULL) AS uncategorized
+FROM products;
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- ==========================================
+-- 商品の全文検索用マイグレーション
+-- ==========================================
+-- バックエンド起動時にも同じ内容（ProductRepository.EnsureSearchIndexes）が実行される
+
+-- 日本語の部分一致・表記揺れ対応のためのトライグラム
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- 商品名（重みA）と説明（重みB）の検索用ベクトル
+ALTER TABLE products
+ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+    ) STORED;
+
+-- インデックス追加
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_products_description_trgm ON products USING GIN (description gin_trgm_ops);
+
+-- マイグレーション完了確認用
+SELECT indexname
+FROM pg_indexes
+WHERE tablename = 'products'
+    AND indexname IN ('idx_products_search_vector', 'idx_products_name_trgm', 'idx_products_description_trgm');
\ No newline at end of file
@@ -178,6 +178,8 @@ export default function OrdersPage() {
                           ? '決済完了'
                           : order.payment.status === 'pending'
                           ? '決済待ち'
+                          : order.payment.status === 'refund_required'
+                          ? '返金手続き中'
                           : '決済失敗'}
                       </span>
                     </div>
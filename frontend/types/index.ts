@@ -84,7 +84,7 @@ export interface Payment {
   order?: Order
 }
 
-export type PaymentStatus = 'pending' | 'succeeded' | 'failed' | 'cancelled'
+export type PaymentStatus = 'pending' | 'succeeded' | 'failed' | 'cancelled' | 'refund_required'
 
 export interface Review {
   id: number